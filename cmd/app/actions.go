@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyAction is what one key binding does on a screen. The keyboard and the
+// command palette both run a screen's actions, so they share its handlers.
+type keyAction struct {
+	binding key.Binding
+	run     func(m *model) (tea.Model, tea.Cmd)
+}
+
+// act adapts a model change with no command to a keyAction run func
+func act(change func(m *model)) func(m *model) (tea.Model, tea.Cmd) {
+	return func(m *model) (tea.Model, tea.Cmd) {
+		change(m)
+		return m, nil
+	}
+}
+
+// runKeyAction runs the action bound to the pressed key, if there is one
+func (m *model) runKeyAction(msg tea.KeyMsg, actions []keyAction) (tea.Model, tea.Cmd) {
+	for _, action := range actions {
+		if key.Matches(msg, action.binding) {
+			return action.run(m)
+		}
+	}
+	return m, nil
+}
+
+// priorityActions binds each number key to setting its priority
+func priorityActions(set func(m *model, priority int)) []keyAction {
+	var actions []keyAction
+	for priority := PriorityHigh; priority <= PriorityLow; priority++ {
+		number := strconv.Itoa(priority)
+		actions = append(actions, keyAction{
+			binding: key.NewBinding(key.WithKeys(number), key.WithHelp(number, "set priority "+priorityName(priority))),
+			run:     act(func(m *model) { set(m, priority) }),
+		})
+	}
+	return actions
+}
+
+// screenActions returns the actions of the current screen, nil while typing
+func (m *model) screenActions() []keyAction {
+	switch m.currentState {
+	case StateMainBrowse:
+		return m.taskListActions()
+	case StateListSelector:
+		switch m.currentSubState {
+		case SubStateListDeleteConfirm:
+			return m.listDeleteConfirmActions()
+		case SubStateListManage:
+			return m.listManageActions()
+		}
+		return m.listSelectorActions()
+	case StateDeleteConfirm:
+		return m.deleteConfirmActions()
+	case StatePrioritySelection:
+		return m.prioritySelectionActions()
+	case StateTrash:
+		if m.currentSubState == SubStateTrashPurgeConfirm {
+			return m.purgeConfirmActions()
+		}
+		return m.trashActions()
+	case StateArchivedView:
+		return m.archivedViewActions()
+	case StateMoveTask:
+		return m.movePickerActions()
+	case StateTaskDetail:
+		if m.currentSubState == SubStateDetailEdit {
+			return nil
+		}
+		return m.taskDetailActions()
+	case StateProfilePicker:
+		return m.profilePickerActions()
+	case StateCalendar:
+		return m.calendarActions()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *model) loadArchivedLists(ctx context.Context) error {
+	lists, err := m.store.GetArchivedTodoLists(ctx)
+	if err != nil {
+		return err
+	}
+	m.archivedLists = lists
+	return nil
+}
+
+// selectorRowCount is the number of list rows in the list selector,
+// including archived lists when they are shown
+func (m *model) selectorRowCount() int {
+	if m.input.showArchived {
+		return len(m.todoLists) + len(m.archivedLists)
+	}
+	return len(m.todoLists)
+}
+
+// selectedArchivedList returns the archived list under the list selector
+// cursor, or nil if the cursor is on a live list
+func (m *model) selectedArchivedList() *todoList {
+	index := m.input.listIndex - len(m.todoLists)
+	if !m.input.showArchived || index < 0 || index >= len(m.archivedLists) {
+		return nil
+	}
+	return &m.archivedLists[index]
+}
+
+func (m *model) toggleShowArchived() {
+	if !m.input.showArchived {
+		ctx, cancel := m.storeContext()
+		defer cancel()
+		if err := m.loadArchivedLists(ctx); err != nil {
+			m.errorMsg = "Failed to load archived lists: " + err.Error()
+			return
+		}
+	}
+	m.input.showArchived = !m.input.showArchived
+	if m.input.listIndex >= m.selectorRowCount() && m.input.listIndex > 0 {
+		m.input.listIndex = m.selectorRowCount() - 1
+	}
+	m.viewport.Height = m.getViewportHeight(m.height)
+}
+
+// openArchivedList shows an archived list's tasks read-only. The current
+// list is restored from currentListIndex when the view closes.
+func (m *model) openArchivedList(list todoList) {
+	m.currentListID = list.id
+	m.input.archivedList = list
+	m.cursor = 0
+	m.invalidateCache()
+	m.setState(StateArchivedView, SubStateNone)
+}
+
+func (m *model) handleArchivedView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	return m.runKeyAction(msg, m.archivedViewActions())
+}
+
+func (m *model) archivedViewActions() []keyAction {
+	k := m.keys
+	return []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.cursor < m.getVisibleItemCount()-1 {
+				m.cursor++
+			}
+		})},
+		{k.Unarchive, act(func(m *model) {
+			m.unarchiveList(m.input.archivedList)
+			if m.currentState == StateArchivedView {
+				return
+			}
+			for i, list := range m.todoLists {
+				if list.id == m.input.archivedList.id {
+					m.switchToList(i)
+				}
+			}
+			m.returnToMain()
+		})},
+		{k.Close, act(func(m *model) {
+			m.switchToList(m.currentListIndex)
+			m.setState(StateListSelector, SubStateNone)
+		})},
+	}
+}
+
+// unarchiveList moves an archived list back to the live lists, leaving the
+// list selector cursor on it
+func (m *model) unarchiveList(list todoList) {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.UnarchiveTodoList(ctx, list.id); err != nil {
+		m.errorMsg = "Failed to unarchive list: " + err.Error()
+		return
+	}
+	m.recordUndo(archiveListCommand(list.id, false))
+	m.reloadFromStore(ctx)
+
+	for i, live := range m.todoLists {
+		if live.id == list.id {
+			m.input.listIndex = i
+		}
+	}
+	m.statusMsg = "Unarchived list " + list.name
+	m.setState(StateListSelector, SubStateNone)
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// calendarState is the calendar's selection
+type calendarState struct {
+	day       time.Time // Selected day, at midnight
+	weekView  bool      // Show the selected week instead of the month
+	taskIndex int       // Selected task among the day's tasks
+	movingID  int       // Task being rescheduled, 0 when none
+}
+
+// openCalendar shows the tasks with a due date from every list, starting
+// on today
+func (m *model) openCalendar() {
+	m.calendar = calendarState{day: startOfDay(time.Now()), weekView: m.calendar.weekView}
+	m.setState(StateCalendar, SubStateNone)
+}
+
+func (m *model) handleCalendar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	return m.runKeyAction(msg, m.calendarActions())
+}
+
+func (m *model) calendarActions() []keyAction {
+	k := m.keys
+	moveBy := func(days int) func(m *model) (tea.Model, tea.Cmd) {
+		return act(func(m *model) { m.selectCalendarDay(m.calendar.day.AddDate(0, 0, days)) })
+	}
+	return []keyAction{
+		{k.PrevDay, moveBy(-1)},
+		{k.NextDay, moveBy(1)},
+		{k.PrevWeek, moveBy(-7)},
+		{k.NextWeek, moveBy(7)},
+		{k.PrevMonth, act(func(m *model) { m.selectCalendarDay(addMonths(m.calendar.day, -1)) })},
+		{k.NextMonth, act(func(m *model) { m.selectCalendarDay(addMonths(m.calendar.day, 1)) })},
+		{k.Today, act(func(m *model) { m.selectCalendarDay(startOfDay(time.Now())) })},
+		{k.CalendarView, act(func(m *model) { m.calendar.weekView = !m.calendar.weekView })},
+		{k.NextDayTask, act(func(m *model) { m.stepCalendarTask(1) })},
+		{k.PrevDayTask, act(func(m *model) { m.stepCalendarTask(-1) })},
+		{k.Reschedule, act(func(m *model) {
+			if m.calendar.movingID != 0 {
+				m.rescheduleTask(m.calendar.movingID, m.calendar.day)
+				return
+			}
+			if tasks := m.calendarTasks(m.calendar.day); m.calendar.taskIndex < len(tasks) {
+				m.calendar.movingID = tasks[m.calendar.taskIndex].id
+			}
+		})},
+		{k.Close, act(func(m *model) {
+			if m.calendar.movingID != 0 {
+				m.calendar.movingID = 0
+			} else {
+				m.returnToMain()
+			}
+		})},
+	}
+}
+
+// stepCalendarTask selects the next or previous task of the selected day,
+// wrapping around
+func (m *model) stepCalendarTask(step int) {
+	tasks := m.calendarTasks(m.calendar.day)
+	if m.calendar.movingID != 0 || len(tasks) == 0 {
+		return
+	}
+	m.calendar.taskIndex = (m.calendar.taskIndex + step + len(tasks)) % len(tasks)
+}
+
+func (m *model) selectCalendarDay(day time.Time) {
+	m.calendar.day = day
+	m.calendar.taskIndex = 0
+}
+
+// rescheduleTask moves a task's due date to the end of day
+func (m *model) rescheduleTask(id int, day time.Time) {
+	m.calendar.movingID = 0
+	index := m.itemIndexByID(id)
+	if index < 0 {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	before := m.items[index]
+	m.items[index].dueDate = setToEndOfDay(day).Unix()
+	if err := m.store.UpdateItem(ctx, m.items[index]); err != nil {
+		m.errorMsg = "Failed to reschedule task: " + err.Error()
+		m.items[index] = before
+		return
+	}
+	m.recordUndo(updateItemCommand("reschedule task", before, m.items[index]))
+	m.invalidateCache()
+	m.sortItems()
+
+	for i, item := range m.calendarTasks(day) {
+		if item.id == id {
+			m.calendar.taskIndex = i
+		}
+	}
+	m.statusMsg = "Due " + day.Format(DateHeadingFormat)
+}
+
+// calendarTasks returns the tasks of live lists due on day, open tasks
+// first, then by priority
+func (m *model) calendarTasks(day time.Time) []todoItem {
+	live := map[int]bool{}
+	for _, list := range m.todoLists {
+		live[list.id] = true
+	}
+
+	var tasks []todoItem
+	for _, item := range m.items {
+		if item.dueDate != 0 && live[item.todoListID] && sameDay(time.Unix(item.dueDate, 0), day) {
+			tasks = append(tasks, item)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].done != tasks[j].done {
+			return !tasks[i].done
+		}
+		return tasks[i].priority < tasks[j].priority
+	})
+	return tasks
+}
+
+// openTaskCount returns the open tasks due on day
+func (m *model) openTaskCount(day time.Time) int {
+	count := 0
+	for _, item := range m.calendarTasks(day) {
+		if !item.done {
+			count++
+		}
+	}
+	return count
+}
+
+// overdueCount returns the open tasks of live lists whose due date passed
+func (m *model) overdueCount() int {
+	live := map[int]bool{}
+	for _, list := range m.todoLists {
+		live[list.id] = true
+	}
+	now := time.Now().Unix()
+	count := 0
+	for _, item := range m.items {
+		if !item.done && item.dueDate != 0 && item.dueDate < now && live[item.todoListID] {
+			count++
+		}
+	}
+	return count
+}
+
+// DateHeadingFormat is how the calendar names the selected day
+const DateHeadingFormat = "Monday, January 2"
+
+func (m model) renderCalendar() string {
+	day := m.calendar.day
+	title := "Calendar · " + day.Format("January 2006")
+	if m.calendar.weekView {
+		title = "Calendar · Week of " + weekStart(day).Format("January 2")
+	}
+	if overdue := m.overdueCount(); overdue > 0 {
+		title += fmt.Sprintf(" · %d overdue", overdue)
+	}
+	lines := []string{TitleStyle.Render(title)}
+
+	if m.calendar.weekView {
+		lines = append(lines, m.renderCalendarWeek()...)
+	} else {
+		lines = append(lines, m.renderCalendarMonth()...)
+	}
+
+	heading := day.Format(DateHeadingFormat)
+	if sameDay(day, time.Now()) {
+		heading += " (today)"
+	}
+	lines = append(lines, "", TitleStyle.Render(heading))
+
+	tasks := m.calendarTasks(day)
+	if len(tasks) == 0 {
+		lines = append(lines, StatusStyle.Render("  No tasks due"))
+	}
+	now := time.Now().Unix()
+	for i, item := range tasks {
+		line := CheckboxOpen + item.todo + " · " + m.listName(item.todoListID)
+		if item.done {
+			line = CheckboxDone + item.todo + " · " + m.listName(item.todoListID)
+		}
+		switch {
+		case i == m.calendar.taskIndex && m.calendar.movingID == 0:
+			lines = append(lines, SelectedStyle.Render("▶ "+line))
+		case item.done:
+			lines = append(lines, DoneStyle.Render("  "+line))
+		case item.dueDate < now:
+			lines = append(lines, OverdueStyle.Render("  "+line))
+		default:
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	lines = append(lines, "")
+	if index := m.itemIndexByID(m.calendar.movingID); index >= 0 {
+		lines = append(lines, SelectedStyle.Render(fmt.Sprintf("Moving %q: choose a day, then press %s to drop it or %s to cancel",
+			m.items[index].todo, m.keys.Reschedule.Help().Key, m.keys.Close.Help().Key)))
+	}
+	if m.errorMsg != "" {
+		lines = append(lines, ErrorStyle.Render("Error: "+m.errorMsg))
+	}
+	if m.statusMsg != "" {
+		lines = append(lines, StatusStyle.Render(m.statusMsg))
+	}
+	k := m.keys
+	lines = append(lines, m.shortHelp([]key.Binding{
+		k.Help, k.PrevDay, k.NextDay, k.PrevWeek, k.NextWeek, k.Reschedule, k.NextDayTask, k.CalendarView,
+		k.PrevMonth, k.NextMonth, k.Today, k.Close,
+	}))
+	return strings.Join(lines, "\n")
+}
+
+// renderCalendarMonth lays out the selected month one week per line, each
+// day with its number of open tasks and a ! when some of them are overdue
+func (m model) renderCalendarMonth() []string {
+	width := m.calendarCellWidth()
+	day := m.calendar.day
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+	today := startOfDay(time.Now())
+
+	lines := []string{m.calendarWeekdays(width)}
+	for start := weekStart(first); start.Month() == day.Month() || start.Before(first); start = start.AddDate(0, 0, 7) {
+		var cells []string
+		for d := start; d.Before(start.AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+			label := fmt.Sprintf("%2d", d.Day())
+			count := m.openTaskCount(d)
+			if count > 0 {
+				label += fmt.Sprintf(" (%d)", count)
+			}
+			overdue := count > 0 && d.Before(today)
+			if overdue {
+				label += "!"
+			}
+			cell := fmt.Sprintf("%-*s", width-1, label)
+
+			switch {
+			case sameDay(d, day):
+				cell = SelectedStyle.Render(cell)
+			case d.Month() != day.Month():
+				cell = StatusStyle.Render(cell)
+			case overdue:
+				cell = OverdueStyle.Render(cell)
+			case sameDay(d, today):
+				cell = lipgloss.NewStyle().Underline(true).Render(cell)
+			}
+			cells = append(cells, cell)
+		}
+		lines = append(lines, strings.Join(cells, " "))
+	}
+	return lines
+}
+
+// renderCalendarWeek lays out the selected week with the tasks due each day
+// listed under it
+func (m model) renderCalendarWeek() []string {
+	width := m.calendarCellWidth()
+	start := weekStart(m.calendar.day)
+	now := time.Now().Unix()
+
+	var headers []string
+	columns := make([][]string, 7)
+	for i := range columns {
+		d := start.AddDate(0, 0, i)
+		header := fmt.Sprintf("%-*s", width-1, d.Format("Mon 2"))
+		if sameDay(d, m.calendar.day) {
+			header = SelectedStyle.Render(header)
+		}
+		headers = append(headers, header)
+
+		tasks := m.calendarTasks(d)
+		for j, item := range tasks {
+			if j == CalendarWeekRows-1 && len(tasks) > CalendarWeekRows {
+				columns[i] = append(columns[i], StatusStyle.Render(fmt.Sprintf("%-*s", width-1, fmt.Sprintf("+%d more", len(tasks)-j))))
+				break
+			}
+			cell := fmt.Sprintf("%-*s", width-1, truncateText(item.todo, width-1))
+			switch {
+			case item.done:
+				cell = DoneStyle.Render(cell)
+			case item.dueDate < now:
+				cell = OverdueStyle.Render(cell)
+			}
+			columns[i] = append(columns[i], cell)
+		}
+	}
+
+	lines := []string{strings.Join(headers, " ")}
+	blank := strings.Repeat(" ", width-1)
+	for row := 0; row < CalendarWeekRows; row++ {
+		var cells []string
+		empty := true
+		for _, column := range columns {
+			if row < len(column) {
+				cells = append(cells, column[row])
+				empty = false
+			} else {
+				cells = append(cells, blank)
+			}
+		}
+		if empty {
+			break
+		}
+		lines = append(lines, strings.Join(cells, " "))
+	}
+	return lines
+}
+
+// calendarWeekdays is the row of weekday names above the month
+func (m model) calendarWeekdays(width int) string {
+	var names []string
+	for i := 0; i < 7; i++ {
+		names = append(names, fmt.Sprintf("%-*s", width-1, time.Weekday((i + 1) % 7).String()[:3]))
+	}
+	return StatusStyle.Render(strings.Join(names, " "))
+}
+
+// calendarCellWidth shrinks the day columns to fit narrow terminals
+func (m model) calendarCellWidth() int {
+	width := CalendarCellWidth
+	if m.width > 0 && m.width/7 < width {
+		width = max(m.width/7, 6)
+	}
+	return width
+}
+
+// startOfDay returns midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// weekStart returns the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	return startOfDay(t).AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// addMonths moves t by months, keeping the day within the target month
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.Local)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// truncateText cuts text to width columns, ending it with … when cut
+func truncateText(text string, width int) string {
+	if lipgloss.Width(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && lipgloss.Width(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// runCommand runs a non-interactive subcommand and returns the process
+// exit code
+func runCommand(cfg Config, args []string) int {
+	switch args[0] {
+	case "sync-key":
+		return runSyncKeyCommand(cfg, args[1:])
+	case "history":
+		return runHistoryCommand(cfg, args[1:])
+	case "config":
+		return runConfigCommand(cfg, args[1:])
+	case "init":
+		return runInitCommand()
+	case "add":
+		return runAddCommand(cfg, args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
+		printUsage()
+		return 2
+	}
+}
+
+func printUsage() {
+	fmt.Println("Usage: commandlinetodo [flags] [command]")
+	fmt.Println()
+	fmt.Println("Run without a command to start the interactive todo list.")
+	fmt.Println()
+	fmt.Println("Flags:")
+	fmt.Println("  --config PATH     Read settings from PATH instead of the default config file")
+	fmt.Println("  --profile NAME    Use the named profile from the config file")
+	fmt.Println("  --db PATH         Database file for the sqlite backend")
+	fmt.Println("  --backend NAME    Storage backend: sqlite, todotxt or markdown")
+	fmt.Println("  --theme NAME      Color theme")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  add TASK          Add a task; TASK may set fields with !1-!4, @list, due:DATE and #tags")
+	fmt.Println("  config show       Print the effective configuration and where each value came from")
+	fmt.Println("  init              Create a project database in .todo/ in the current directory")
+	fmt.Println("  history [text]    List archived completed tasks, optionally matching text")
+	fmt.Println("  sync-key init     Derive the sync encryption key from a passphrase; add SALT to join other devices")
+	fmt.Println("  sync-key rotate   Switch to a new passphrase, keeping old keys for reading; add SALT as for init")
+	fmt.Println("  sync-key status   Show the keys in the local keyfile")
+}
+
+func runSyncKeyCommand(cfg Config, args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 2
+	}
+
+	switch args[0] {
+	case "init":
+		passphrase, err := readPassphrase("Passphrase: ", true)
+		if err != nil {
+			logErrorMsg("read passphrase", err)
+			return 1
+		}
+		c, err := NewSyncCipher(cfg.Sync.KeyFile, passphrase, saltArg(args))
+		if err != nil {
+			logErrorMsg("create sync key", err)
+			return 1
+		}
+		fmt.Printf("Created sync key %s in %s\n", c.CurrentKeyID(), cfg.Sync.KeyFile)
+		fmt.Printf("Run 'sync-key init %s' with the same passphrase on your other devices.\n", c.KeySalt(c.CurrentKeyID()))
+		return 0
+
+	case "rotate":
+		c, err := LoadSyncCipher(cfg.Sync.KeyFile)
+		if err != nil {
+			logErrorMsg("load sync key", err)
+			return 1
+		}
+		passphrase, err := readPassphrase("New passphrase: ", false)
+		if err != nil {
+			logErrorMsg("read passphrase", err)
+			return 1
+		}
+		previous := c.CurrentKeyID()
+		if err := c.RotateKey(passphrase, saltArg(args)); err != nil {
+			logErrorMsg("rotate sync key", err)
+			return 1
+		}
+		fmt.Printf("Rotated sync key %s -> %s\n", previous, c.CurrentKeyID())
+		fmt.Println("All tasks are re-encrypted with the new key on the next sync.")
+		fmt.Printf("Run 'sync-key rotate %s' with the same passphrase on your other devices.\n", c.KeySalt(c.CurrentKeyID()))
+		return 0
+
+	case "status":
+		c, err := LoadSyncCipher(cfg.Sync.KeyFile)
+		if err != nil {
+			logErrorMsg("load sync key", err)
+			return 1
+		}
+		fmt.Printf("Keyfile: %s\n", cfg.Sync.KeyFile)
+		fmt.Printf("Encryption enabled: %v\n", cfg.Sync.Encrypt)
+		for _, id := range c.KeyIDs() {
+			marker := " "
+			if id == c.CurrentKeyID() {
+				marker = "*"
+			}
+			fmt.Printf("%s %s  salt %s\n", marker, id, c.KeySalt(id))
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown sync-key command: %s\n", args[0])
+		return 2
+	}
+}
+
+// saltArg returns the salt given after a sync-key subcommand, or an empty
+// string to create a new one
+func saltArg(args []string) string {
+	if len(args) > 1 {
+		return args[1]
+	}
+	return ""
+}
+
+func runConfigCommand(cfg Config, args []string) int {
+	if len(args) != 1 || args[0] != "show" {
+		printUsage()
+		return 2
+	}
+	printConfig(os.Stdout, cfg)
+	return 0
+}
+
+// runInitCommand creates .todo/todo.db in the working directory. The app
+// then uses it whenever it is started in this directory or below.
+func runInitCommand() int {
+	cwd, err := os.Getwd()
+	if err != nil {
+		logErrorMsg("get working directory", err)
+		return 1
+	}
+	path := filepath.Join(cwd, projectDirName, dbFileName)
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("Project database already exists: %s\n", path)
+		return 0
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logErrorMsg("create "+projectDirName, err)
+		return 1
+	}
+	ctx := context.Background()
+	store, err := OpenLocalStore(ctx, path)
+	if err != nil {
+		logErrorMsg("initialize database", err)
+		return 1
+	}
+	store.Close()
+
+	fmt.Printf("Created project database %s\n", path)
+	return 0
+}
+
+// runAddCommand adds a task written in the quick-add syntax, to the
+// default list unless it names one
+func runAddCommand(cfg Config, args []string) int {
+	ctx := context.Background()
+	opened, err := openCommandStore(ctx, &cfg, func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", a...)
+	})
+	if err != nil {
+		logErrorMsg("open data store", err)
+		return 1
+	}
+	defer opened.close()
+
+	m, err := loadModel(ctx, opened.store)
+	if err != nil {
+		logErrorMsg("load data", err)
+		return 1
+	}
+	m.applyConfig(cfg)
+	if m.errorMsg != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", m.errorMsg)
+	}
+
+	// The lists are needed to tell an @list token from an @word in the text
+	q, err := parseQuickAdd(strings.Join(args, " "), m.todoLists)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if _, err := validateTaskText(q.text); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	list, err := m.addQuickTask(q)
+	if err != nil {
+		logErrorMsg("add task", err)
+		return 1
+	}
+	fmt.Printf("Added to %s: %s\n", list.name, q.text)
+	return 0
+}
+
+// runHistoryCommand prints archived completions, most recent first, with a
+// count per list
+func runHistoryCommand(cfg Config, args []string) int {
+	if cfg.Backend != BackendSQLite {
+		fmt.Fprintf(os.Stderr, "History is not available with the %s backend\n", cfg.Backend)
+		return 1
+	}
+
+	ctx := context.Background()
+	store, err := OpenLocalStore(ctx, cfg.DBPath)
+	if err != nil {
+		logErrorMsg("open database", err)
+		return 1
+	}
+	defer store.Close()
+
+	history, err := store.GetHistory(ctx, strings.Join(args, " "))
+	if err != nil {
+		logErrorMsg("load history", err)
+		return 1
+	}
+	printHistory(os.Stdout, history)
+	return 0
+}
+
+func printHistory(w io.Writer, history []historyItem) {
+	counts := map[string]int{}
+	var lists []string
+	for _, item := range history {
+		completed := time.Unix(item.dateCompleted, 0).Format("2006-01-02")
+		fmt.Fprintf(w, "%s  %-12s %s\n", completed, item.listName, item.todo)
+		if counts[item.listName] == 0 {
+			lists = append(lists, item.listName)
+		}
+		counts[item.listName]++
+	}
+
+	fmt.Fprintf(w, "\n%d completed task(s)\n", len(history))
+	for _, list := range lists {
+		fmt.Fprintf(w, "  %-12s %d\n", list, counts[list])
+	}
+}
+
+// readPassphrase returns the passphrase from TODO_SYNC_PASSPHRASE when
+// allowEnv is set, otherwise it prompts for one on stdin. Typing is not
+// echoed when stdin is a terminal; piped input is read a line at a time.
+func readPassphrase(prompt string, allowEnv bool) (string, error) {
+	if allowEnv {
+		if passphrase := os.Getenv(syncPassphraseEnvVar); passphrase != "" {
+			return passphrase, nil
+		}
+	}
+
+	fmt.Print(prompt)
+	var line string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		typed, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		line = string(typed)
+	} else {
+		read, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && read == "" {
+			return "", err
+		}
+		line = read
+	}
+
+	passphrase := strings.TrimRight(line, "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase cannot be empty")
+	}
+	return passphrase, nil
+}
@@ -1,17 +1,58 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 type Config struct {
-	DBPath string
-	Sync   SyncConfig
+	DBPath             string
+	Backend            string
+	TodoTxtPath        string
+	DoneTxtPath        string
+	MarkdownPath       string
+	TrashRetentionDays int                          // 0 keeps deleted items until purged by hand
+	HistoryAfterDays   int                          // 0 keeps completed tasks in their lists
+	Theme              string                       // Built-in or [themes.<name>] theme
+	Themes             map[string]map[string]string // User-defined themes by name, from [themes.<name>] tables
+	DefaultPriority    int                          // Priority preselected for new tasks
+	DefaultList        string                       // List opened at startup, empty for the first
+	Mouse              bool                         // Capture the mouse for clicking and scrolling
+	Keymap             string                       // Keymap preset the keybindings start from
+	Keybindings        map[string]string            // Action name to keys, from the [keybindings] table
+	Sync               SyncConfig
+
+	ConfigPath string            // Config file that was looked for
+	Sources    map[string]string // Where each setting came from, by config key
+	Profile    string            // Active profile, empty for the top-level settings
+	Profiles   []string          // Profiles defined in the config file, sorted
+	args       []string          // Command line, kept to reload with another profile
 }
 
+// Storage backends accepted by TODO_BACKEND
+const (
+	BackendSQLite   = "sqlite"
+	BackendTodoTxt  = "todotxt"
+	BackendMarkdown = "markdown"
+)
+
+// Where a setting's value came from, highest precedence first
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceProfile = "profile" // The active profile's table in the config file
+	SourceProject = "project" // Found by walking up from the working directory
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
 type SyncConfig struct {
 	Enabled             bool
 	ServerURL           string
@@ -21,14 +62,68 @@ type SyncConfig struct {
 	AutoSyncOnChange    bool
 	RetryAttempts       int
 	TimeoutSeconds      int
+	Encrypt             bool
+	KeyFile             string
+	Transport           string
+	Dir                 string
 }
 
-// defaultDBPath is the default location for the database file
-const defaultDBPath = "./todo.db"
+// legacyDBPath is where older versions kept the database. It is still used
+// when it exists and no path is configured.
+const legacyDBPath = "./todo.db"
+
+// dbFileName is the database file name inside the data directory and a
+// project's .todo directory
+const dbFileName = "todo.db"
+
+// Project-local database markers, looked for in the working directory and
+// each parent: a .todo directory holding todo.db, or a .todo.db file
+const (
+	projectDirName    = ".todo"
+	projectDBFileName = ".todo.db"
+)
 
 // dbPathEnvVar is the environment variable name for database path configuration
 const dbPathEnvVar = "TODO_DB_PATH"
 
+// Storage backend environment variables
+const (
+	backendEnvVar     = "TODO_BACKEND"
+	todoTxtPathEnvVar = "TODO_TODOTXT_PATH"
+	doneTxtPathEnvVar = "TODO_DONETXT_PATH"
+	markdownEnvVar    = "TODO_MARKDOWN_PATH"
+)
+
+// defaultTodoTxtPath is the default todo.txt location for the todotxt backend
+const defaultTodoTxtPath = "./todo.txt"
+
+// trashRetentionEnvVar sets how many days deleted items stay in the trash
+const trashRetentionEnvVar = "TODO_TRASH_RETENTION_DAYS"
+
+// defaultTrashRetentionDays keeps deleted items until they are purged by
+// hand unless a retention period is configured
+const defaultTrashRetentionDays = 0
+
+// historyAfterEnvVar sets how many days completed tasks stay in their list
+// before moving to the history
+const historyAfterEnvVar = "TODO_HISTORY_AFTER_DAYS"
+
+// defaultHistoryAfterDays keeps completed tasks in their lists unless a
+// number of days is configured
+const defaultHistoryAfterDays = 0
+
+// Appearance and default environment variables
+const (
+	themeEnvVar           = "TODO_THEME"
+	defaultPriorityEnvVar = "TODO_DEFAULT_PRIORITY"
+	defaultListEnvVar     = "TODO_DEFAULT_LIST"
+	keymapEnvVar          = "TODO_KEYMAP"
+	mouseEnvVar           = "TODO_MOUSE"
+)
+
+// defaultTheme is the theme used when none is configured
+const defaultTheme = "default"
+
 // Sync environment variables
 const (
 	syncEnabledEnvVar      = "TODO_SYNC_ENABLED"
@@ -39,70 +134,488 @@ const (
 	autoSyncOnChangeEnvVar = "TODO_AUTO_SYNC_ON_CHANGE"
 	retryAttemptsEnvVar    = "TODO_SYNC_RETRY_ATTEMPTS"
 	timeoutSecondsEnvVar   = "TODO_SYNC_TIMEOUT"
+	syncEncryptEnvVar      = "TODO_SYNC_ENCRYPT"
+	syncKeyFileEnvVar      = "TODO_SYNC_KEY_FILE"
+	syncPassphraseEnvVar   = "TODO_SYNC_PASSPHRASE"
+	syncKeySaltEnvVar      = "TODO_SYNC_KEY_SALT"
+	syncTransportEnvVar    = "TODO_SYNC_TRANSPORT"
+	syncDirEnvVar          = "TODO_SYNC_DIR"
+)
+
+// appConfigDirName is the per-user directory name used under the config and
+// data directories
+const appConfigDirName = "commandlinetodo"
+
+// defaultKeyFileName is the name of the sync keyfile inside the config directory
+const defaultKeyFileName = "sync.key"
+
+// keybindingsTable is the config file table mapping actions to keys
+const keybindingsTable = "keybindings"
+
+// profilesTable holds one table of settings per profile, such as
+// [profiles.work] and [profiles.work.sync]
+const profilesTable = "profiles"
+
+// The profile is chosen by the top-level profile key, TODO_PROFILE or
+// --profile. DefaultProfile names the top-level settings alone.
+const (
+	profileKey     = "profile"
+	profileEnvVar  = "TODO_PROFILE"
+	DefaultProfile = "default"
 )
 
 // Default sync configuration values
 const (
-	defaultSyncInterval    = 60
-	defaultRetryAttempts   = 3
-	defaultTimeoutSeconds  = 10
+	defaultSyncInterval   = 60
+	defaultRetryAttempts  = 3
+	defaultTimeoutSeconds = 10
 )
 
-func LoadConfig() Config {
-	cfg := Config{
-		DBPath: defaultDBPath,
-		Sync:   loadSyncConfig(),
+// setting ties one Config field to its config file key, environment
+// variable and command line flag. Empty env or flag means the setting
+// cannot be set that way.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	quoted bool // Shown as a TOML string by config show
+	secret bool // Hidden by config show
+	set    func(cfg *Config, value string) error
+	get    func(cfg *Config) string
+}
+
+// settings lists every configurable value in config show order
+var settings = []setting{
+	stringSetting("db_path", dbPathEnvVar, "db", "database file for the sqlite backend", func(c *Config) *string { return &c.DBPath }),
+	choiceSetting("backend", backendEnvVar, "backend", "storage backend", []string{BackendSQLite, BackendTodoTxt, BackendMarkdown}, func(c *Config) *string { return &c.Backend }),
+	stringSetting("todotxt_path", todoTxtPathEnvVar, "", "", func(c *Config) *string { return &c.TodoTxtPath }),
+	stringSetting("donetxt_path", doneTxtPathEnvVar, "", "", func(c *Config) *string { return &c.DoneTxtPath }),
+	stringSetting("markdown_path", markdownEnvVar, "", "", func(c *Config) *string { return &c.MarkdownPath }),
+	intSetting("trash_retention_days", trashRetentionEnvVar, 0, -1, func(c *Config) *int { return &c.TrashRetentionDays }),
+	intSetting("history_after_days", historyAfterEnvVar, 0, -1, func(c *Config) *int { return &c.HistoryAfterDays }),
+	stringSetting("theme", themeEnvVar, "theme", "color theme", func(c *Config) *string { return &c.Theme }),
+	intSetting("defaults.priority", defaultPriorityEnvVar, PriorityHigh, PriorityLow, func(c *Config) *int { return &c.DefaultPriority }),
+	boolSetting("mouse", mouseEnvVar, func(c *Config) *bool { return &c.Mouse }),
+	choiceSetting("keymap", keymapEnvVar, "", "", KeymapPresets, func(c *Config) *string { return &c.Keymap }),
+	stringSetting("defaults.list", defaultListEnvVar, "", "", func(c *Config) *string { return &c.DefaultList }),
+	boolSetting("sync.enabled", syncEnabledEnvVar, func(c *Config) *bool { return &c.Sync.Enabled }),
+	stringSetting("sync.server_url", syncServerURLEnvVar, "", "", func(c *Config) *string { return &c.Sync.ServerURL }),
+	secretSetting(stringSetting("sync.api_key", syncAPIKeyEnvVar, "", "", func(c *Config) *string { return &c.Sync.APIKey })),
+	stringSetting("sync.device_id", syncDeviceIDEnvVar, "", "", func(c *Config) *string { return &c.Sync.DeviceID }),
+	intSetting("sync.interval", syncIntervalEnvVar, 1, -1, func(c *Config) *int { return &c.Sync.SyncIntervalSeconds }),
+	boolSetting("sync.auto_sync_on_change", autoSyncOnChangeEnvVar, func(c *Config) *bool { return &c.Sync.AutoSyncOnChange }),
+	intSetting("sync.retry_attempts", retryAttemptsEnvVar, 0, -1, func(c *Config) *int { return &c.Sync.RetryAttempts }),
+	intSetting("sync.timeout", timeoutSecondsEnvVar, 1, -1, func(c *Config) *int { return &c.Sync.TimeoutSeconds }),
+	boolSetting("sync.encrypt", syncEncryptEnvVar, func(c *Config) *bool { return &c.Sync.Encrypt }),
+	stringSetting("sync.key_file", syncKeyFileEnvVar, "", "", func(c *Config) *string { return &c.Sync.KeyFile }),
+	choiceSetting("sync.transport", syncTransportEnvVar, "", "", []string{SyncTransportHTTP, SyncTransportFile}, func(c *Config) *string { return &c.Sync.Transport }),
+	stringSetting("sync.dir", syncDirEnvVar, "", "", func(c *Config) *string { return &c.Sync.Dir }),
+}
+
+func stringSetting(key, env, flagName, usage string, field func(*Config) *string) setting {
+	return setting{
+		key: key, env: env, flag: flagName, usage: usage, quoted: true,
+		set: func(cfg *Config, value string) error {
+			*field(cfg) = value
+			return nil
+		},
+		get: func(cfg *Config) string { return *field(cfg) },
+	}
+}
+
+// choiceSetting accepts one of choices, ignoring case
+func choiceSetting(key, env, flagName, usage string, choices []string, field func(*Config) *string) setting {
+	s := stringSetting(key, env, flagName, usage, field)
+	s.set = func(cfg *Config, value string) error {
+		value = strings.ToLower(value)
+		for _, choice := range choices {
+			if value == choice {
+				*field(cfg) = value
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(choices, ", "))
+	}
+	return s
+}
+
+// intSetting accepts whole numbers from low up to high; a negative high
+// means no upper limit
+func intSetting(key, env string, low, high int, field func(*Config) *int) setting {
+	return setting{
+		key: key, env: env,
+		set: func(cfg *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a whole number", value)
+			}
+			if n < low || (high >= 0 && n > high) {
+				if high >= 0 {
+					return fmt.Errorf("%d is not between %d and %d", n, low, high)
+				}
+				return fmt.Errorf("%d is less than %d", n, low)
+			}
+			*field(cfg) = n
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.Itoa(*field(cfg)) },
+	}
+}
+
+// boolSetting accepts true/false, 1/0 and yes/no
+func boolSetting(key, env string, field func(*Config) *bool) setting {
+	return setting{
+		key: key, env: env,
+		set: func(cfg *Config, value string) error {
+			switch strings.ToLower(value) {
+			case "true", "1", "yes":
+				*field(cfg) = true
+			case "false", "0", "no":
+				*field(cfg) = false
+			default:
+				return fmt.Errorf("%q is not true or false", value)
+			}
+			return nil
+		},
+		get: func(cfg *Config) string { return strconv.FormatBool(*field(cfg)) },
+	}
+}
+
+func secretSetting(s setting) setting {
+	s.secret = true
+	return s
+}
+
+func defaultConfig() Config {
+	return Config{
+		DBPath:             defaultDBPath(),
+		Backend:            BackendSQLite,
+		TodoTxtPath:        defaultTodoTxtPath,
+		TrashRetentionDays: defaultTrashRetentionDays,
+		HistoryAfterDays:   defaultHistoryAfterDays,
+		Theme:              defaultTheme,
+		DefaultPriority:    DefaultPriority,
+		Mouse:              false,
+		Keymap:             KeymapDefault,
+		Keybindings:        map[string]string{},
+		Themes:             map[string]map[string]string{},
+		Sync: SyncConfig{
+			SyncIntervalSeconds: defaultSyncInterval,
+			AutoSyncOnChange:    true,
+			RetryAttempts:       defaultRetryAttempts,
+			TimeoutSeconds:      defaultTimeoutSeconds,
+			Transport:           SyncTransportHTTP,
+			KeyFile:             defaultKeyFilePath(),
+		},
+		Sources: map[string]string{},
+	}
+}
+
+// LoadConfig builds the configuration from defaults, the config file, the
+// active profile, environment variables and command line flags, each
+// overriding the one before. It returns the arguments left after the flags.
+// Every invalid value is reported in the error.
+func LoadConfig(args []string) (Config, []string, error) {
+	return loadConfig(args, "")
+}
+
+// WithProfile reloads the configuration from the same command line with
+// another profile active
+func (cfg Config) WithProfile(name string) (Config, error) {
+	newCfg, _, err := loadConfig(cfg.args, name)
+	return newCfg, err
+}
+
+// loadConfig is LoadConfig with the profile forced to profile, unless it is
+// empty
+func loadConfig(args []string, profile string) (Config, []string, error) {
+	cfg := defaultConfig()
+	cfg.args = args
+
+	flags := flag.NewFlagSet(appConfigDirName, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configPath := flags.String("config", "", "config file")
+	profileFlag := flags.String("profile", "", "profile")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		if s.flag != "" {
+			flagValues[s.key] = flags.String(s.flag, "", s.usage)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+	setFlags := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	cfg.ConfigPath = defaultConfigPath()
+	if path := os.Getenv(configPathEnvVar); path != "" {
+		cfg.ConfigPath = path
+	}
+	if setFlags["config"] {
+		cfg.ConfigPath = *configPath
+	}
+
+	var errs []error
+	fileValues := map[string]string{}
+	if cfg.ConfigPath != "" {
+		values, err := readConfigFile(cfg.ConfigPath)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			fileValues = values
+		}
+	}
+
+	profiles := splitProfiles(fileValues)
+	for name := range profiles {
+		cfg.Profiles = append(cfg.Profiles, name)
+	}
+	sort.Strings(cfg.Profiles)
+
+	cfg.Profile = fileValues[profileKey]
+	delete(fileValues, profileKey)
+	if name := os.Getenv(profileEnvVar); name != "" {
+		cfg.Profile = name
+	}
+	if setFlags["profile"] {
+		cfg.Profile = *profileFlag
+	}
+	if profile != "" {
+		cfg.Profile = profile
+	}
+	if cfg.Profile == DefaultProfile {
+		cfg.Profile = ""
+	}
+	profileValues := profiles[cfg.Profile]
+	if cfg.Profile != "" && profileValues == nil {
+		errs = append(errs, fmt.Errorf("unknown profile %q", cfg.Profile))
+	}
+
+	for _, s := range settings {
+		cfg.Sources[s.key] = SourceDefault
+		if value, ok := fileValues[s.key]; ok {
+			errs = append(errs, cfg.apply(s, value, SourceFile, s.key))
+			delete(fileValues, s.key)
+		}
+		if value, ok := profileValues[s.key]; ok {
+			errs = append(errs, cfg.apply(s, value, SourceProfile, "profile "+cfg.Profile))
+			delete(profileValues, s.key)
+		}
+		if value := os.Getenv(s.env); s.env != "" && value != "" {
+			errs = append(errs, cfg.apply(s, value, SourceEnv, s.env))
+		}
+		if setFlags[s.flag] {
+			errs = append(errs, cfg.apply(s, *flagValues[s.key], SourceFlag, "--"+s.flag))
+		}
+	}
+
+	// A project database beats the config file but not the environment or
+	// flags
+	if source := cfg.Sources["db_path"]; source == SourceDefault || source == SourceFile {
+		if cwd, err := os.Getwd(); err == nil {
+			if path, found := findProjectDB(cwd); found {
+				cfg.DBPath = path
+				cfg.Sources["db_path"] = SourceProject
+			}
+		}
+	}
+
+	for key := range profileValues {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %s in profile %s", cfg.ConfigPath, key, cfg.Profile))
 	}
 
-	if envPath := os.Getenv(dbPathEnvVar); envPath != "" {
-		cfg.DBPath = envPath
+	// Whatever is left in the file is a keybinding, a theme or a typo
+	for key, value := range fileValues {
+		if action, isBinding := strings.CutPrefix(key, keybindingsTable+"."); isBinding {
+			cfg.Keybindings[action] = value
+			cfg.Sources[key] = SourceFile
+			continue
+		}
+		if rest, isTheme := strings.CutPrefix(key, themesTable+"."); isTheme {
+			if name, themeKey, found := strings.Cut(rest, "."); found && name != "" {
+				name = strings.ToLower(name)
+				if cfg.Themes[name] == nil {
+					cfg.Themes[name] = map[string]string{}
+				}
+				cfg.Themes[name][themeKey] = value
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: unknown setting %s", cfg.ConfigPath, key))
+	}
+	if _, err := loadTheme(cfg); err != nil {
+		errs = append(errs, fmt.Errorf("invalid theme from %s: %w", cfg.Sources["theme"], err))
+	}
+	if _, err := newKeyMap(cfg.Keymap, cfg.Keybindings); err != nil {
+		errs = append(errs, fmt.Errorf("invalid keybindings: %w", err))
+	}
+	for name := range cfg.Themes {
+		if name == strings.ToLower(cfg.Theme) {
+			continue
+		}
+		theme := cfg
+		theme.Theme = name
+		if _, err := loadTheme(theme); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cfg.ConfigPath, err))
+		}
 	}
 
-	if err := ensureDBDirectory(cfg.DBPath); err != nil {
-		logErrorMsg("create database directory", err)
+	return cfg, flags.Args(), errors.Join(errs...)
+}
+
+// splitProfiles moves the profiles.<name>.* keys out of values, returning
+// each profile's settings by name
+func splitProfiles(values map[string]string) map[string]map[string]string {
+	profiles := map[string]map[string]string{}
+	for key, value := range values {
+		rest, isProfile := strings.CutPrefix(key, profilesTable+".")
+		if !isProfile {
+			continue
+		}
+		name, setting, found := strings.Cut(rest, ".")
+		if !found || name == "" {
+			continue
+		}
+		if profiles[name] == nil {
+			profiles[name] = map[string]string{}
+		}
+		profiles[name][setting] = value
+		delete(values, key)
 	}
+	return profiles
+}
 
-	return cfg
+// profileName is the name shown for a profile, DefaultProfile for none
+func profileName(profile string) string {
+	if profile == "" {
+		return DefaultProfile
+	}
+	return profile
+}
+
+// apply sets one value, naming where it came from if it is invalid
+func (cfg *Config) apply(s setting, value string, source string, origin string) error {
+	if err := s.set(cfg, value); err != nil {
+		return fmt.Errorf("invalid %s from %s: %w", s.key, origin, err)
+	}
+	cfg.Sources[s.key] = source
+	return nil
 }
 
-func loadSyncConfig() SyncConfig {
-	syncCfg := SyncConfig{
-		Enabled:             parseBoolEnv(syncEnabledEnvVar, false),
-		ServerURL:           os.Getenv(syncServerURLEnvVar),
-		APIKey:              os.Getenv(syncAPIKeyEnvVar),
-		DeviceID:            os.Getenv(syncDeviceIDEnvVar),
-		SyncIntervalSeconds: parseIntEnv(syncIntervalEnvVar, defaultSyncInterval),
-		AutoSyncOnChange:    parseBoolEnv(autoSyncOnChangeEnvVar, true),
-		RetryAttempts:       parseIntEnv(retryAttemptsEnvVar, defaultRetryAttempts),
-		TimeoutSeconds:      parseIntEnv(timeoutSecondsEnvVar, defaultTimeoutSeconds),
+// printConfig writes the effective configuration with the source of each
+// value, one setting per line
+func printConfig(w io.Writer, cfg Config) {
+	status := "not found"
+	if _, err := os.Stat(cfg.ConfigPath); err == nil {
+		status = "loaded"
 	}
+	fmt.Fprintf(w, "Config file: %s (%s)\n", cfg.ConfigPath, status)
+	fmt.Fprintf(w, "Profile: %s (available: %s)\n\n", profileName(cfg.Profile), strings.Join(append([]string{DefaultProfile}, cfg.Profiles...), ", "))
 
-	// Generate device ID if not set
-	if syncCfg.DeviceID == "" {
-		syncCfg.DeviceID = generateClientID()
+	for _, s := range settings {
+		value := s.get(&cfg)
+		if s.secret && value != "" {
+			value = "********"
+		}
+		if s.quoted {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(w, "%-26s = %-40s (%s)\n", s.key, value, cfg.Sources[s.key])
 	}
 
-	return syncCfg
+	actions := make([]string, 0, len(cfg.Keybindings))
+	for action := range cfg.Keybindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		key := keybindingsTable + "." + action
+		fmt.Fprintf(w, "%-26s = %-40s (%s)\n", key, strconv.Quote(cfg.Keybindings[action]), cfg.Sources[key])
+	}
+
+	var themeKeys []string
+	for name, values := range cfg.Themes {
+		for key := range values {
+			themeKeys = append(themeKeys, name+"."+key)
+		}
+	}
+	sort.Strings(themeKeys)
+	for _, key := range themeKeys {
+		name, themeKey, _ := strings.Cut(key, ".")
+		fmt.Fprintf(w, "%-26s = %-40s (%s)\n", themesTable+"."+key, strconv.Quote(cfg.Themes[name][themeKey]), SourceFile)
+	}
+	fmt.Fprintf(w, "\nThemes: %s\n", strings.Join(themeNames(cfg), ", "))
+}
+
+// findProjectDB walks up from dir looking for a .todo directory or a
+// .todo.db file, the nearest one winning
+func findProjectDB(dir string) (string, bool) {
+	current := dir
+	for {
+		if info, err := os.Stat(filepath.Join(current, projectDirName)); err == nil && info.IsDir() {
+			return filepath.Join(current, projectDirName, dbFileName), true
+		}
+		candidate := filepath.Join(current, projectDBFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
 }
 
-func parseBoolEnv(key string, defaultVal bool) bool {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
+// dbLabel is a short description of the database in use for the TUI title:
+// the project directory for a project database, otherwise the path with
+// the home directory shortened to ~
+func dbLabel(cfg Config) string {
+	if cfg.Backend != BackendSQLite {
+		return ""
 	}
-	// Accept "true", "1", "yes" as true
-	return strings.ToLower(val) == "true" || val == "1" || strings.ToLower(val) == "yes"
+	path := cfg.DBPath
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if cfg.Sources["db_path"] == SourceProject {
+		project := filepath.Dir(path)
+		if filepath.Base(project) == projectDirName {
+			project = filepath.Dir(project)
+		}
+		return "project " + filepath.Base(project)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = filepath.Join("~", rel)
+		}
+	}
+	return path
 }
 
-func parseIntEnv(key string, defaultVal int) int {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
+// defaultDBPath keeps using ./todo.db where an older version created it,
+// and otherwise stores the database in the user's data directory
+func defaultDBPath() string {
+	if _, err := os.Stat(legacyDBPath); err == nil {
+		return legacyDBPath
 	}
-	if intVal, err := strconv.Atoi(val); err == nil {
-		return intVal
+	dir, err := dataDir()
+	if err != nil {
+		return legacyDBPath
+	}
+	return filepath.Join(dir, dbFileName)
+}
+
+// defaultKeyFilePath returns the keyfile location inside the user's config
+// directory, falling back to the working directory if it is unknown
+func defaultKeyFilePath() string {
+	dir, err := configDir()
+	if err != nil {
+		return defaultKeyFileName
 	}
-	return defaultVal
+	return filepath.Join(dir, defaultKeyFileName)
 }
 
 func ensureDBDirectory(dbPath string) error {
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestParseConfigFile(t *testing.T) {
+	values, err := parseConfigFile(strings.NewReader(`
+# Top-level settings
+db_path = "/data/todo.db"  # trailing comment
+theme = 'dark # not a comment'
+
+[sync]
+enabled = true
+interval = 1_800
+
+[keybindings]
+add = "n"
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := map[string]string{
+		"db_path":         "/data/todo.db",
+		"theme":           "dark # not a comment",
+		"sync.enabled":    "true",
+		"sync.interval":   "1800",
+		"keybindings.add": "n",
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, values[key])
+		}
+	}
+}
+
+func TestParseConfigFile_Errors(t *testing.T) {
+	for _, content := range []string{
+		"db_path = /data/todo.db",
+		"[sync",
+		"theme",
+		"theme = \"dark\"\ntheme = \"light\"",
+		"interval = 1.5",
+	} {
+		if _, err := parseConfigFile(strings.NewReader(content)); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+db_path = "/file/todo.db"
+theme = "light"
+trash_retention_days = 10
+
+[sync]
+interval = 30
+`)
+	t.Setenv(configPathEnvVar, path)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv(dbPathEnvVar, filepath.Join(t.TempDir(), "env.db"))
+	t.Setenv(themeEnvVar, "dark")
+
+	cfg, args, err := LoadConfig([]string{"--theme", "high-contrast", "config", "show"})
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if strings.Join(args, " ") != "config show" {
+		t.Errorf("expected the command left after flags, got %v", args)
+	}
+
+	checks := []struct {
+		key, got, want, source string
+	}{
+		{"theme", cfg.Theme, "high-contrast", SourceFlag},
+		{"db_path", filepath.Base(cfg.DBPath), "env.db", SourceEnv},
+		{"trash_retention_days", strconv.Itoa(cfg.TrashRetentionDays), "10", SourceFile},
+		{"sync.interval", strconv.Itoa(cfg.Sync.SyncIntervalSeconds), "30", SourceFile},
+		{"history_after_days", strconv.Itoa(cfg.HistoryAfterDays), "0", SourceDefault},
+	}
+	for _, c := range checks {
+		if c.got != c.want || cfg.Sources[c.key] != c.source {
+			t.Errorf("%s: expected %q from %s, got %q from %s", c.key, c.want, c.source, c.got, cfg.Sources[c.key])
+		}
+	}
+}
+
+func TestLoadConfig_InvalidValues(t *testing.T) {
+	path := writeConfigFile(t, `
+backend = "postgres"
+colour = "red"
+
+[defaults]
+priority = 9
+`)
+	t.Setenv(configPathEnvVar, path)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv(syncIntervalEnvVar, "soon")
+
+	_, _, err := LoadConfig(nil)
+	if err == nil {
+		t.Fatal("expected invalid values to be reported")
+	}
+	for _, want := range []string{"backend", "colour", "defaults.priority", syncIntervalEnvVar} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_Help(t *testing.T) {
+	t.Setenv(configPathEnvVar, filepath.Join(t.TempDir(), "missing.toml"))
+	for _, arg := range []string{"-h", "--help"} {
+		if _, _, err := LoadConfig([]string{arg}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%s: expected flag.ErrHelp so usage is printed, got %v", arg, err)
+		}
+	}
+}
+
+func TestFindProjectDB(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if _, found := findProjectDB(nested); found {
+		t.Fatal("expected no project database yet")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, projectDBFileName), nil, 0600); err != nil {
+		t.Fatalf("write .todo.db: %v", err)
+	}
+	if path, _ := findProjectDB(nested); path != filepath.Join(root, projectDBFileName) {
+		t.Errorf("expected the root .todo.db, got %q", path)
+	}
+
+	// The nearest marker wins
+	if err := os.Mkdir(filepath.Join(root, "src", projectDirName), 0755); err != nil {
+		t.Fatalf("mkdir .todo: %v", err)
+	}
+	if path, _ := findProjectDB(nested); path != filepath.Join(root, "src", projectDirName, dbFileName) {
+		t.Errorf("expected src/.todo/todo.db, got %q", path)
+	}
+}
+
+func TestLoadConfig_ProjectDB(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, projectDirName), 0755); err != nil {
+		t.Fatalf("mkdir .todo: %v", err)
+	}
+	t.Chdir(root)
+	t.Setenv(configPathEnvVar, writeConfigFile(t, `db_path = "/file/todo.db"`))
+
+	cfg, _, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Sources["db_path"] != SourceProject || dbLabel(cfg) != "project "+filepath.Base(root) {
+		t.Errorf("expected the project database over the config file, got %q from %s", cfg.DBPath, cfg.Sources["db_path"])
+	}
+
+	t.Setenv(dbPathEnvVar, "/env/todo.db")
+	if cfg, _, _ = LoadConfig(nil); cfg.DBPath != "/env/todo.db" {
+		t.Errorf("expected TODO_DB_PATH to beat the project database, got %q", cfg.DBPath)
+	}
+}
+
+func TestLoadConfig_Profiles(t *testing.T) {
+	path := writeConfigFile(t, `
+profile = "work"
+db_path = "/file/todo.db"
+theme = "light"
+
+[profiles.work]
+db_path = "/work/todo.db"
+
+[profiles.work.sync]
+enabled = true
+
+[profiles.home]
+db_path = "/home/todo.db"
+`)
+	t.Setenv(configPathEnvVar, path)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	cfg, _, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Profile != "work" || cfg.DBPath != "/work/todo.db" || !cfg.Sync.Enabled {
+		t.Errorf("expected the work profile from the file, got %q with %q, sync %v", cfg.Profile, cfg.DBPath, cfg.Sync.Enabled)
+	}
+	if cfg.Sources["db_path"] != SourceProfile || cfg.Theme != "light" {
+		t.Errorf("expected profile values over top-level ones, got db_path from %s and theme %q", cfg.Sources["db_path"], cfg.Theme)
+	}
+	if strings.Join(cfg.Profiles, ",") != "home,work" {
+		t.Errorf("expected sorted profiles, got %v", cfg.Profiles)
+	}
+
+	t.Setenv(profileEnvVar, "home")
+	if cfg, _, _ = LoadConfig(nil); cfg.DBPath != "/home/todo.db" {
+		t.Errorf("expected TODO_PROFILE to pick home, got %q", cfg.DBPath)
+	}
+	if cfg, _, _ = LoadConfig([]string{"--profile", DefaultProfile}); cfg.Profile != "" || cfg.DBPath != "/file/todo.db" {
+		t.Errorf("expected --profile default to use the top-level settings, got %q", cfg.DBPath)
+	}
+
+	switched, err := cfg.WithProfile("work")
+	if err != nil || switched.DBPath != "/work/todo.db" {
+		t.Errorf("expected WithProfile to load work, got %q: %v", switched.DBPath, err)
+	}
+	if _, err := cfg.WithProfile("school"); err == nil || !strings.Contains(err.Error(), "school") {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	path := writeConfigFile(t, `
+theme = "Solarized"
+
+[themes.solarized]
+base = "light"
+accent = "#268bd2"
+priority_high = "#dc322f,#ff6e67"
+reverse_selection = true
+`)
+	t.Setenv(configPathEnvVar, path)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	cfg, _, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	theme, err := loadTheme(cfg)
+	if err != nil {
+		t.Fatalf("load theme: %v", err)
+	}
+	if theme.Accent != lipgloss.Color("#268bd2") || theme.Muted != lightTheme.Muted || !theme.ReverseSelection {
+		t.Errorf("expected accent and selection over the light theme, got %+v", theme)
+	}
+	if theme.PriorityHigh != (lipgloss.AdaptiveColor{Light: "#dc322f", Dark: "#ff6e67"}) {
+		t.Errorf("expected an adaptive high priority color, got %v", theme.PriorityHigh)
+	}
+
+	for _, bad := range []string{
+		`theme = "neon"`,
+		"theme = \"mine\"\n[themes.mine]\naccent = \"blue\"",
+		"theme = \"mine\"\n[themes.mine]\nbase = \"mine\"",
+		"[themes.mine]\nborder = \"#fff\"\n[sync]\nenabled = false\n",
+	} {
+		cfg.Theme = defaultTheme
+		cfg.Themes = map[string]map[string]string{}
+		t.Setenv(configPathEnvVar, writeConfigFile(t, bad))
+		if _, _, err := LoadConfig(nil); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestUseTheme_NoColor(t *testing.T) {
+	t.Cleanup(func() { applyTheme(BuiltinThemes[ThemeDefault]) })
+	t.Setenv(noColorEnvVar, "1")
+
+	if err := useTheme(Config{Theme: ThemeDark}); err != nil {
+		t.Fatalf("use theme: %v", err)
+	}
+	if _, isColor := OneStyle.GetForeground().(lipgloss.NoColor); !isColor || !SelectedStyle.GetReverse() {
+		t.Errorf("expected no colors and a reverse video selection with NO_COLOR")
+	}
+}
+
+func TestNewKeyMap(t *testing.T) {
+	keys, err := newKeyMap(KeymapDefault, map[string]string{"add": "n, ctrl+n", "toggle_done": "space"})
+	if err != nil {
+		t.Fatalf("new keymap: %v", err)
+	}
+	if strings.Join(keys.Add.Keys(), " ") != "n ctrl+n" || keys.Add.Help().Desc != "add" {
+		t.Errorf("expected add on n and ctrl+n, got %v", keys.Add.Keys())
+	}
+	if keys.ToggleDone.Keys()[0] != " " || keys.ToggleDone.Help().Key != "space" {
+		t.Errorf("expected space to be spelled out, got %q", keys.ToggleDone.Keys())
+	}
+
+	for _, preset := range KeymapPresets {
+		if _, err := newKeyMap(preset, nil); err != nil {
+			t.Errorf("preset %s: %v", preset, err)
+		}
+	}
+	if vim, _ := newKeyMap(KeymapVim, nil); vim.Group.Keys()[0] != "z" {
+		t.Errorf("expected vim grouping on z, got %v", vim.Group.Keys())
+	}
+
+	_, err = newKeyMap(KeymapDefault, map[string]string{"add": "d", "fly": "f"})
+	if err == nil {
+		t.Fatal("expected errors for a conflict and an unknown action")
+	}
+	for _, want := range []string{`"d" is bound to both`, "task list", `unknown action "fly"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in error: %v", want, err)
+		}
+	}
+
+	// Keys may repeat across screens
+	if _, err := newKeyMap(KeymapDefault, map[string]string{"restore": "a"}); err != nil {
+		t.Errorf("expected a trash key to be free to reuse a task list key: %v", err)
+	}
+
+	// Actions clash even when they share a description, but a binding
+	// listing a key twice does not clash with itself
+	groups := [][]key.Binding{{binding("delete", "d")}, {binding("delete", "d", "x", "x")}}
+	if conflicts := keyConflicts(screenTaskList, groups); len(conflicts) != 1 || !strings.Contains(conflicts[0], `"d"`) {
+		t.Errorf("expected only d to conflict, got %v", conflicts)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configFileName is the name of the config file inside the config directory
+const configFileName = "config.toml"
+
+// configPathEnvVar points at a config file other than the default one
+const configPathEnvVar = "TODO_CONFIG"
+
+// configDir returns $XDG_CONFIG_HOME/commandlinetodo, falling back to the
+// platform's user config directory
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appConfigDirName), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appConfigDirName), nil
+}
+
+// dataDir returns $XDG_DATA_HOME/commandlinetodo, falling back to
+// ~/.local/share/commandlinetodo
+func dataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appConfigDirName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", appConfigDirName), nil
+}
+
+// defaultConfigPath returns the config file location, or an empty string if
+// there is no config directory
+func defaultConfigPath() string {
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, configFileName)
+}
+
+// readConfigFile loads a config file into a map of dotted keys to values.
+// A missing file is not an error and gives an empty map.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values, err := parseConfigFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseConfigFile decodes a TOML config file. Keys inside tables are
+// returned as "table.key", and values must be strings, integers or
+// booleans.
+func parseConfigFile(r io.Reader) (map[string]string, error) {
+	var raw map[string]interface{}
+	if _, err := toml.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if err := flattenConfig(values, "", raw); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flattenConfig adds the values of a decoded table to values under dotted
+// keys starting with prefix
+func flattenConfig(values map[string]string, prefix string, table map[string]interface{}) error {
+	for key, raw := range table {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := raw.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(values, key, value); err != nil {
+				return err
+			}
+		case string:
+			values[key] = value
+		case int64:
+			values[key] = strconv.FormatInt(value, 10)
+		case bool:
+			values[key] = strconv.FormatBool(value)
+		default:
+			return fmt.Errorf("%s: unsupported value %v (use a string, integer or boolean)", key, value)
+		}
+	}
+	return nil
+}
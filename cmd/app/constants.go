@@ -1,43 +1,33 @@
 package main
 
 import (
+	"time"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Styling constants
+// Styles, built from the active theme by applyTheme
 var (
-	TitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#3c71A8")).
-			MarginBottom(1)
-	SelectedStyle     = lipgloss.NewStyle().Background(lipgloss.Color("#A3A3A3"))
-	DoneStyle         = lipgloss.NewStyle().Strikethrough(true)
-	SelectedDoneStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#A3A3A3")).
-				Strikethrough(true)
-	ItemStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#3c71A8"))
-	DocStyle         = lipgloss.NewStyle().Margin(0)
-	OneStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
-	TwoStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
-	ThreeStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00"))
-	FourStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("#008000"))
-	SelectedOneStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#A3A3A3")).
-				Foreground(lipgloss.Color("#FF0000"))
-	SelectedTwoStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#A3A3A3")).
-				Foreground(lipgloss.Color("#FFA500"))
-	SelectedThreeStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#A3A3A3")).
-				Foreground(lipgloss.Color("#FFFF00"))
-	SelectedFourStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#A3A3A3")).
-				Foreground(lipgloss.Color("#008000"))
-	OverdueStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6B6B"))
-	ErrorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF0000")).
-			Bold(true)
+	TitleStyle         lipgloss.Style
+	SelectedStyle      lipgloss.Style
+	DoneStyle          lipgloss.Style
+	SelectedDoneStyle  lipgloss.Style
+	ItemStyle          lipgloss.Style
+	DocStyle           lipgloss.Style
+	OneStyle           lipgloss.Style
+	TwoStyle           lipgloss.Style
+	ThreeStyle         lipgloss.Style
+	FourStyle          lipgloss.Style
+	SelectedOneStyle   lipgloss.Style
+	SelectedTwoStyle   lipgloss.Style
+	SelectedThreeStyle lipgloss.Style
+	SelectedFourStyle  lipgloss.Style
+	OverdueStyle       lipgloss.Style
+	ErrorStyle         lipgloss.Style
+	StatusStyle        lipgloss.Style
+	GroupHeaderStyle   lipgloss.Style
+	ArchivedStyle      lipgloss.Style
+	DetailPaneStyle    lipgloss.Style
 )
 
 // Input modes
@@ -60,6 +50,12 @@ const (
 	StateDeleteConfirm
 	StateListSelector
 	StateListNameInput
+	StateTrash
+	StateArchivedView
+	StateMoveTask
+	StateTaskDetail
+	StateProfilePicker
+	StateCalendar
 )
 
 // Sub-states - Context modifiers for complex states
@@ -72,6 +68,49 @@ const (
 	SubStateEditDueDate
 	SubStateListRename
 	SubStateListCreate
+	SubStateTrashPurgeConfirm
+	SubStateMoveTask
+	SubStateCopyTask
+	SubStateBulkDelete
+	SubStateBulkDueDate
+	SubStateDetailEdit
+	SubStateEditPriority
+	SubStateBulkPriority
+)
+
+// Editable fields of the task detail view, in display order
+const (
+	DetailFieldText = iota
+	DetailFieldPriority
+	DetailFieldDueDate
+	DetailFieldList
+	DetailFieldStatus
+	DetailFieldCount
+)
+
+// Sort modes stored per list
+const (
+	SortModePriority  = ""
+	SortModeManual    = "manual"
+	SortModeDueDate   = "due"
+	SortModeCreated   = "created"
+	SortModeCompleted = "completed"
+	SortModeAlpha     = "alpha"
+)
+
+// Group modes stored per list
+const (
+	GroupModeNone     = ""
+	GroupModePriority = "priority"
+	GroupModeDueDate  = "due"
+	GroupModeTag      = "tag"
+)
+
+// Completed task filters, cycled with h
+const (
+	CompletedShowAll = iota
+	CompletedHide
+	CompletedToday
 )
 
 // Priority levels
@@ -82,44 +121,18 @@ const (
 	PriorityLow     = 4
 )
 
-// Keyboard shortcuts
-const (
-	KeyUp    = "up"
-	KeyDown  = "down"
-	KeyEnter = "enter"
-	KeyEsc   = "esc"
-	KeyQ     = "q"
-	KeyCtrlC = "ctrl+c"
-	KeySpace = " "
-	KeyK     = "k"
-	KeyJ     = "j"
-	KeyA     = "a"
-	KeyE     = "e"
-	KeyD     = "d"
-	KeyT     = "t"
-	KeyY     = "y"
-	KeyN     = "n"
-	KeyW     = "w"
-	KeyR     = "r"
-	KeyL     = "l"
-	KeyM     = "m"
-	KeyS     = "s"
-)
-
-// Priority selection keys
-const (
-	KeyPriority1 = "1"
-	KeyPriority2 = "2"
-	KeyPriority3 = "3"
-	KeyPriority4 = "4"
-)
-
 // Viewport configuration
 const (
-	ViewportWidth     = 80
-	ViewportHeight    = 20
-	ViewportBaseLines = 5
-	LinesPerTask      = 3
+	ViewportWidth      = 80
+	ViewportHeight     = 20
+	ViewportBaseLines  = 5
+	TrashChromeLines   = 7   // Title, spacing, help and message lines around the trash list
+	DetailPaneWidth    = 44  // Columns taken by the detail pane beside the list
+	DetailPaneMinWidth = 120 // Narrowest terminal that shows the detail pane
+	TaskLines          = 3   // Lines a task takes in the list: text, dates and a gap
+	PaletteChromeLines = 6   // Title, input, spacing and help lines around the palette's matches
+	CalendarCellWidth  = 11  // Columns per day in the calendar, less on narrow terminals
+	CalendarWeekRows   = 8   // Tasks listed under each day in the week layout
 )
 
 // Text input configuration
@@ -137,17 +150,34 @@ const (
 // UI text
 const (
 	TextInputPlaceholder = "Enter task description..."
+	MarkPrefix           = "● " // Shown before tasks marked for bulk actions
+	CheckboxOpen         = "[ ] "
+	CheckboxDone         = "[x] " // Same width as CheckboxOpen
 )
 
+// DetailTimeFormat is how the detail view shows timestamps
+const DetailTimeFormat = "2006-01-02 15:04"
+
 // Time calculations
 const (
 	SecondsPerDay = 24 * 3600
 	MaxDaysOffset = 36500 // 100 years
 )
 
+// StoreTimeout bounds each storage call made from the UI
+const StoreTimeout = 5 * time.Second
+
+// MaxUndoHistory caps how many actions can be undone
+const MaxUndoHistory = 100
+
 var (
 	PriorityStyles         map[int]lipgloss.Style
 	PriorityLabels         map[int]string
+	SortModes              []string // Order the sort key cycles through
+	SortModeLabels         map[string]string
+	GroupModes             []string // Order the grouping cycles through
+	GroupModeLabels        map[string]string
+	CompletedFilterLabels  map[int]string
 	SelectedPriorityStyles map[int]lipgloss.Style
 	StateHeightAdjustments map[AppState]int
 )
@@ -156,27 +186,45 @@ func init() {
 
 	StateHeightAdjustments = map[AppState]int{
 		StateMainBrowse:        0,
-		StateTaskInput:         6,
+		StateTaskInput:         7,
 		StatePrioritySelection: 11,
 		StateDueDateInput:      6,
 		StateEditTask:          6,
 		StateDeleteConfirm:     2,
 		StateListSelector:      0,
 		StateListNameInput:     6,
+		StateTrash:             0,
+		StateArchivedView:      3,
+		StateMoveTask:          0,
+		StateTaskDetail:        0,
+		StateProfilePicker:     0,
+		StateCalendar:          0,
+	}
+
+	applyTheme(BuiltinThemes[ThemeDefault])
+
+	SortModes = []string{SortModePriority, SortModeManual, SortModeDueDate, SortModeCreated, SortModeCompleted, SortModeAlpha}
+	SortModeLabels = map[string]string{
+		SortModePriority:  "priority",
+		SortModeManual:    "manual",
+		SortModeDueDate:   "due date",
+		SortModeCreated:   "newest first",
+		SortModeCompleted: "recently completed",
+		SortModeAlpha:     "alphabetical",
 	}
 
-	PriorityStyles = map[int]lipgloss.Style{
-		PriorityHigh:    OneStyle,
-		PriorityMedHigh: TwoStyle,
-		PriorityMed:     ThreeStyle,
-		PriorityLow:     FourStyle,
+	GroupModes = []string{GroupModeNone, GroupModePriority, GroupModeDueDate, GroupModeTag}
+	GroupModeLabels = map[string]string{
+		GroupModeNone:     "none",
+		GroupModePriority: "priority",
+		GroupModeDueDate:  "due date",
+		GroupModeTag:      "tag",
 	}
 
-	SelectedPriorityStyles = map[int]lipgloss.Style{
-		PriorityHigh:    SelectedOneStyle,
-		PriorityMedHigh: SelectedTwoStyle,
-		PriorityMed:     SelectedThreeStyle,
-		PriorityLow:     SelectedFourStyle,
+	CompletedFilterLabels = map[int]string{
+		CompletedShowAll: "all tasks",
+		CompletedHide:    "hiding completed",
+		CompletedToday:   "completed today",
 	}
 
 	PriorityLabels = map[int]string{
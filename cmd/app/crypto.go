@@ -0,0 +1,398 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrSyncKeyMismatch is returned when a sync payload cannot be decrypted
+// with any key known to this device
+var ErrSyncKeyMismatch = errors.New("sync data is encrypted with a key this device does not have; run 'sync-key init SALT' with the passphrase and salt used on your other devices")
+
+// Key derivation parameters. Every key gets its own random salt, which is
+// kept in the keyfile and sent with the payloads the key seals. Other
+// devices derive the same key from the same passphrase and salt.
+const (
+	syncKeySaltLength = 16
+	syncKeyIterations = 600000
+	syncKeyLength     = 32
+)
+
+// syncKey is a single derived key stored in the keyfile
+type syncKey struct {
+	ID        string `json:"id"`
+	Key       string `json:"key"`
+	Salt      string `json:"salt"` // Hex encoded
+	CreatedAt int64  `json:"created_at"`
+}
+
+// syncKeyFile is the on-disk representation of the local keyring
+type syncKeyFile struct {
+	Current string    `json:"current"`
+	Keys    []syncKey `json:"keys"`
+}
+
+// SyncCipher encrypts and decrypts sync payloads using a local keyring.
+// New payloads are sealed with the current key; older keys are kept so
+// data written before a rotation can still be read.
+type SyncCipher struct {
+	path      string
+	currentID string
+	keys      map[string][]byte
+	salts     map[string]string
+	order     []syncKey
+}
+
+// deriveSyncKey derives an AES-256 key from a passphrase and a hex encoded
+// salt
+func deriveSyncKey(passphrase string, salt string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	rawSalt, err := hex.DecodeString(salt)
+	if err != nil || len(rawSalt) == 0 {
+		return nil, fmt.Errorf("invalid salt %q", salt)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, rawSalt, syncKeyIterations, syncKeyLength)
+}
+
+// newSyncKeySalt returns a random hex encoded salt for a new key
+func newSyncKeySalt() (string, error) {
+	salt := make([]byte, syncKeySaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// syncKeyID returns a short, non-secret fingerprint of a key
+func syncKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// LoadSyncCipher reads the keyring at path
+func LoadSyncCipher(path string) (*SyncCipher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file syncKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid sync keyfile %s: %w", path, err)
+	}
+
+	c := &SyncCipher{
+		path:      path,
+		currentID: file.Current,
+		keys:      make(map[string][]byte),
+		salts:     make(map[string]string),
+	}
+	for _, k := range file.Keys {
+		raw, err := base64.StdEncoding.DecodeString(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s in sync keyfile: %w", k.ID, err)
+		}
+		c.keys[k.ID] = raw
+		c.salts[k.ID] = k.Salt
+		c.order = append(c.order, k)
+	}
+
+	if _, ok := c.keys[c.currentID]; !ok {
+		return nil, fmt.Errorf("sync keyfile %s has no current key", path)
+	}
+
+	return c, nil
+}
+
+// NewSyncCipher creates a keyring at path from a passphrase, replacing any
+// existing keyfile. An empty salt creates a new random one; pass the salt
+// of a key made on another device to derive the same key.
+func NewSyncCipher(path string, passphrase string, salt string) (*SyncCipher, error) {
+	c := &SyncCipher{
+		path:  path,
+		keys:  make(map[string][]byte),
+		salts: make(map[string]string),
+	}
+	if err := c.addKey(passphrase, salt); err != nil {
+		return nil, err
+	}
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RotateKey derives a new current key from passphrase and salt, an empty
+// salt creating a new one. Previous keys are retained for decrypting
+// payloads that have not been re-encrypted yet.
+func (c *SyncCipher) RotateKey(passphrase string, salt string) error {
+	if err := c.addKey(passphrase, salt); err != nil {
+		return err
+	}
+	return c.save()
+}
+
+// CurrentKeyID returns the ID of the key used for new payloads
+func (c *SyncCipher) CurrentKeyID() string {
+	return c.currentID
+}
+
+// KeySalt returns the salt of the key with the given ID, which other
+// devices need to derive the same key
+func (c *SyncCipher) KeySalt(id string) string {
+	return c.salts[id]
+}
+
+// KeyIDs returns the IDs of all keys in the keyring, oldest first
+func (c *SyncCipher) KeyIDs() []string {
+	ids := make([]string, len(c.order))
+	for i, k := range c.order {
+		ids[i] = k.ID
+	}
+	return ids
+}
+
+func (c *SyncCipher) addKey(passphrase string, salt string) error {
+	if salt == "" {
+		var err error
+		if salt, err = newSyncKeySalt(); err != nil {
+			return err
+		}
+	}
+	key, err := deriveSyncKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	id := syncKeyID(key)
+	if _, exists := c.keys[id]; !exists {
+		c.keys[id] = key
+		c.salts[id] = salt
+		c.order = append(c.order, syncKey{
+			ID:        id,
+			Key:       base64.StdEncoding.EncodeToString(key),
+			Salt:      salt,
+			CreatedAt: time.Now().Unix(),
+		})
+	}
+	c.currentID = id
+	return nil
+}
+
+func (c *SyncCipher) save() error {
+	data, err := json.MarshalIndent(syncKeyFile{Current: c.currentID, Keys: c.order}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(c.path, data, 0600)
+}
+
+// seal encrypts plaintext with the current key. The client ID is bound as
+// additional data so a blob cannot be replayed onto another entity.
+func (c *SyncCipher) seal(clientID string, plaintext []byte) (string, string, error) {
+	gcm, err := newGCM(c.keys[c.currentID])
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(clientID))
+	return c.currentID, base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a blob produced by seal. The salt sent with the blob is
+// only used to tell the user which key is missing.
+func (c *SyncCipher) open(clientID string, keyID string, salt string, blob string) ([]byte, error) {
+	key, ok := c.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w (key %s, salt %s)", ErrSyncKeyMismatch, keyID, salt)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext for %s: %w", clientID, err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid ciphertext for %s: too short", clientID)
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(clientID))
+	if err != nil {
+		return nil, fmt.Errorf("%w (key %s)", ErrSyncKeyMismatch, keyID)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealTask replaces a task payload with an encrypted envelope that only
+// exposes the client ID and version
+func (c *SyncCipher) SealTask(p TaskPayload) (TaskPayload, error) {
+	plaintext, err := json.Marshal(p)
+	if err != nil {
+		return TaskPayload{}, err
+	}
+	keyID, blob, err := c.seal(p.ClientID, plaintext)
+	if err != nil {
+		return TaskPayload{}, err
+	}
+	return TaskPayload{ClientID: p.ClientID, Version: p.Version, KeyID: keyID, KeySalt: c.salts[keyID], Ciphertext: blob}, nil
+}
+
+// OpenTask restores a task payload sealed by SealTask
+func (c *SyncCipher) OpenTask(p TaskPayload) (TaskPayload, error) {
+	if p.Ciphertext == "" {
+		return p, nil
+	}
+	plaintext, err := c.open(p.ClientID, p.KeyID, p.KeySalt, p.Ciphertext)
+	if err != nil {
+		return TaskPayload{}, err
+	}
+	var opened TaskPayload
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return TaskPayload{}, err
+	}
+	return opened, nil
+}
+
+// SealList replaces a list payload with an encrypted envelope that only
+// exposes the client ID and version
+func (c *SyncCipher) SealList(p ListPayload) (ListPayload, error) {
+	plaintext, err := json.Marshal(p)
+	if err != nil {
+		return ListPayload{}, err
+	}
+	keyID, blob, err := c.seal(p.ClientID, plaintext)
+	if err != nil {
+		return ListPayload{}, err
+	}
+	return ListPayload{ClientID: p.ClientID, Version: p.Version, KeyID: keyID, KeySalt: c.salts[keyID], Ciphertext: blob}, nil
+}
+
+// OpenList restores a list payload sealed by SealList
+func (c *SyncCipher) OpenList(p ListPayload) (ListPayload, error) {
+	if p.Ciphertext == "" {
+		return p, nil
+	}
+	plaintext, err := c.open(p.ClientID, p.KeyID, p.KeySalt, p.Ciphertext)
+	if err != nil {
+		return ListPayload{}, err
+	}
+	var opened ListPayload
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return ListPayload{}, err
+	}
+	return opened, nil
+}
+
+// SealPush encrypts every payload in a push request
+func (c *SyncCipher) SealPush(req *PushRequest) error {
+	for i, task := range req.Tasks {
+		sealed, err := c.SealTask(task)
+		if err != nil {
+			return err
+		}
+		req.Tasks[i] = sealed
+	}
+	for i, list := range req.Lists {
+		sealed, err := c.SealList(list)
+		if err != nil {
+			return err
+		}
+		req.Lists[i] = sealed
+	}
+	return nil
+}
+
+// OpenPull decrypts every payload in a pull response
+func (c *SyncCipher) OpenPull(resp *PullResponse) error {
+	for i, task := range resp.Tasks {
+		opened, err := c.OpenTask(task)
+		if err != nil {
+			return err
+		}
+		resp.Tasks[i] = opened
+	}
+	for i, list := range resp.Lists {
+		opened, err := c.OpenList(list)
+		if err != nil {
+			return err
+		}
+		resp.Lists[i] = opened
+	}
+	return nil
+}
+
+// checkPlaintextPull reports an error if encrypted payloads arrive on a
+// device that has encryption disabled
+func checkPlaintextPull(resp *PullResponse) error {
+	for _, task := range resp.Tasks {
+		if task.Ciphertext != "" {
+			return fmt.Errorf("%w (key %s; encryption is disabled on this device)", ErrSyncKeyMismatch, task.KeyID)
+		}
+	}
+	for _, list := range resp.Lists {
+		if list.Ciphertext != "" {
+			return fmt.Errorf("%w (key %s; encryption is disabled on this device)", ErrSyncKeyMismatch, list.KeyID)
+		}
+	}
+	return nil
+}
+
+// openSyncCipher loads the keyring configured for sync. It returns nil when
+// encryption is disabled and creates the keyfile from TODO_SYNC_PASSPHRASE
+// and TODO_SYNC_KEY_SALT on first use.
+func openSyncCipher(cfg SyncConfig) (*SyncCipher, error) {
+	if !cfg.Encrypt {
+		return nil, nil
+	}
+
+	c, err := LoadSyncCipher(cfg.KeyFile)
+	if err == nil {
+		return c, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if passphrase := os.Getenv(syncPassphraseEnvVar); passphrase != "" {
+		return NewSyncCipher(cfg.KeyFile, passphrase, os.Getenv(syncKeySaltEnvVar))
+	}
+	return nil, fmt.Errorf("sync encryption is enabled but no key exists at %s; run 'sync-key init' or set %s", cfg.KeyFile, syncPassphraseEnvVar)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncCipher_RoundTrip(t *testing.T) {
+	c, err := NewSyncCipher(filepath.Join(t.TempDir(), "sync.key"), "correct horse", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+
+	task := TaskPayload{ClientID: "abc", Todo: "secret task", Priority: 2, Version: 3, UpdatedAt: 100}
+	sealed, err := c.SealTask(task)
+	if err != nil {
+		t.Fatalf("seal task: %v", err)
+	}
+
+	if sealed.Todo != "" || sealed.Priority != 0 || sealed.UpdatedAt != 0 {
+		t.Errorf("sealed payload leaks plaintext fields: %+v", sealed)
+	}
+	if sealed.ClientID != "abc" || sealed.Version != 3 || sealed.KeySalt != c.KeySalt(c.CurrentKeyID()) {
+		t.Errorf("sealed payload should keep client ID and version and name the key's salt, got %+v", sealed)
+	}
+
+	opened, err := c.OpenTask(sealed)
+	if err != nil {
+		t.Fatalf("open task: %v", err)
+	}
+	if opened.Todo != task.Todo || opened.Priority != task.Priority || opened.UpdatedAt != task.UpdatedAt {
+		t.Errorf("expected %+v, got %+v", task, opened)
+	}
+}
+
+func TestSyncCipher_WrongKey(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewSyncCipher(filepath.Join(dir, "a.key"), "passphrase one", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	b, err := NewSyncCipher(filepath.Join(dir, "b.key"), "passphrase two", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+
+	sealed, err := a.SealList(ListPayload{ClientID: "list", Name: "Work"})
+	if err != nil {
+		t.Fatalf("seal list: %v", err)
+	}
+
+	if _, err := b.OpenList(sealed); !errors.Is(err, ErrSyncKeyMismatch) {
+		t.Errorf("expected ErrSyncKeyMismatch, got %v", err)
+	}
+
+	if err := checkPlaintextPull(&PullResponse{Lists: []ListPayload{sealed}}); !errors.Is(err, ErrSyncKeyMismatch) {
+		t.Errorf("expected ErrSyncKeyMismatch for unencrypted device, got %v", err)
+	}
+}
+
+func TestSyncCipher_RotationKeepsOldKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.key")
+	c, err := NewSyncCipher(path, "old passphrase", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+
+	oldSealed, err := c.SealTask(TaskPayload{ClientID: "t1", Todo: "before rotation"})
+	if err != nil {
+		t.Fatalf("seal task: %v", err)
+	}
+	oldID := c.CurrentKeyID()
+
+	if err := c.RotateKey("new passphrase", ""); err != nil {
+		t.Fatalf("rotate key: %v", err)
+	}
+	if c.CurrentKeyID() == oldID {
+		t.Fatal("expected current key to change after rotation")
+	}
+
+	reloaded, err := LoadSyncCipher(path)
+	if err != nil {
+		t.Fatalf("reload cipher: %v", err)
+	}
+	if reloaded.CurrentKeyID() != c.CurrentKeyID() {
+		t.Errorf("expected current key %s after reload, got %s", c.CurrentKeyID(), reloaded.CurrentKeyID())
+	}
+
+	opened, err := reloaded.OpenTask(oldSealed)
+	if err != nil {
+		t.Fatalf("open task sealed with old key: %v", err)
+	}
+	if opened.Todo != "before rotation" {
+		t.Errorf("expected old payload to decrypt, got %q", opened.Todo)
+	}
+
+	newSealed, err := reloaded.SealTask(TaskPayload{ClientID: "t1"})
+	if err != nil {
+		t.Fatalf("seal task: %v", err)
+	}
+	if newSealed.KeyID != c.CurrentKeyID() {
+		t.Errorf("expected new payloads to use key %s, got %s", c.CurrentKeyID(), newSealed.KeyID)
+	}
+}
+
+func TestSyncCipher_SaltPerKey(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewSyncCipher(filepath.Join(dir, "a.key"), "shared secret", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	b, err := NewSyncCipher(filepath.Join(dir, "b.key"), "shared secret", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	if a.CurrentKeyID() == b.CurrentKeyID() {
+		t.Fatal("expected new keys from the same passphrase to get different salts")
+	}
+
+	joined, err := NewSyncCipher(filepath.Join(dir, "joined.key"), "shared secret", a.KeySalt(a.CurrentKeyID()))
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	if joined.CurrentKeyID() != a.CurrentKeyID() {
+		t.Errorf("expected the same passphrase and salt to derive key %s, got %s", a.CurrentKeyID(), joined.CurrentKeyID())
+	}
+
+	sealed, err := a.SealTask(TaskPayload{ClientID: "t1", Todo: "shared"})
+	if err != nil {
+		t.Fatalf("seal task: %v", err)
+	}
+	if _, err := b.OpenTask(sealed); !errors.Is(err, ErrSyncKeyMismatch) || !strings.Contains(err.Error(), sealed.KeySalt) {
+		t.Errorf("expected a key mismatch naming the salt, got %v", err)
+	}
+
+	if _, err := NewSyncCipher(filepath.Join(dir, "bad.key"), "shared secret", "not hex"); err == nil {
+		t.Error("expected an invalid salt to be rejected")
+	}
+}
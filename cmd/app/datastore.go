@@ -1,34 +1,61 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 )
 
-// DataStore interface abstracts all data access operations
+// ErrNotSupported is returned by backends that cannot perform an operation
+var ErrNotSupported = errors.New("operation not supported by this storage backend")
+
+// DataStore interface abstracts all data access operations. Every call
+// takes a context so callers can cancel or time out slow storage.
 type DataStore interface {
 	// Lists
-	GetTodoLists() ([]todoList, error)
-	CreateTodoList(name string) (int, error)
-	UpdateTodoListName(id int, name string) error
-	DeleteTodoList(id int) error
-	ArchiveTodoList(id int) error
-	UnarchiveTodoList(id int) error
+	GetTodoLists(ctx context.Context) ([]todoList, error)
+	CreateTodoList(ctx context.Context, name string) (int, error)
+	UpdateTodoListName(ctx context.Context, id int, name string) error
+	DeleteTodoList(ctx context.Context, id int) error
+	ArchiveTodoList(ctx context.Context, id int) error
+	UnarchiveTodoList(ctx context.Context, id int) error
+	GetArchivedTodoLists(ctx context.Context) ([]todoList, error)
+	ReorderTodoLists(ctx context.Context, ids []int) error
+	SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error
+	RestoreTodoList(ctx context.Context, list todoList) error
+	GetDeletedTodoLists(ctx context.Context) ([]todoList, error)
+	PurgeTodoList(ctx context.Context, id int) error
 
 	// Tasks
-	GetItems() ([]todoItem, error)
-	GetItemByID(id int) (todoItem, error)
-	GetItemByClientID(clientID string) (todoItem, error)
-	SaveItem(item todoItem) error
-	UpdateItem(item todoItem) error
-	DeleteItem(id int) error
+	GetItems(ctx context.Context) ([]todoItem, error)
+	GetItemByID(ctx context.Context, id int) (todoItem, error)
+	GetItemByClientID(ctx context.Context, clientID string) (todoItem, error)
+	SaveItem(ctx context.Context, item todoItem) (int, error)
+	UpdateItem(ctx context.Context, item todoItem) error
+	DeleteItem(ctx context.Context, id int) error
+	RestoreItem(ctx context.Context, item todoItem) error
+	GetDeletedItems(ctx context.Context) ([]todoItem, error)
+	PurgeItem(ctx context.Context, id int) error
+
+	// Bulk task changes, applied all-or-nothing
+	UpdateItems(ctx context.Context, items []todoItem) error
+	DeleteItems(ctx context.Context, ids []int) error
+	RestoreItems(ctx context.Context, items []todoItem) error
+
+	// Trash
+	PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error)
+
+	// Completed task history, kept out of GetItems
+	ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error)
+	GetHistory(ctx context.Context, query string) ([]historyItem, error)
 
 	// Sync metadata
-	GetLastSyncTime() (int64, error)
-	SetLastSyncTime(timestamp int64) error
-	GetPendingChanges() ([]Change, error)
-	MarkChangeSynced(changeID int) error
-	LogChange(entityType string, entityID int, changeType string) error
+	GetLastSyncTime(ctx context.Context) (int64, error)
+	SetLastSyncTime(ctx context.Context, timestamp int64) error
+	GetPendingChanges(ctx context.Context) ([]Change, error)
+	MarkChangeSynced(ctx context.Context, changeID int) error
+	LogChange(ctx context.Context, entityType string, entityID int, changeType string) error
 }
 
 // LocalStore implements DataStore for local SQLite database
@@ -43,121 +70,655 @@ func NewLocalStore(database *sql.DB) *LocalStore {
 	}
 }
 
-// GetTodoLists retrieves all non-archived todo lists
-func (s *LocalStore) GetTodoLists() ([]todoList, error) {
-	return getTodoLists()
+// Close closes the underlying database handle
+func (s *LocalStore) Close() error {
+	return s.db.Close()
+}
+
+// GetTodoLists retrieves all non-archived, non-deleted todo lists
+func (s *LocalStore) GetTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.queryTodoLists(ctx, "WHERE archived = 0 AND deleted = 0 ORDER BY display_order")
+}
+
+// ReorderTodoLists sets display_order to follow the order of ids
+func (s *LocalStore) ReorderTodoLists(ctx context.Context, ids []int) error {
+	return s.inTx(ctx, "reorder todo lists", func(tx *sql.Tx) error {
+		return reorderTodoLists(ctx, tx, ids, now())
+	})
+}
+
+func reorderTodoLists(ctx context.Context, conn sqlExecer, ids []int, timestamp int64) error {
+	for i, id := range ids {
+		if err := executeStmt(ctx, conn, "reorder todo list",
+			"UPDATE todoLists SET display_order = ?, updated_at = ? WHERE id = ?",
+			i, timestamp, id,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTodoListView remembers how a list's tasks are sorted and grouped
+func (s *LocalStore) SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error {
+	return executeStmt(ctx, s.db, "set list view",
+		"UPDATE todoLists SET sort_mode = ?, group_mode = ?, updated_at = ? WHERE id = ?",
+		sortMode, groupMode, now(), id,
+	)
+}
+
+// GetTodoListByClientID retrieves a list by its sync client ID
+func (s *LocalStore) GetTodoListByClientID(ctx context.Context, clientID string) (todoList, error) {
+	list, err := scanList(s.db.QueryRowContext(ctx, "SELECT "+listColumns+" FROM todoLists WHERE client_id = ? LIMIT 1", clientID))
+	if err == sql.ErrNoRows {
+		return todoList{}, fmt.Errorf("list not found with client_id: %s", clientID)
+	}
+	if err != nil {
+		logError("query list by client_id", err)
+		return todoList{}, err
+	}
+	return list, nil
+}
+
+// ApplyTodoListOrder sets a list's place and sort mode from another
+// device, keeping that device's update time so the change is not pushed
+// back as newer
+func (s *LocalStore) ApplyTodoListOrder(ctx context.Context, id int, displayOrder int, sortMode string, updatedAt int64) error {
+	return executeStmt(ctx, s.db, "apply list order",
+		"UPDATE todoLists SET display_order = ?, sort_mode = ?, updated_at = ? WHERE id = ?",
+		displayOrder, sortMode, updatedAt, id,
+	)
+}
+
+// GetArchivedTodoLists retrieves archived lists that are not in the trash
+func (s *LocalStore) GetArchivedTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.queryTodoLists(ctx, "WHERE archived = 1 AND deleted = 0 ORDER BY display_order")
+}
+
+// GetDeletedTodoLists retrieves the lists in the trash, newest first
+func (s *LocalStore) GetDeletedTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.queryTodoLists(ctx, "WHERE deleted = 1 ORDER BY deleted_at DESC")
+}
+
+func (s *LocalStore) queryTodoLists(ctx context.Context, where string) ([]todoList, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+listColumns+" FROM todoLists "+where)
+	if err != nil {
+		fmt.Println("Failed to query todoLists:", err)
+		return []todoList{}, err
+	}
+	defer rows.Close()
+
+	lists := []todoList{}
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			fmt.Println("Failed to scan todoList:", err)
+			return []todoList{}, err
+		}
+		// Generate client ID if missing (for backward compatibility)
+		if list.clientID == "" {
+			list.clientID = generateClientID()
+		}
+		lists = append(lists, list)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Println("Error iterating todoLists:", err)
+		return []todoList{}, err
+	}
+
+	return lists, nil
 }
 
 // CreateTodoList creates a new todo list
-func (s *LocalStore) CreateTodoList(name string) (int, error) {
-	return createTodoList(name)
+func (s *LocalStore) CreateTodoList(ctx context.Context, name string) (int, error) {
+	return executeStmtWithID(ctx, s.db, "create todo list",
+		"INSERT INTO todoLists (name, display_order, archived, created_at, updated_at, client_id) VALUES (?, (SELECT COUNT(*) FROM todoLists), 0, ?, ?, ?)",
+		name, now(), now(), generateClientID(),
+	)
 }
 
 // UpdateTodoListName updates the name of a todo list
-func (s *LocalStore) UpdateTodoListName(id int, name string) error {
-	return updateTodoListName(id, name)
+func (s *LocalStore) UpdateTodoListName(ctx context.Context, id int, name string) error {
+	return executeStmt(ctx, s.db, "update todo list name",
+		"UPDATE todoLists SET name = ?, updated_at = ? WHERE id = ?",
+		name, now(), id,
+	)
 }
 
-// DeleteTodoList deletes a todo list and all its tasks
-func (s *LocalStore) DeleteTodoList(id int) error {
-	return deleteTodoList(id)
+// DeleteTodoList moves a todo list and all its tasks to the trash
+func (s *LocalStore) DeleteTodoList(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logError("begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	timestamp := now()
+
+	if err := executeStmt(ctx, tx, "delete todo list",
+		"UPDATE todoLists SET deleted = 1, deleted_at = ?, updated_at = ? WHERE id = ?",
+		timestamp, timestamp, id,
+	); err != nil {
+		return err
+	}
+
+	if err := executeStmt(ctx, tx, "delete tasks in list",
+		"UPDATE tasks SET deleted = 1, deletedAt = ? WHERE todoList_id = ? AND deleted = 0",
+		timestamp, id,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *LocalStore) setTodoListArchived(ctx context.Context, id int, archived bool) error {
+	operation := "archive todo list"
+	if !archived {
+		operation = "unarchive todo list"
+	}
+
+	return executeStmt(ctx, s.db, operation,
+		"UPDATE todoLists SET archived = ?, updated_at = ? WHERE id = ?",
+		archived, now(), id,
+	)
 }
 
 // ArchiveTodoList archives a todo list
-func (s *LocalStore) ArchiveTodoList(id int) error {
-	return archiveTodoList(id)
+func (s *LocalStore) ArchiveTodoList(ctx context.Context, id int) error {
+	return s.setTodoListArchived(ctx, id, true)
 }
 
 // UnarchiveTodoList unarchives a todo list
-func (s *LocalStore) UnarchiveTodoList(id int) error {
-	return unarchiveTodoList(id)
+func (s *LocalStore) UnarchiveTodoList(ctx context.Context, id int) error {
+	return s.setTodoListArchived(ctx, id, false)
+}
+
+// RestoreTodoList takes a list out of the trash. Its tasks are restored
+// separately.
+func (s *LocalStore) RestoreTodoList(ctx context.Context, list todoList) error {
+	return executeStmt(ctx, s.db, "restore todo list",
+		"UPDATE todoLists SET deleted = 0, deleted_at = 0, updated_at = ? WHERE id = ?",
+		now(), list.id,
+	)
+}
+
+// PurgeTodoList permanently removes a deleted list and the tasks that went
+// to the trash with it. A list that still has live tasks, such as one
+// synced in after the list was deleted, is kept so they are not lost.
+func (s *LocalStore) PurgeTodoList(ctx context.Context, id int) error {
+	var live int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE todoList_id = ? AND deleted = 0", id).Scan(&live); err != nil {
+		logError("count live tasks", err)
+		return err
+	}
+	if live > 0 {
+		return fmt.Errorf("list still has %d tasks outside the trash; restore it to keep them", live)
+	}
+	return s.purge(ctx,
+		"deleted = 1 AND todoList_id IN (SELECT id FROM todoLists WHERE id = ?1 AND deleted = 1)",
+		"id = ?1 AND deleted = 1",
+		id,
+	)
 }
 
 // GetItems retrieves all non-deleted items
-func (s *LocalStore) GetItems() ([]todoItem, error) {
-	return getItemsFromDB()
+func (s *LocalStore) GetItems(ctx context.Context) ([]todoItem, error) {
+	return s.queryItems(ctx, "WHERE deleted = 0 ORDER BY id")
+}
+
+// GetDeletedItems retrieves the tasks in the trash, newest first
+func (s *LocalStore) GetDeletedItems(ctx context.Context) ([]todoItem, error) {
+	return s.queryItems(ctx, "WHERE deleted = 1 ORDER BY deletedAt DESC")
+}
+
+func (s *LocalStore) queryItems(ctx context.Context, where string) ([]todoItem, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks "+where)
+	if err != nil {
+		fmt.Println("Failed to query items:", err)
+		return []todoItem{}, err
+	}
+	defer rows.Close()
+
+	items := []todoItem{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			fmt.Println("Failed to scan item:", err)
+			return []todoItem{}, err
+		}
+		// Validate priority to ensure it's a valid value (1-4)
+		item.priority = validatePriority(item.priority)
+		// Generate client ID if missing (for backward compatibility)
+		if item.clientID == "" {
+			item.clientID = generateClientID()
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Println("Error iterating items:", err)
+		return []todoItem{}, err
+	}
+
+	return items, nil
 }
 
 // GetItemByID retrieves a single item by ID
-func (s *LocalStore) GetItemByID(id int) (todoItem, error) {
-	items, err := getItemsFromDB()
+func (s *LocalStore) GetItemByID(ctx context.Context, id int) (todoItem, error) {
+	item, err := scanItem(s.db.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ? AND deleted = 0", id))
+	if err == sql.ErrNoRows {
+		return todoItem{}, fmt.Errorf("item not found: %d", id)
+	}
 	if err != nil {
+		logError("query item by id", err)
 		return todoItem{}, err
 	}
+	return item, nil
+}
+
+// GetItemByClientID retrieves an item by its client ID
+func (s *LocalStore) GetItemByClientID(ctx context.Context, clientID string) (todoItem, error) {
+	item, err := scanItem(s.db.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE client_id = ? LIMIT 1", clientID))
+	if err == sql.ErrNoRows {
+		return todoItem{}, fmt.Errorf("item not found with client_id: %s", clientID)
+	}
+	if err != nil {
+		logError("query item by client_id", err)
+		return todoItem{}, err
+	}
+	return item, nil
+}
+
+// SaveItem saves a new item to the database and returns its ID
+func (s *LocalStore) SaveItem(ctx context.Context, item todoItem) (int, error) {
+	// Generate client ID if not present
+	if item.clientID == "" {
+		item.clientID = generateClientID()
+	}
+	if item.dateAdded == 0 {
+		item.dateAdded = now()
+	}
+	if item.version == 0 {
+		item.version = 1
+	}
+	return executeStmtWithID(ctx, s.db, "insert item",
+		"INSERT INTO tasks (todo, priority, done, dateAdded, dateCompleted, dueDate, deleted, deletedAt, todoList_id, client_id, version, position) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, COALESCE(NULLIF(?12, 0), (SELECT COALESCE(MAX(position), 0) + 1 FROM tasks WHERE todoList_id = ?9)))",
+		item.todo, item.priority, item.done, item.dateAdded, item.dateCompleted, item.dueDate, item.deleted, item.deletedAt, item.todoListID, item.clientID, item.version, item.position,
+	)
+}
+
+// UpdateItem updates an existing item
+func (s *LocalStore) UpdateItem(ctx context.Context, item todoItem) error {
+	return updateItem(ctx, s.db, item)
+}
+
+func updateItem(ctx context.Context, conn sqlExecer, item todoItem) error {
+	return executeStmt(ctx, conn, "update item",
+		"UPDATE tasks SET todo = ?, done = ?, priority = ?, dateCompleted = ?, dueDate = ?, todoList_id = ?, position = ? WHERE id = ?",
+		item.todo, item.done, item.priority, item.dateCompleted, item.dueDate, item.todoListID, item.position, item.id,
+	)
+}
+
+// DeleteItem marks an item as deleted
+func (s *LocalStore) DeleteItem(ctx context.Context, id int) error {
+	return deleteItem(ctx, s.db, id, now())
+}
+
+func deleteItem(ctx context.Context, conn sqlExecer, id int, deletedAt int64) error {
+	return executeStmt(ctx, conn, "delete item",
+		"UPDATE tasks SET deleted = 1, deletedAt = ? WHERE id = ?",
+		deletedAt, id,
+	)
+}
+
+// RestoreItem clears the deleted flag on an item
+func (s *LocalStore) RestoreItem(ctx context.Context, item todoItem) error {
+	return restoreItem(ctx, s.db, item.id)
+}
+
+func restoreItem(ctx context.Context, conn sqlExecer, id int) error {
+	return executeStmt(ctx, conn, "restore item",
+		"UPDATE tasks SET deleted = 0, deletedAt = 0 WHERE id = ?",
+		id,
+	)
+}
+
+// UpdateItems updates several items in one transaction
+func (s *LocalStore) UpdateItems(ctx context.Context, items []todoItem) error {
+	return s.inTx(ctx, "update items", func(tx *sql.Tx) error {
+		return updateItems(ctx, tx, items)
+	})
+}
+
+func updateItems(ctx context.Context, conn sqlExecer, items []todoItem) error {
+	for _, item := range items {
+		if err := updateItem(ctx, conn, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteItems marks several items as deleted in one transaction
+func (s *LocalStore) DeleteItems(ctx context.Context, ids []int) error {
+	return s.inTx(ctx, "delete items", func(tx *sql.Tx) error {
+		return deleteItems(ctx, tx, ids, now())
+	})
+}
+
+func deleteItems(ctx context.Context, conn sqlExecer, ids []int, deletedAt int64) error {
+	for _, id := range ids {
+		if err := deleteItem(ctx, conn, id, deletedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// RestoreItems clears the deleted flag on several items in one transaction
+func (s *LocalStore) RestoreItems(ctx context.Context, items []todoItem) error {
+	return s.inTx(ctx, "restore items", func(tx *sql.Tx) error {
+		return restoreItems(ctx, tx, items)
+	})
+}
+
+func restoreItems(ctx context.Context, conn sqlExecer, items []todoItem) error {
 	for _, item := range items {
-		if item.id == id {
-			return item, nil
+		if err := restoreItem(ctx, conn, item.id); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing only if it succeeds
+func (s *LocalStore) inTx(ctx context.Context, operation string, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logError("begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
 
-	return todoItem{}, fmt.Errorf("item not found: %d", id)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logError("commit "+operation, err)
+		return err
+	}
+	return nil
 }
 
-// GetItemByClientID retrieves an item by its client ID
-func (s *LocalStore) GetItemByClientID(clientID string) (todoItem, error) {
-	rows, err := s.db.Query(
-		"SELECT id, todo, priority, done, dateAdded, dateCompleted, dueDate, deleted, deletedAt, todoList_id, COALESCE(client_id, ''), COALESCE(server_id, 0), COALESCE(version, 1) FROM tasks WHERE client_id = ? LIMIT 1",
-		clientID,
+// PurgeItem permanently removes a deleted item
+func (s *LocalStore) PurgeItem(ctx context.Context, id int) error {
+	return s.purge(ctx, "id = ?1 AND deleted = 1", "", id)
+}
+
+// PurgeDeletedBefore permanently removes tasks and lists that were deleted
+// before cutoff and returns how many were removed. Lists that still have
+// live tasks are kept.
+func (s *LocalStore) PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return s.purgeCount(ctx,
+		"deleted = 1 AND (deletedAt < ?1 OR todoList_id IN (SELECT id FROM todoLists WHERE deleted = 1 AND deleted_at < ?1))",
+		"deleted = 1 AND deleted_at < ?1 AND id NOT IN (SELECT todoList_id FROM tasks WHERE deleted = 0)",
+		cutoff,
+	)
+}
+
+// ArchiveCompletedBefore moves tasks completed before cutoff out of the
+// tasks table into task_history and returns how many were moved
+func (s *LocalStore) ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	const where = "done = 1 AND deleted = 0 AND dateCompleted > 0 AND dateCompleted < ?1"
+	archived := 0
+	err := s.inTx(ctx, "archive completed tasks", func(tx *sql.Tx) error {
+		if err := executeStmt(ctx, tx, "copy tasks to history",
+			`INSERT INTO task_history (todo, priority, dateAdded, dateCompleted, dueDate, todoList_id, list_name, client_id, archived_at)
+			SELECT todo, priority, dateAdded, dateCompleted, dueDate, todoList_id,
+				COALESCE((SELECT name FROM todoLists WHERE todoLists.id = tasks.todoList_id), ''), COALESCE(client_id, ''), ?2
+			FROM tasks WHERE `+where,
+			cutoff, now(),
+		); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM tasks WHERE "+where, cutoff)
+		if err != nil {
+			logError("archive completed tasks", err)
+			return err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			logError("archive completed tasks", err)
+			return err
+		}
+		archived = int(count)
+		return nil
+	})
+	return archived, err
+}
+
+// GetHistory returns archived completions whose text contains query, most
+// recently completed first. An empty query returns the whole history.
+func (s *LocalStore) GetHistory(ctx context.Context, query string) ([]historyItem, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, todo, priority, dateAdded, dateCompleted, dueDate, todoList_id, list_name, client_id, archived_at
+		FROM task_history WHERE instr(lower(todo), lower(?)) > 0 ORDER BY dateCompleted DESC, id DESC`,
+		query,
 	)
 	if err != nil {
-		logError("query item by client_id", err)
-		return todoItem{}, err
+		logError("query history", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	if rows.Next() {
-		var item todoItem
-		if err := rows.Scan(&item.id, &item.todo, &item.priority, &item.done, &item.dateAdded, &item.dateCompleted, &item.dueDate, &item.deleted, &item.deletedAt, &item.todoListID, &item.clientID, &item.serverID, &item.version); err != nil {
-			logError("scan item by client_id", err)
-			return todoItem{}, err
+	history := []historyItem{}
+	for rows.Next() {
+		item := historyItem{todoItem: todoItem{done: true}}
+		if err := rows.Scan(&item.id, &item.todo, &item.priority, &item.dateAdded, &item.dateCompleted, &item.dueDate, &item.todoListID, &item.listName, &item.clientID, &item.archivedAt); err != nil {
+			logError("scan history", err)
+			return nil, err
 		}
-		return item, nil
+		history = append(history, item)
 	}
+	return history, rows.Err()
+}
 
-	return todoItem{}, fmt.Errorf("item not found with client_id: %s", clientID)
+// InHistory reports whether a task with this client ID was archived here
+func (s *LocalStore) InHistory(ctx context.Context, clientID string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM task_history WHERE client_id = ? AND client_id != ''", clientID).Scan(&count)
+	if err != nil {
+		logError("query history", err)
+		return false, err
+	}
+	return count > 0, nil
 }
 
-// SaveItem saves a new item to the database
-func (s *LocalStore) SaveItem(item todoItem) error {
-	// Generate client ID if not present
-	if item.clientID == "" {
-		item.clientID = generateClientID()
+func (s *LocalStore) purge(ctx context.Context, taskWhere string, listWhere string, arg interface{}) error {
+	_, err := s.purgeCount(ctx, taskWhere, listWhere, arg)
+	return err
+}
+
+// purgeCount deletes the matching tasks, then the matching lists, in one
+// transaction. Conditions refer to arg as ?1. Each purged row with a client
+// ID leaves a tombstone so sync can tell other devices. An empty listWhere
+// purges tasks only.
+func (s *LocalStore) purgeCount(ctx context.Context, taskWhere string, listWhere string, arg interface{}) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logError("begin transaction", err)
+		return 0, err
 	}
-	return saveItemToDB(item)
+	defer tx.Rollback()
+
+	timestamp := now()
+	purged := 0
+
+	targets := []struct {
+		entityType string
+		table      string
+		where      string
+	}{
+		{"task", "tasks", taskWhere},
+		{"list", "todoLists", listWhere},
+	}
+	for _, target := range targets {
+		if target.where == "" {
+			continue
+		}
+
+		if err := executeStmt(ctx, tx, "record "+target.entityType+" tombstones",
+			"INSERT OR REPLACE INTO tombstones (client_id, entity_type, deleted_at, synced) SELECT client_id, ?2, ?3, 0 FROM "+target.table+" WHERE client_id IS NOT NULL AND client_id != '' AND ("+target.where+")",
+			arg, target.entityType, timestamp,
+		); err != nil {
+			return 0, err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM "+target.table+" WHERE "+target.where, arg)
+		if err != nil {
+			logError("purge "+target.table, err)
+			return 0, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			logError("purge "+target.table, err)
+			return 0, err
+		}
+		purged += int(count)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError("commit purge", err)
+		return 0, err
+	}
+	return purged, nil
 }
 
-// UpdateItem updates an existing item
-func (s *LocalStore) UpdateItem(item todoItem) error {
-	return updateItemInDB(item)
+// GetTombstones retrieves the tombstones not yet pushed to other devices
+func (s *LocalStore) GetTombstones(ctx context.Context) ([]Tombstone, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT client_id, entity_type, deleted_at FROM tombstones WHERE synced = 0")
+	if err != nil {
+		logError("query tombstones", err)
+		return []Tombstone{}, err
+	}
+	defer rows.Close()
+
+	tombstones := []Tombstone{}
+	for rows.Next() {
+		var tombstone Tombstone
+		if err := rows.Scan(&tombstone.clientID, &tombstone.entityType, &tombstone.deletedAt); err != nil {
+			logError("scan tombstone", err)
+			return []Tombstone{}, err
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	if err := rows.Err(); err != nil {
+		logError("iterate tombstones", err)
+		return []Tombstone{}, err
+	}
+
+	return tombstones, nil
 }
 
-// DeleteItem marks an item as deleted
-func (s *LocalStore) DeleteItem(id int) error {
-	return markItemAsDeleted(id)
+// MarkTombstoneSynced marks a tombstone as pushed
+func (s *LocalStore) MarkTombstoneSynced(ctx context.Context, clientID string) error {
+	return executeStmt(ctx, s.db, "mark tombstone synced",
+		"UPDATE tombstones SET synced = 1 WHERE client_id = ?",
+		clientID,
+	)
+}
+
+// HasTombstone reports whether a row with this client ID was purged here
+func (s *LocalStore) HasTombstone(ctx context.Context, clientID string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tombstones WHERE client_id = ?", clientID).Scan(&count)
+	if err != nil {
+		logError("query tombstone", err)
+		return false, err
+	}
+	return count > 0, nil
 }
 
 // GetLastSyncTime retrieves the timestamp of the last successful sync
-func (s *LocalStore) GetLastSyncTime() (int64, error) {
-	return getLastSyncTime()
+func (s *LocalStore) GetLastSyncTime(ctx context.Context) (int64, error) {
+	var timestamp int64
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM sync_metadata WHERE key = 'last_sync_time'").Scan(&timestamp)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		logError("get last sync time", err)
+		return 0, err
+	}
+
+	return timestamp, nil
 }
 
 // SetLastSyncTime updates the last sync timestamp
-func (s *LocalStore) SetLastSyncTime(timestamp int64) error {
-	return setLastSyncTime(timestamp)
+func (s *LocalStore) SetLastSyncTime(ctx context.Context, timestamp int64) error {
+	// Use INSERT OR REPLACE to handle both insert and update
+	return executeStmt(ctx, s.db, "set last sync time",
+		"INSERT OR REPLACE INTO sync_metadata (key, value) VALUES ('last_sync_time', ?)",
+		fmt.Sprintf("%d", timestamp),
+	)
 }
 
 // GetPendingChanges retrieves all unsynced changes
-func (s *LocalStore) GetPendingChanges() ([]Change, error) {
-	return getPendingChanges()
+func (s *LocalStore) GetPendingChanges(ctx context.Context) ([]Change, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, entity_type, entity_id, change_type, timestamp, synced FROM change_log WHERE synced = 0 ORDER BY timestamp")
+	if err != nil {
+		logError("query pending changes", err)
+		return []Change{}, err
+	}
+	defer rows.Close()
+
+	changes := []Change{}
+	for rows.Next() {
+		var change Change
+		if err := rows.Scan(&change.id, &change.entityType, &change.entityID, &change.changeType, &change.timestamp, &change.synced); err != nil {
+			logError("scan change", err)
+			return []Change{}, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError("iterate changes", err)
+		return []Change{}, err
+	}
+
+	return changes, nil
 }
 
 // MarkChangeSynced marks a change as successfully synced
-func (s *LocalStore) MarkChangeSynced(changeID int) error {
-	return markChangeSynced(changeID)
+func (s *LocalStore) MarkChangeSynced(ctx context.Context, changeID int) error {
+	return executeStmt(ctx, s.db, "mark change synced",
+		"UPDATE change_log SET synced = 1 WHERE id = ?",
+		changeID,
+	)
 }
 
 // LogChange records a local change for later sync
-func (s *LocalStore) LogChange(entityType string, entityID int, changeType string) error {
-	return logChange(entityType, entityID, changeType)
+func (s *LocalStore) LogChange(ctx context.Context, entityType string, entityID int, changeType string) error {
+	return logChange(ctx, s.db, entityType, entityID, changeType, now())
+}
+
+// logChanges records the same change for several entities
+func logChanges(ctx context.Context, conn sqlExecer, entityType string, entityIDs []int, changeType string, timestamp int64) error {
+	for _, id := range entityIDs {
+		if err := logChange(ctx, conn, entityType, id, changeType, timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func logChange(ctx context.Context, conn sqlExecer, entityType string, entityID int, changeType string, timestamp int64) error {
+	return executeStmt(ctx, conn, "log change",
+		"INSERT INTO change_log (entity_type, entity_id, change_type, timestamp, synced) VALUES (?, ?, ?, ?, 0)",
+		entityType, entityID, changeType, timestamp,
+	)
 }
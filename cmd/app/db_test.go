@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T, name string) *LocalStore {
+	t.Helper()
+
+	store, err := OpenLocalStore(t.Context(), filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// TestLocalStore_IndependentHandles verifies two databases can be open in
+// one process without sharing state
+func TestLocalStore_IndependentHandles(t *testing.T) {
+	ctx := t.Context()
+	a := openTestStore(t, "a.db")
+	b := openTestStore(t, "b.db")
+
+	listID, err := a.CreateTodoList(ctx, "Work")
+	if err != nil {
+		t.Fatalf("create list: %v", err)
+	}
+	id, err := a.SaveItem(ctx, todoItem{todo: "Only in a", priority: PriorityHigh, todoListID: listID})
+	if err != nil {
+		t.Fatalf("save item: %v", err)
+	}
+	if id == 0 {
+		t.Fatal("expected SaveItem to return the new ID")
+	}
+
+	saved, err := a.GetItemByID(ctx, id)
+	if err != nil {
+		t.Fatalf("get item: %v", err)
+	}
+	if saved.clientID == "" || saved.version != 1 {
+		t.Errorf("expected client ID and version to be stored, got %q v%d", saved.clientID, saved.version)
+	}
+
+	items, err := b.GetItems(ctx)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected second database to be empty, got %d items", len(items))
+	}
+
+	idA, _ := a.GetDeviceID(ctx)
+	idB, _ := b.GetDeviceID(ctx)
+	if idA == "" || idA == idB {
+		t.Errorf("expected distinct device IDs, got %q and %q", idA, idB)
+	}
+	if again, _ := a.GetDeviceID(ctx); again != idA {
+		t.Errorf("expected device ID to persist, got %q then %q", idA, again)
+	}
+}
+
+func TestLocalStore_ArchivedLists(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	work, _ := store.CreateTodoList(ctx, "Work")
+	home, _ := store.CreateTodoList(ctx, "Home")
+	store.ArchiveTodoList(ctx, work)
+	store.ArchiveTodoList(ctx, home)
+	store.DeleteTodoList(ctx, home)
+
+	archived, err := store.GetArchivedTodoLists(ctx)
+	if err != nil {
+		t.Fatalf("get archived lists: %v", err)
+	}
+	if len(archived) != 1 || archived[0].id != work {
+		t.Fatalf("expected only Work to be archived, got %+v", archived)
+	}
+	if lists, _ := store.GetTodoLists(ctx); len(lists) != 0 {
+		t.Errorf("expected no live lists, got %d", len(lists))
+	}
+}
+
+func TestLocalStore_BulkDeleteAndRestore(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	list, _ := store.CreateTodoList(ctx, "Work")
+	a, _ := store.SaveItem(ctx, todoItem{todo: "A", todoListID: list})
+	b, _ := store.SaveItem(ctx, todoItem{todo: "B", todoListID: list})
+
+	if err := store.DeleteItems(ctx, []int{a, b}); err != nil {
+		t.Fatalf("delete items: %v", err)
+	}
+	deleted, _ := store.GetDeletedItems(ctx)
+	if len(deleted) != 2 || deleted[0].deletedAt != deleted[1].deletedAt {
+		t.Fatalf("expected both items deleted together, got %+v", deleted)
+	}
+
+	if err := store.RestoreItems(ctx, deleted); err != nil {
+		t.Fatalf("restore items: %v", err)
+	}
+	if items, _ := store.GetItems(ctx); len(items) != 2 {
+		t.Errorf("expected both items restored, got %d", len(items))
+	}
+}
+
+func TestLocalStore_ArchiveCompleted(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	list, _ := store.CreateTodoList(ctx, "Work")
+	old := time.Now().AddDate(0, 0, -10).Unix()
+	store.SaveItem(ctx, todoItem{todo: "Old report", done: true, dateCompleted: old, todoListID: list})
+	store.SaveItem(ctx, todoItem{todo: "Fresh report", done: true, dateCompleted: now(), todoListID: list})
+	store.SaveItem(ctx, todoItem{todo: "Open task", todoListID: list})
+
+	archived, err := store.ArchiveCompletedBefore(ctx, time.Now().AddDate(0, 0, -7).Unix())
+	if err != nil {
+		t.Fatalf("archive completed: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("expected 1 task archived, got %d", archived)
+	}
+	if items, _ := store.GetItems(ctx); len(items) != 2 {
+		t.Errorf("expected 2 tasks left, got %d", len(items))
+	}
+
+	history, err := store.GetHistory(ctx, "REPORT")
+	if err != nil {
+		t.Fatalf("get history: %v", err)
+	}
+	if len(history) != 1 || history[0].todo != "Old report" || history[0].listName != "Work" {
+		t.Errorf("expected the old report in history, got %+v", history)
+	}
+	if history, _ := store.GetHistory(ctx, "open"); len(history) != 0 {
+		t.Errorf("expected no match for open tasks, got %+v", history)
+	}
+}
+
+// TestArchiveOldCompletions verifies completed tasks are only archived when
+// configured and that the app reports how many were moved
+func TestArchiveOldCompletions(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemoryStore()
+	list, _ := store.CreateTodoList(ctx, "Work")
+	store.SaveItem(ctx, todoItem{todo: "Old report", done: true, dateCompleted: time.Now().AddDate(0, 0, -10).Unix(), todoListID: list})
+	warn := func(format string, a ...interface{}) { t.Errorf(format, a...) }
+
+	if archived := archiveOldCompletions(ctx, store, defaultHistoryAfterDays, warn); archived != 0 {
+		t.Fatalf("expected nothing archived by default, got %d", archived)
+	}
+	archived := archiveOldCompletions(ctx, store, 7, warn)
+	if archived != 1 {
+		t.Fatalf("expected 1 task archived, got %d", archived)
+	}
+
+	m := initialModel(nil, nil)
+	m.useStore(Config{}, openedStore{archived: archived})
+	if m.statusMsg != "Moved 1 completed task(s) to the history" {
+		t.Errorf("expected the archived count in the status line, got %q", m.statusMsg)
+	}
+}
+
+// TestOpenCommandStore verifies one-off commands leave the startup
+// housekeeping to the app
+func TestOpenCommandStore(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "todo.db")
+	store, err := OpenLocalStore(ctx, path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	list, _ := store.CreateTodoList(ctx, "Work")
+	store.SaveItem(ctx, todoItem{todo: "Old report", done: true, dateCompleted: time.Now().AddDate(0, 0, -10).Unix(), todoListID: list})
+	store.Close()
+
+	cfg := Config{Backend: BackendSQLite, DBPath: path, HistoryAfterDays: 7}
+	warn := func(format string, a ...interface{}) { t.Errorf(format, a...) }
+	opened, err := openCommandStore(ctx, &cfg, warn)
+	if err != nil {
+		t.Fatalf("open command store: %v", err)
+	}
+	items, _ := opened.store.GetItems(ctx)
+	opened.close()
+	if len(items) != 1 {
+		t.Errorf("expected the command to leave the completed task in place, got %d tasks", len(items))
+	}
+
+	opened, err = openStore(ctx, &cfg, warn)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer opened.close()
+	if opened.archived != 1 {
+		t.Errorf("expected the app to archive the completed task, got %d", opened.archived)
+	}
+}
+
+func TestLocalStore_Ordering(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	work, _ := store.CreateTodoList(ctx, "Work")
+	home, _ := store.CreateTodoList(ctx, "Home")
+	a, _ := store.SaveItem(ctx, todoItem{todo: "A", todoListID: work})
+	b, _ := store.SaveItem(ctx, todoItem{todo: "B", todoListID: work})
+
+	first, _ := store.GetItemByID(ctx, a)
+	second, _ := store.GetItemByID(ctx, b)
+	if first.position != 1 || second.position != 2 {
+		t.Errorf("expected new tasks appended in order, got %d and %d", first.position, second.position)
+	}
+
+	if err := store.ReorderTodoLists(ctx, []int{home, work}); err != nil {
+		t.Fatalf("reorder lists: %v", err)
+	}
+	store.SetTodoListView(ctx, work, SortModeManual, GroupModeTag)
+	lists, _ := store.GetTodoLists(ctx)
+	if lists[0].id != home || lists[1].sortMode != SortModeManual || lists[1].groupMode != GroupModeTag {
+		t.Errorf("expected Home first and Work in manual order grouped by tag, got %+v", lists)
+	}
+}
+
+func TestLocalStore_CancelledContext(t *testing.T) {
+	store := openTestStore(t, "todo.db")
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	if _, err := store.GetItems(ctx); err == nil {
+		t.Error("expected query with an expired context to fail")
+	}
+}
+
+func TestLocalStore_PurgeLeavesTombstones(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	work, _ := store.CreateTodoList(ctx, "Work")
+	home, _ := store.CreateTodoList(ctx, "Home")
+	keep, _ := store.SaveItem(ctx, todoItem{todo: "Keep", todoListID: work})
+	old, _ := store.SaveItem(ctx, todoItem{todo: "Old", todoListID: work})
+	store.SaveItem(ctx, todoItem{todo: "Chores", todoListID: home})
+
+	if err := store.PurgeItem(ctx, keep); err != nil {
+		t.Fatalf("purge live item: %v", err)
+	}
+	if _, err := store.GetItemByID(ctx, keep); err != nil {
+		t.Fatal("expected purge to leave live items alone")
+	}
+
+	store.DeleteItem(ctx, old)
+	store.DeleteTodoList(ctx, home)
+
+	lists, _ := store.GetDeletedTodoLists(ctx)
+	items, _ := store.GetDeletedItems(ctx)
+	if len(lists) != 1 || len(items) != 2 {
+		t.Fatalf("expected 1 list and 2 tasks in trash, got %d and %d", len(lists), len(items))
+	}
+
+	// Nothing is old enough yet
+	if n, _ := store.PurgeDeletedBefore(ctx, now()-SecondsPerDay); n != 0 {
+		t.Fatalf("expected nothing purged, got %d", n)
+	}
+
+	n, err := store.PurgeDeletedBefore(ctx, now()+1)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 2 tasks and 1 list purged, got %d", n)
+	}
+
+	tombstones, _ := store.GetTombstones(ctx)
+	if len(tombstones) != 3 {
+		t.Fatalf("expected 3 tombstones, got %d", len(tombstones))
+	}
+	if purged, _ := store.HasTombstone(ctx, items[0].clientID); !purged {
+		t.Error("expected tombstone for purged task")
+	}
+
+	store.MarkTombstoneSynced(ctx, tombstones[0].clientID)
+	if remaining, _ := store.GetTombstones(ctx); len(remaining) != 2 {
+		t.Errorf("expected synced tombstone to be skipped, got %d", len(remaining))
+	}
+}
+
+func TestLocalStore_PurgeTodoListOnlyPurgesTrashedTasks(t *testing.T) {
+	ctx := t.Context()
+	store := openTestStore(t, "todo.db")
+
+	home, _ := store.CreateTodoList(ctx, "Home")
+	trashed, _ := store.SaveItem(ctx, todoItem{todo: "Chores", todoListID: home})
+	store.DeleteTodoList(ctx, home)
+
+	// A task synced into the list after it went to the trash is still live
+	live, _ := store.SaveItem(ctx, todoItem{todo: "Groceries", todoListID: home})
+
+	if err := store.PurgeTodoList(ctx, home); err == nil {
+		t.Fatal("expected a list with live tasks to be kept")
+	}
+	if n, err := store.PurgeDeletedBefore(ctx, now()+1); err != nil || n != 1 {
+		t.Fatalf("expected only the trashed task purged, got %d (%v)", n, err)
+	}
+	if _, err := store.GetItemByID(ctx, live); err != nil {
+		t.Errorf("expected the live task kept, got %v", err)
+	}
+
+	store.DeleteItem(ctx, live)
+	if err := store.PurgeTodoList(ctx, home); err != nil {
+		t.Fatalf("purge list: %v", err)
+	}
+	if lists, _ := store.GetDeletedTodoLists(ctx); len(lists) != 0 {
+		t.Errorf("expected the list purged, got %+v", lists)
+	}
+	items, _ := store.GetDeletedItems(ctx)
+	for _, item := range items {
+		if item.id == trashed || item.id == live {
+			t.Errorf("expected the list's trashed tasks purged, found %+v", item)
+		}
+	}
+}
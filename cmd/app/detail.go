@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openTaskDetail shows every field of the task under the cursor
+func (m *model) openTaskDetail() {
+	actualIndex := m.getVisibleItemActualIndex(m.cursor)
+	if actualIndex < 0 {
+		return
+	}
+	item := m.items[actualIndex]
+	m.input.detailItemID = item.id
+	m.input.detailField = DetailFieldText
+	m.input.detailPending = false
+
+	if m.syncEnabled {
+		ctx, cancel := m.storeContext()
+		defer cancel()
+		changes, err := m.store.GetPendingChanges(ctx)
+		if err != nil {
+			m.errorMsg = "Failed to load sync state: " + err.Error()
+		}
+		for _, change := range changes {
+			if change.entityType == "task" && change.entityID == item.id {
+				m.input.detailPending = true
+			}
+		}
+	}
+	m.setState(StateTaskDetail, SubStateNone)
+}
+
+func (m *model) handleTaskDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	index := m.itemIndexByID(m.input.detailItemID)
+	if index < 0 {
+		m.returnToMain()
+		return m, nil
+	}
+
+	if m.currentSubState == SubStateDetailEdit {
+		switch {
+		case key.Matches(msg, m.keys.Cancel):
+			m.textInput.Reset()
+			m.currentSubState = SubStateNone
+		case key.Matches(msg, m.keys.Confirm):
+			m.saveDetailEdit()
+		default:
+			var cmd tea.Cmd
+			m.textInput, cmd = m.textInput.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	return m.runKeyAction(msg, m.taskDetailActions())
+}
+
+func (m *model) taskDetailActions() []keyAction {
+	k := m.keys
+	edit := act(func(m *model) {
+		if index := m.itemIndexByID(m.input.detailItemID); index >= 0 {
+			m.editDetailField(m.items[index])
+		}
+	})
+	actions := []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.input.detailField > 0 {
+				m.input.detailField--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.input.detailField < DetailFieldCount-1 {
+				m.input.detailField++
+			}
+		})},
+		{k.Confirm, edit},
+		{k.ToggleDone, edit},
+	}
+	actions = append(actions, priorityActions(func(m *model, priority int) {
+		m.updateDetailItem("change priority", func(item *todoItem) {
+			item.priority = priority
+		})
+	})...)
+	return append(actions, keyAction{k.Close, act((*model).closeTaskDetail)})
+}
+
+// editDetailField changes the selected field. Text and due date open an
+// input in the pane; the other fields step to their next value.
+func (m *model) editDetailField(item todoItem) {
+	switch m.input.detailField {
+	case DetailFieldText:
+		m.textInput.SetValue(item.todo)
+		m.textInput.Focus()
+		m.currentSubState = SubStateDetailEdit
+	case DetailFieldDueDate:
+		m.textInput.Reset()
+		m.textInput.Focus()
+		m.currentSubState = SubStateDetailEdit
+	case DetailFieldPriority:
+		m.updateDetailItem("change priority", func(item *todoItem) {
+			item.priority = item.priority%PriorityLow + 1
+		})
+	case DetailFieldList:
+		target := m.nextListID(item.todoListID)
+		if target == item.todoListID {
+			return
+		}
+		position := m.nextPosition(target)
+		m.updateDetailItem("move task", func(item *todoItem) {
+			item.todoListID = target
+			item.position = position
+		})
+	case DetailFieldStatus:
+		completedAt := time.Now().Unix()
+		m.updateDetailItem("toggle task", func(item *todoItem) {
+			item.done = !item.done
+			if item.done {
+				item.dateCompleted = completedAt
+			} else {
+				item.dateCompleted = 0
+			}
+		})
+	}
+}
+
+func (m *model) saveDetailEdit() {
+	value := m.textInput.Value()
+	switch m.input.detailField {
+	case DetailFieldText:
+		text, err := validateTaskText(value)
+		if err != nil {
+			m.errorMsg = err.Error()
+			return
+		}
+		m.updateDetailItem("edit task", func(item *todoItem) {
+			item.todo = text
+		})
+	case DetailFieldDueDate:
+		dueDate := parseDueDate(value)
+		if dueDate == 0 && strings.TrimSpace(value) != "" {
+			m.errorMsg = "Invalid due date: " + value
+			return
+		}
+		m.updateDetailItem("change due date", func(item *todoItem) {
+			item.dueDate = dueDate
+		})
+	}
+	m.textInput.Reset()
+	m.currentSubState = SubStateNone
+}
+
+// updateDetailItem saves one change to the task in the detail view
+func (m *model) updateDetailItem(description string, change func(item *todoItem)) {
+	index := m.itemIndexByID(m.input.detailItemID)
+	if index < 0 {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	before := m.items[index]
+	change(&m.items[index])
+	if err := m.store.UpdateItem(ctx, m.items[index]); err != nil {
+		m.errorMsg = "Failed to update task: " + err.Error()
+		m.items[index] = before
+		return
+	}
+	m.recordUndo(updateItemCommand(description, before, m.items[index]))
+	if m.syncEnabled {
+		m.input.detailPending = true
+	}
+	m.sortItems()
+}
+
+// closeTaskDetail returns to the main view with the cursor on the task, if
+// it is still in the current list
+func (m *model) closeTaskDetail() {
+	m.selectItem(m.input.detailItemID)
+	m.returnToMain()
+}
+
+// nextListID returns the live list after listID, wrapping to the first
+func (m *model) nextListID(listID int) int {
+	for i, list := range m.todoLists {
+		if list.id == listID {
+			return m.todoLists[(i+1)%len(m.todoLists)].id
+		}
+	}
+	if len(m.todoLists) > 0 {
+		return m.todoLists[0].id
+	}
+	return listID
+}
+
+func (m *model) listName(listID int) string {
+	for _, list := range append(append([]todoList{}, m.todoLists...), m.archivedLists...) {
+		if list.id == listID {
+			return list.name
+		}
+	}
+	return "Unknown"
+}
+
+// detailRows lists a task's fields as label and value pairs. The editable
+// fields come first, in DetailField order.
+func (m *model) detailRows(item todoItem) [][2]string {
+	status := "open"
+	if item.done {
+		status = "done"
+	}
+	return [][2]string{
+		{"Text", item.todo},
+		{"Priority", PriorityLabels[item.priority]},
+		{"Due", formatDetailTime(item.dueDate)},
+		{"List", m.listName(item.todoListID)},
+		{"Status", status},
+		{"Created", formatDetailTime(item.dateAdded)},
+		{"Completed", formatDetailTime(item.dateCompleted)},
+		{"Client ID", item.clientID},
+		{"Version", fmt.Sprint(item.version)},
+	}
+}
+
+func formatDetailTime(timestamp int64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(timestamp, 0).Format(DetailTimeFormat)
+}
+
+// syncStateLabel describes whether a task's latest change was synced. A
+// task with no change waiting in the change log has been synced.
+func (m *model) syncStateLabel() string {
+	switch {
+	case !m.syncEnabled:
+		return "sync off"
+	case m.input.detailPending:
+		return "changes pending"
+	}
+	return "synced"
+}
+
+func (m model) renderTaskDetail() string {
+	lines := []string{TitleStyle.Render("Task details")}
+
+	index := m.itemIndexByID(m.input.detailItemID)
+	if index < 0 {
+		return strings.Join(lines, "\n")
+	}
+	item := m.items[index]
+
+	rows := append(m.detailRows(item), [2]string{"Sync", m.syncStateLabel()})
+	for i, row := range rows {
+		line := fmt.Sprintf("%-10s %s", row[0]+":", row[1])
+		switch {
+		case i == m.input.detailField:
+			lines = append(lines, SelectedStyle.Render("▶ "+line))
+		case i < DetailFieldCount:
+			lines = append(lines, "  "+line)
+		default:
+			lines = append(lines, StatusStyle.Render("  "+line))
+		}
+	}
+
+	lines = append(lines, "")
+	if m.currentSubState == SubStateDetailEdit {
+		lines = append(lines, m.textInput.View())
+		if m.input.detailField == DetailFieldDueDate {
+			lines = append(lines, m.keyHint("Enter days like '3' or date like '12/25/2025', leave empty to clear;", relabel(m.keys.Confirm, "save"), m.keys.Cancel))
+		} else {
+			lines = append(lines, m.keyHint("", relabel(m.keys.Confirm, "save"), m.keys.Cancel))
+		}
+	} else {
+		lines = append(lines, m.keyHint("", m.keys.Up, m.keys.Down, relabel(m.keys.Confirm, "edit"), relabel(m.keys.SetPriority, "priority"), relabel(m.keys.Close, "back")))
+	}
+
+	if m.errorMsg != "" {
+		lines = append(lines, ErrorStyle.Render("Error: "+m.errorMsg))
+	}
+	if m.statusMsg != "" {
+		lines = append(lines, StatusStyle.Render(m.statusMsg))
+	}
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+// showDetailPane reports whether the terminal is wide enough to show the
+// selected task's details next to the list
+func (m model) showDetailPane() bool {
+	return m.width >= DetailPaneMinWidth
+}
+
+// taskListWidth is the viewport width left beside the detail pane
+func (m model) taskListWidth(width int) int {
+	if width >= DetailPaneMinWidth {
+		return width - DetailPaneWidth
+	}
+	return width
+}
+
+// renderDetailPane shows the fields of the task under the cursor beside
+// the task list
+func (m model) renderDetailPane() string {
+	var lines []string
+	if actualIndex := m.getVisibleItemActualIndex(m.cursor); actualIndex >= 0 {
+		for _, row := range m.detailRows(m.items[actualIndex]) {
+			lines = append(lines, row[0]+": "+row[1])
+		}
+	}
+	return DetailPaneStyle.
+		Width(DetailPaneWidth - 2).
+		Height(m.viewport.Height).
+		Render(strings.Join(lines, "\n"))
+}
+
+// joinDetailPane places the detail pane to the right of the task list
+func (m model) joinDetailPane(list string) string {
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, m.renderDetailPane())
+}
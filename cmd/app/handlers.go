@@ -3,6 +3,7 @@ package main
 import (
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -11,10 +12,35 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.height = msg.Height
 		m.width = msg.Width
-		m.viewport.Width = msg.Width
+		m.viewport.Width = m.taskListWidth(msg.Width)
 		m.viewport.Height = m.getViewportHeight(msg.Height)
+	case profileOpenedMsg:
+		m.useProfile(msg)
+	case tea.MouseMsg:
+		if !m.showHelp && !m.palette.open {
+			return m.handleMouse(msg)
+		}
 	case tea.KeyMsg:
 		m.errorMsg = ""
+		m.statusMsg = ""
+		m.scrolled = false
+		if m.showHelp {
+			if key.Matches(msg, m.keys.Help, m.keys.Close, m.keys.Cancel) {
+				m.showHelp = false
+			}
+			return m, nil
+		}
+		if m.palette.open {
+			return m.handlePalette(msg)
+		}
+		if key.Matches(msg, m.keys.Help) && !m.typing() {
+			m.showHelp = true
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.Palette) && !m.typing() {
+			m.openPalette()
+			return m, nil
+		}
 		switch m.currentState {
 		case StateListSelector:
 			return m.handleListSelector(msg)
@@ -24,6 +50,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleDeleteConfirm(msg)
 		case StateTaskInput, StatePrioritySelection, StateDueDateInput, StateListNameInput:
 			return m.handleInputMode(msg)
+		case StateTrash:
+			return m.handleTrash(msg)
+		case StateArchivedView:
+			return m.handleArchivedView(msg)
+		case StateMoveTask:
+			return m.handleMovePicker(msg)
+		case StateTaskDetail:
+			return m.handleTaskDetail(msg)
+		case StateProfilePicker:
+			return m.handleProfilePicker(msg)
+		case StateCalendar:
+			return m.handleCalendar(msg)
 		case StateMainBrowse:
 			return m.handleMainKeyboard(msg)
 		}
@@ -32,21 +70,25 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleEditMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyEsc:
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
 		m.returnToMain()
 		return m, nil
-	case KeyEnter:
+	case key.Matches(msg, m.keys.Confirm):
 		editedText, err := validateTaskText(m.textInput.Value())
 		if err != nil {
 			m.errorMsg = err.Error()
 			return m, nil
 		}
 		if m.input.itemIndex >= 0 && m.input.itemIndex < len(m.items) {
+			ctx, cancel := m.storeContext()
+			defer cancel()
+			before := m.items[m.input.itemIndex]
 			m.items[m.input.itemIndex].todo = editedText
-			m.items[m.input.itemIndex].todoListID = m.currentListID
-			if err := m.store.UpdateItem(m.items[m.input.itemIndex]); err != nil {
+			if err := m.store.UpdateItem(ctx, m.items[m.input.itemIndex]); err != nil {
 				m.errorMsg = "Failed to update task: " + err.Error()
+			} else {
+				m.recordUndo(updateItemCommand("edit task", before, m.items[m.input.itemIndex]))
 			}
 			m.invalidateCache()
 		}
@@ -60,27 +102,41 @@ func (m *model) handleEditMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleDeleteConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyY:
-		actualIndex := m.getVisibleItemActualIndex(m.input.deleteIndex)
-		if actualIndex >= 0 && actualIndex < len(m.items) {
-			if err := m.store.DeleteItem(m.items[actualIndex].id); err != nil {
-				m.errorMsg = "Failed to delete task: " + err.Error()
-			}
-			m.items = append(m.items[:actualIndex], m.items[actualIndex+1:]...)
-			if m.cursor >= m.getVisibleItemCount() && m.cursor > 0 {
-				m.cursor--
-			}
-			m.invalidateCache()
-			m.sortItems()
-		}
-		m.returnToMain()
-		return m, nil
-	case KeyN, KeyEsc:
+	return m.runKeyAction(msg, m.deleteConfirmActions())
+}
+
+func (m *model) deleteConfirmActions() []keyAction {
+	return []keyAction{
+		{m.keys.Yes, act((*model).confirmDelete)},
+		{m.keys.No, act((*model).returnToMain)},
+		{m.keys.Cancel, act((*model).returnToMain)},
+	}
+}
+
+// confirmDelete deletes the task or marked tasks waiting for confirmation
+func (m *model) confirmDelete() {
+	if m.currentSubState == SubStateBulkDelete {
+		m.bulkDelete()
 		m.returnToMain()
-		return m, nil
+		return
 	}
-	return m, nil
+	actualIndex := m.getVisibleItemActualIndex(m.input.deleteIndex)
+	if actualIndex >= 0 && actualIndex < len(m.items) {
+		ctx, cancel := m.storeContext()
+		defer cancel()
+		if err := m.store.DeleteItem(ctx, m.items[actualIndex].id); err != nil {
+			m.errorMsg = "Failed to delete task: " + err.Error()
+		} else {
+			m.recordUndo(deleteItemCommand(m.items[actualIndex]))
+		}
+		m.items = append(m.items[:actualIndex], m.items[actualIndex+1:]...)
+		if m.cursor >= m.getVisibleItemCount() && m.cursor > 0 {
+			m.cursor--
+		}
+		m.invalidateCache()
+		m.sortItems()
+	}
+	m.returnToMain()
 }
 
 func (m *model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -89,38 +145,41 @@ func (m *model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleTaskCreationFlow(msg)
 	}
 
-	key := msg.String()
-
-	if key == KeyEsc {
+	if key.Matches(msg, m.keys.Cancel) {
 		m.returnToMain()
 		return m, nil
 	}
 
-	if key == KeyEnter {
+	if key.Matches(msg, m.keys.Confirm) {
 		listName, err := validateListName(m.textInput.Value())
 		if err != nil {
 			m.errorMsg = err.Error()
 			return m, nil
 		}
 
+		ctx, cancel := m.storeContext()
+		defer cancel()
+
 		if m.currentSubState == SubStateListRename {
 			if m.input.listIndex < len(m.todoLists) {
 				listID := m.todoLists[m.input.listIndex].id
-				if err := m.store.UpdateTodoListName(listID, listName); err != nil {
+				oldName := m.todoLists[m.input.listIndex].name
+				if err := m.store.UpdateTodoListName(ctx, listID, listName); err != nil {
 					m.errorMsg = "Failed to rename list: " + err.Error()
 					m.returnToMain()
 					return m, nil
 				}
 				m.todoLists[m.input.listIndex].name = listName
+				m.recordUndo(renameListCommand(listID, oldName, listName))
 			}
 		} else {
-			newID, err := m.store.CreateTodoList(listName)
+			newID, err := m.store.CreateTodoList(ctx, listName)
 			if err != nil {
 				m.errorMsg = "Failed to create list: " + err.Error()
 				m.returnToMain()
 				return m, nil
 			}
-			todolists, err := m.store.GetTodoLists()
+			todolists, err := m.store.GetTodoLists(ctx)
 			if err != nil {
 				m.errorMsg = "Failed to load lists: " + err.Error()
 				m.returnToMain()
@@ -135,7 +194,6 @@ func (m *model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				}
 			}
 			m.cursor = 0
-			m.scrollOffset = 0
 		}
 		m.returnToMain()
 		return m, nil
@@ -147,174 +205,297 @@ func (m *model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleTaskCreationFlow(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	key := msg.String()
-
-	if key == KeyEsc {
-		if m.taskFlow.step > TaskFlowInputText {
-			m.taskFlow.previousStep()
-			m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
-			m.textInput.Reset()
-		} else {
-			m.taskFlow.reset()
-			m.returnToMain()
-		}
-		return m, nil
+	if m.taskFlow.step == TaskFlowSelectPriority {
+		return m.runKeyAction(msg, m.prioritySelectionActions())
 	}
-
-	if key == KeyEnter {
-		switch m.taskFlow.step {
-		case TaskFlowInputText:
-			taskText, err := validateTaskText(m.textInput.Value())
-			if err != nil {
-				m.errorMsg = err.Error()
-				return m, nil
-			}
-			m.taskFlow.text = taskText
-			m.taskFlow.priority = DefaultPriority
-			m.taskFlow.nextStep()
-			m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
-			m.textInput.Reset()
-			return m, nil
-
-		case TaskFlowSelectPriority:
-			m.taskFlow.nextStep()
-			m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
-			m.textInput.Reset()
-			return m, nil
-
-		case TaskFlowSetDueDate:
-			dueDate := parseDueDate(m.textInput.Value())
-
-			if m.currentSubState == SubStateEditDueDate && m.input.itemIndex >= 0 && m.input.itemIndex < len(m.items) {
-				m.items[m.input.itemIndex].dueDate = dueDate
-				m.items[m.input.itemIndex].todoListID = m.currentListID
-				if err := m.store.UpdateItem(m.items[m.input.itemIndex]); err != nil {
-					m.errorMsg = "Failed to update task: " + err.Error()
-				}
-				m.invalidateCache()
-			} else {
-				newTask := todoItem{
-					done:       false,
-					todo:       m.taskFlow.text,
-					priority:   m.taskFlow.priority,
-					dateAdded:  time.Now().Unix(),
-					dueDate:    dueDate,
-					todoListID: m.currentListID,
-				}
-				if err := m.store.SaveItem(newTask); err != nil {
-					m.errorMsg = "Failed to save task: " + err.Error()
-				}
-				m.items = append(m.items, newTask)
-				m.sortItems()
-				m.cursor = 0
-			}
-
-			m.taskFlow.reset()
-			m.returnToMain()
-			return m, nil
-		}
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelTaskFlow()
+		return m, nil
+	case key.Matches(msg, m.keys.Confirm):
+		m.confirmTaskFlow()
 		return m, nil
 	}
 
-	if m.taskFlow.step == TaskFlowSelectPriority {
-		switch key {
-		case KeyPriority1:
-			m.taskFlow.priority = PriorityHigh
-			return m, nil
-		case KeyPriority2:
-			m.taskFlow.priority = PriorityMedHigh
-			return m, nil
-		case KeyPriority3:
-			m.taskFlow.priority = PriorityMed
-			return m, nil
-		case KeyPriority4:
-			m.taskFlow.priority = PriorityLow
-			return m, nil
-		case KeyUp, KeyK:
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// prioritySelectionActions are the actions of the task flow's priority step
+func (m *model) prioritySelectionActions() []keyAction {
+	k := m.keys
+	actions := []keyAction{
+		{k.Up, act(func(m *model) {
 			if m.taskFlow.priority > PriorityHigh {
 				m.taskFlow.priority--
 			}
-			return m, nil
-		case KeyDown, KeyJ:
+		})},
+		{k.Down, act(func(m *model) {
 			if m.taskFlow.priority < PriorityLow {
 				m.taskFlow.priority++
 			}
-			return m, nil
-		}
+		})},
 	}
+	actions = append(actions, priorityActions(func(m *model, priority int) {
+		m.taskFlow.priority = priority
+	})...)
+	return append(actions,
+		keyAction{k.Confirm, act((*model).confirmTaskFlow)},
+		keyAction{k.Cancel, act((*model).cancelTaskFlow)},
+	)
+}
 
-	var cmd tea.Cmd
-	m.textInput, cmd = m.textInput.Update(msg)
-	return m, cmd
+// cancelTaskFlow steps back through the task flow, leaving it from its
+// first step or when it edits existing tasks
+func (m *model) cancelTaskFlow() {
+	if m.editsExistingTasks() {
+		m.taskFlow.reset()
+		m.returnToMain()
+	} else if m.taskFlow.step > TaskFlowInputText {
+		m.taskFlow.previousStep()
+		m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
+		m.textInput.Reset()
+	} else {
+		m.taskFlow.reset()
+		m.returnToMain()
+	}
 }
 
-func (m *model) handleMainKeyboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyCtrlC, KeyQ:
-		return m, tea.Quit
-	case KeyL:
-		m.input.listIndex = m.currentListIndex
-		m.setState(StateListSelector, SubStateNone)
-		return m, nil
-	case KeyS:
-		if m.syncEnabled {
-			if syncStore, ok := m.store.(*SyncStore); ok {
-				m.syncStatus.syncing = true
-				return m, func() tea.Msg {
-					if err := syncStore.FullSync(); err != nil {
-						m.syncStatus.errorMessage = err.Error()
-					} else {
-						m.syncStatus.errorMessage = ""
-						m.syncStatus.lastSyncTime = time.Now().Unix()
-					}
-					m.syncStatus.syncing = false
-					return nil
-				}
-			}
-		}
-		return m, nil
-	case KeyUp, KeyK:
-		if m.cursor > 0 {
-			m.cursor--
+// confirmTaskFlow finishes the task flow's current step
+func (m *model) confirmTaskFlow() {
+	switch m.taskFlow.step {
+	case TaskFlowInputText:
+		q, err := parseQuickAdd(m.textInput.Value(), m.todoLists)
+		if err != nil {
+			m.errorMsg = err.Error()
+			return
 		}
-	case KeyDown, KeyJ:
-		visibleCount := m.getVisibleItemCount()
-		if m.cursor < visibleCount-1 {
-			m.cursor++
+		taskText, err := validateTaskText(q.text)
+		if err != nil {
+			m.errorMsg = err.Error()
+			return
 		}
-	case KeyEnter:
-		if m.cursor < m.getVisibleItemCount() {
-			m.toggleTaskDone(m.cursor)
+		if q.hasFields() {
+			list, err := m.addQuickTask(q)
+			if err != nil {
+				m.errorMsg = "Failed to save task: " + err.Error()
+				return
+			}
+			if list.id != m.currentListID {
+				m.statusMsg = "Added to " + list.name
+			}
+			m.taskFlow.reset()
+			m.returnToMain()
+			return
 		}
-	case KeySpace:
-		if m.cursor < m.getVisibleItemCount() {
-			m.toggleTaskDone(m.cursor)
+		m.taskFlow.text = taskText
+		m.taskFlow.priority = m.defaultPriority
+		m.taskFlow.nextStep()
+		m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
+		m.textInput.Reset()
+
+	case TaskFlowSelectPriority:
+		if m.currentSubState == SubStateBulkPriority {
+			m.bulkSetPriority(m.taskFlow.priority)
+			m.taskFlow.reset()
+			m.returnToMain()
+			return
 		}
-	case KeyD:
-		if m.cursor < m.getVisibleItemCount() {
-			m.input.deleteIndex = m.cursor
-			m.setState(StateDeleteConfirm, SubStateNone)
+		if m.currentSubState == SubStateEditPriority {
+			m.setTaskPriority(m.input.itemIndex, m.taskFlow.priority)
+			m.taskFlow.reset()
+			m.returnToMain()
+			return
 		}
-	case KeyA:
+		m.taskFlow.nextStep()
+		m.setState(m.getStateForFlowStep(m.taskFlow.step), SubStateNone)
 		m.textInput.Reset()
-		m.textInput.Focus()
-		m.setState(StateTaskInput, SubStateNone)
-	case KeyE:
-		if m.cursor < m.getVisibleItemCount() {
-			m.input.itemIndex = m.getVisibleItemActualIndex(m.cursor)
-			m.textInput.SetValue(m.items[m.input.itemIndex].todo)
-			m.textInput.Focus()
-			m.setState(StateEditTask, SubStateNone)
+
+	case TaskFlowSetDueDate:
+		dueDate := parseDueDate(m.textInput.Value())
+		ctx, cancel := m.storeContext()
+		defer cancel()
+
+		if m.currentSubState == SubStateBulkDueDate {
+			m.bulkSetDueDate(dueDate)
+		} else if m.currentSubState == SubStateEditDueDate && m.input.itemIndex >= 0 && m.input.itemIndex < len(m.items) {
+			before := m.items[m.input.itemIndex]
+			m.items[m.input.itemIndex].dueDate = dueDate
+			if err := m.store.UpdateItem(ctx, m.items[m.input.itemIndex]); err != nil {
+				m.errorMsg = "Failed to update task: " + err.Error()
+			} else {
+				m.recordUndo(updateItemCommand("change due date", before, m.items[m.input.itemIndex]))
+			}
+			m.invalidateCache()
+		} else {
+			newTask := todoItem{
+				todo:       m.taskFlow.text,
+				priority:   m.taskFlow.priority,
+				dueDate:    dueDate,
+				todoListID: m.currentListID,
+			}
+			if err := m.addTask(newTask); err != nil {
+				m.errorMsg = "Failed to save task: " + err.Error()
+			}
 		}
-	case KeyT:
-		if m.cursor < m.getVisibleItemCount() {
-			m.input.itemIndex = m.getVisibleItemActualIndex(m.cursor)
+
+		m.taskFlow.reset()
+		m.returnToMain()
+	}
+}
+
+func (m *model) handleMainKeyboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	return m.runKeyAction(msg, m.taskListActions())
+}
+
+// taskListActions are the task list's actions, in the order of its help
+func (m *model) taskListActions() []keyAction {
+	k := m.keys
+	actions := []keyAction{
+		{k.Up, act(func(m *model) { m.moveCursor(-1) })},
+		{k.Down, act(func(m *model) { m.moveCursor(1) })},
+		{k.Top, act(func(m *model) { m.cursor = 0 })},
+		{k.Bottom, act(func(m *model) { m.moveCursor(m.getVisibleItemCount()) })},
+		{k.PageUp, act(func(m *model) { m.moveCursor(-m.pageSize()) })},
+		{k.PageDown, act(func(m *model) { m.moveCursor(m.pageSize()) })},
+		{k.Details, act(func(m *model) {
+			if len(m.marked) > 0 {
+				m.bulkToggleDone()
+			} else {
+				m.openTaskDetail()
+			}
+		})},
+		{k.ToggleDone, act(func(m *model) {
+			if len(m.marked) > 0 {
+				m.toggleMark()
+			} else if m.cursor < m.getVisibleItemCount() {
+				m.toggleTaskDone(m.cursor)
+			}
+		})},
+		{k.Add, act(func(m *model) {
 			m.textInput.Reset()
 			m.textInput.Focus()
-			m.setState(StateDueDateInput, SubStateEditDueDate)
+			m.setState(StateTaskInput, SubStateNone)
+		})},
+		{k.Edit, act(func(m *model) {
+			if m.cursor < m.getVisibleItemCount() {
+				m.input.itemIndex = m.getVisibleItemActualIndex(m.cursor)
+				m.textInput.SetValue(m.items[m.input.itemIndex].todo)
+				m.textInput.Focus()
+				m.setState(StateEditTask, SubStateNone)
+			}
+		})},
+		{k.Delete, act(func(m *model) {
+			if len(m.marked) > 0 {
+				m.setState(StateDeleteConfirm, SubStateBulkDelete)
+			} else if m.cursor < m.getVisibleItemCount() {
+				m.input.deleteIndex = m.cursor
+				m.setState(StateDeleteConfirm, SubStateNone)
+			}
+		})},
+		{k.Priority, act((*model).startPriorityEdit)},
+		{k.RaisePriority, act(func(m *model) { m.bumpPriority(-1) })},
+		{k.LowerPriority, act(func(m *model) { m.bumpPriority(1) })},
+	}
+	actions = append(actions, priorityActions(func(m *model, priority int) {
+		if len(m.marked) > 0 {
+			m.bulkSetPriority(priority)
 		}
+	})...)
+	actions = append(actions, []keyAction{
+		{k.DueDate, act(func(m *model) {
+			if len(m.marked) > 0 {
+				m.startDueDateEdit(SubStateBulkDueDate)
+			} else if m.cursor < m.getVisibleItemCount() {
+				m.input.itemIndex = m.getVisibleItemActualIndex(m.cursor)
+				m.startDueDateEdit(SubStateEditDueDate)
+			}
+		})},
+		{k.Mark, act((*model).toggleMark)},
+		{k.ClearMarks, act((*model).clearMarks)},
+		{k.Move, act(func(m *model) { m.startMove(false) })},
+		{k.Copy, act(func(m *model) { m.startMove(true) })},
+		{k.MoveDown, act(func(m *model) {
+			if m.listSortMode(m.currentListID) == SortModeManual && len(m.marked) == 0 {
+				m.moveTask(1)
+			} else {
+				m.extendMark(1)
+			}
+		})},
+		{k.MoveUp, act(func(m *model) {
+			if m.listSortMode(m.currentListID) == SortModeManual && len(m.marked) == 0 {
+				m.moveTask(-1)
+			} else {
+				m.extendMark(-1)
+			}
+		})},
+		{k.Undo, act((*model).undo)},
+		{k.Redo, act((*model).redo)},
+		{k.Sort, act((*model).cycleSortMode)},
+		{k.Group, act((*model).cycleGroupMode)},
+		{k.FilterCompleted, act((*model).cycleCompletedFilter)},
+		{k.Lists, act(func(m *model) {
+			m.input.listIndex = m.currentListIndex
+			m.setState(StateListSelector, SubStateNone)
+		})},
+		{k.Calendar, act((*model).openCalendar)},
+		{k.Trash, act((*model).openTrash)},
+		{k.Profiles, act((*model).openProfilePicker)},
+	}...)
+	if m.syncEnabled {
+		actions = append(actions, keyAction{k.Sync, (*model).startSync})
 	}
-	return m, nil
+	return append(actions, keyAction{k.Quit, func(m *model) (tea.Model, tea.Cmd) { return m, tea.Quit }})
+}
+
+// startSync runs a full sync in the background
+func (m *model) startSync() (tea.Model, tea.Cmd) {
+	syncStore, ok := m.store.(*SyncStore)
+	if !ok {
+		return m, nil
+	}
+	m.syncStatus.syncing = true
+	return m, func() tea.Msg {
+		if err := syncStore.FullSync(syncStore.ctx); err != nil {
+			m.syncStatus.errorMessage = err.Error()
+		} else {
+			m.syncStatus.errorMessage = ""
+			m.syncStatus.lastSyncTime = time.Now().Unix()
+		}
+		m.syncStatus.syncing = false
+		return nil
+	}
+}
+
+// moveCursor moves the cursor by delta tasks, stopping at either end
+func (m *model) moveCursor(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, m.getVisibleItemCount()-1))
+}
+
+// pageSize is the number of tasks that fit in the viewport
+func (m *model) pageSize() int {
+	return max(1, m.viewport.Height/TaskLines)
+}
+
+// editsExistingTasks reports whether the task flow was opened to change one
+// field of existing tasks rather than to create a task
+func (m *model) editsExistingTasks() bool {
+	switch m.currentSubState {
+	case SubStateEditDueDate, SubStateBulkDueDate, SubStateEditPriority, SubStateBulkPriority:
+		return true
+	}
+	return false
+}
+
+// startDueDateEdit opens the due date prompt for existing tasks, skipping
+// the earlier steps of the task creation flow
+func (m *model) startDueDateEdit(subState SubState) {
+	m.taskFlow.reset()
+	m.taskFlow.step = TaskFlowSetDueDate
+	m.textInput.Reset()
+	m.textInput.Focus()
+	m.setState(StateDueDateInput, subState)
 }
 
 func (m *model) toggleTaskDone(visibleIndex int) {
@@ -322,14 +503,21 @@ func (m *model) toggleTaskDone(visibleIndex int) {
 	if actualIndex < 0 || actualIndex >= len(m.items) {
 		return
 	}
+	before := m.items[actualIndex]
 	m.items[actualIndex].done = !m.items[actualIndex].done
 	if m.items[actualIndex].done {
 		m.items[actualIndex].dateCompleted = time.Now().Unix()
 	} else {
 		m.items[actualIndex].dateCompleted = 0
 	}
-	if err := m.store.UpdateItem(m.items[actualIndex]); err != nil {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+	if err := m.store.UpdateItem(ctx, m.items[actualIndex]); err != nil {
 		m.errorMsg = "Failed to update task: " + err.Error()
+	} else if m.items[actualIndex].done {
+		m.recordUndo(updateItemCommand("complete task", before, m.items[actualIndex]))
+	} else {
+		m.recordUndo(updateItemCommand("reopen task", before, m.items[actualIndex]))
 	}
 	m.invalidateCache()
 	m.sortItems()
@@ -346,51 +534,65 @@ func (m *model) handleListSelector(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleListDeleteConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyY:
-		m.deleteCurrentList()
-	case KeyN, KeyEsc:
-		m.currentSubState = SubStateListManage
+	return m.runKeyAction(msg, m.listDeleteConfirmActions())
+}
+
+func (m *model) listDeleteConfirmActions() []keyAction {
+	backToMenu := act(func(m *model) { m.currentSubState = SubStateListManage })
+	return []keyAction{
+		{m.keys.Yes, act((*model).deleteCurrentList)},
+		{m.keys.No, backToMenu},
+		{m.keys.Cancel, backToMenu},
 	}
-	return m, nil
 }
 
 func (m *model) handleListManageMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyR:
-		m.enterRenameMode()
-	case KeyD:
-		m.currentSubState = SubStateListDeleteConfirm
-	case KeyA:
-		m.toggleListArchive()
-	case KeyEsc:
-		m.currentSubState = SubStateNone
+	return m.runKeyAction(msg, m.listManageActions())
+}
+
+func (m *model) listManageActions() []keyAction {
+	return []keyAction{
+		{m.keys.RenameList, act((*model).enterRenameMode)},
+		{m.keys.DeleteList, act(func(m *model) { m.currentSubState = SubStateListDeleteConfirm })},
+		{m.keys.ArchiveList, act((*model).toggleListArchive)},
+		{m.keys.Cancel, act(func(m *model) { m.currentSubState = SubStateNone })},
 	}
-	return m, nil
 }
 
 func (m *model) handleListNavigation(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case KeyUp, KeyK:
-		if m.input.listIndex > 0 {
-			m.input.listIndex--
-		}
-	case KeyDown, KeyJ:
-		if m.input.listIndex < len(m.todoLists)-1 {
-			m.input.listIndex++
-		}
-	case KeyM:
-		if m.input.listIndex < len(m.todoLists) {
-			m.currentSubState = SubStateListManage
-		}
-	case KeyEnter:
-		m.handleListSelection()
-	case KeyN:
-		m.startCreateNewList()
-	case KeyEsc:
-		m.returnToMain()
+	return m.runKeyAction(msg, m.listSelectorActions())
+}
+
+func (m *model) listSelectorActions() []keyAction {
+	k := m.keys
+	return []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.input.listIndex > 0 {
+				m.input.listIndex--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.input.listIndex < m.selectorRowCount()-1 {
+				m.input.listIndex++
+			}
+		})},
+		{k.Confirm, act((*model).handleListSelection)},
+		{k.Cancel, act((*model).returnToMain)},
+		{k.NewList, act((*model).startCreateNewList)},
+		{k.ManageList, act(func(m *model) {
+			if m.input.listIndex < len(m.todoLists) {
+				m.currentSubState = SubStateListManage
+			}
+		})},
+		{k.ShowArchived, act((*model).toggleShowArchived)},
+		{k.Unarchive, act(func(m *model) {
+			if list := m.selectedArchivedList(); list != nil {
+				m.unarchiveList(*list)
+			}
+		})},
+		{k.MoveDown, act(func(m *model) { m.moveList(1) })},
+		{k.MoveUp, act(func(m *model) { m.moveList(-1) })},
 	}
-	return m, nil
 }
 
 func (m *model) deleteCurrentList() {
@@ -399,20 +601,27 @@ func (m *model) deleteCurrentList() {
 		return
 	}
 
-	selectedListID := m.todoLists[m.input.listIndex].id
-	if err := m.store.DeleteTodoList(selectedListID); err != nil {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	selectedList := m.todoLists[m.input.listIndex]
+	selectedListID := selectedList.id
+	if err := m.store.DeleteTodoList(ctx, selectedListID); err != nil {
 		m.errorMsg = "Failed to delete list: " + err.Error()
 		m.currentSubState = SubStateNone
 		return
 	}
 
-	var remainingItems []todoItem
+	var remainingItems, deletedItems []todoItem
 	for _, item := range m.items {
 		if item.todoListID != selectedListID {
 			remainingItems = append(remainingItems, item)
+		} else {
+			deletedItems = append(deletedItems, item)
 		}
 	}
 	m.items = remainingItems
+	m.recordUndo(deleteListCommand(selectedList, deletedItems))
 	m.invalidateCache()
 
 	m.todoLists = append(m.todoLists[:m.input.listIndex], m.todoLists[m.input.listIndex+1:]...)
@@ -440,37 +649,37 @@ func (m *model) toggleListArchive() {
 		return
 	}
 
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
 	selectedList := m.todoLists[m.input.listIndex]
 	if selectedList.archived {
-		if err := m.store.UnarchiveTodoList(selectedList.id); err != nil {
-			m.errorMsg = "Failed to unarchive list: " + err.Error()
-			return
-		}
-		m.todoLists[m.input.listIndex].archived = false
-	} else {
-		if err := m.store.ArchiveTodoList(selectedList.id); err != nil {
-			m.errorMsg = "Failed to archive list: " + err.Error()
-			return
-		}
-		m.todoLists[m.input.listIndex].archived = true
-		if selectedList.id == m.currentListID {
-			m.switchToFirstNonArchivedList()
-		}
+		m.unarchiveList(selectedList)
+		return
 	}
-	m.currentSubState = SubStateNone
-}
 
-func (m *model) switchToFirstNonArchivedList() {
-	for i, list := range m.todoLists {
-		if !list.archived {
-			m.switchToList(i)
-			return
-		}
+	if err := m.store.ArchiveTodoList(ctx, selectedList.id); err != nil {
+		m.errorMsg = "Failed to archive list: " + err.Error()
+		return
+	}
+	m.recordUndo(archiveListCommand(selectedList.id, true))
+
+	// Reloading moves the list into the archived section and off the
+	// current list if it was selected
+	m.reloadFromStore(ctx)
+	if m.input.listIndex >= m.selectorRowCount() && m.input.listIndex > 0 {
+		m.input.listIndex = m.selectorRowCount() - 1
 	}
+	m.statusMsg = "Archived list " + selectedList.name
+	m.currentSubState = SubStateNone
 }
 
 func (m *model) handleListSelection() {
-	if m.input.listIndex == len(m.todoLists) {
+	if list := m.selectedArchivedList(); list != nil {
+		m.openArchivedList(*list)
+		return
+	}
+	if m.input.listIndex == m.selectorRowCount() {
 		m.startCreateNewList()
 		return
 	}
@@ -486,8 +695,8 @@ func (m *model) switchToList(index int) {
 	}
 	m.currentListIndex = index
 	m.currentListID = m.todoLists[index].id
+	m.clearMarks()
 	m.cursor = 0
-	m.scrollOffset = 0
 	m.invalidateCache()
 }
 
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// KeyMap holds the key bindings of every screen. Bindings on different
+// screens may share keys; bindings on the same screen may not.
+type KeyMap struct {
+	// Shared by the pickers, dialogs and text inputs
+	Up      key.Binding
+	Down    key.Binding
+	Confirm key.Binding
+	Cancel  key.Binding
+	Close   key.Binding // Leaves a read-only view
+	Yes     key.Binding
+	No      key.Binding
+
+	// Task list
+	Top             key.Binding
+	Bottom          key.Binding
+	PageUp          key.Binding
+	PageDown        key.Binding
+	Add             key.Binding
+	Edit            key.Binding
+	Delete          key.Binding
+	ToggleDone      key.Binding
+	Details         key.Binding
+	Priority        key.Binding
+	RaisePriority   key.Binding
+	LowerPriority   key.Binding
+	SetPriority     key.Binding // The number keys, not configurable
+	DueDate         key.Binding
+	Mark            key.Binding
+	ClearMarks      key.Binding
+	Move            key.Binding
+	Copy            key.Binding
+	MoveDown        key.Binding // Moves the task in manual order or extends the marks
+	MoveUp          key.Binding
+	Sort            key.Binding
+	Group           key.Binding
+	FilterCompleted key.Binding
+	Lists           key.Binding
+	Calendar        key.Binding
+	Trash           key.Binding
+	Profiles        key.Binding
+	Undo            key.Binding
+	Redo            key.Binding
+	Sync            key.Binding
+	Help            key.Binding
+	Palette         key.Binding
+	Quit            key.Binding
+
+	// List selector
+	NewList      key.Binding
+	ManageList   key.Binding
+	ShowArchived key.Binding
+	Unarchive    key.Binding
+	RenameList   key.Binding
+	DeleteList   key.Binding
+	ArchiveList  key.Binding
+
+	// Trash
+	Restore key.Binding
+	Purge   key.Binding
+
+	// Calendar
+	PrevDay      key.Binding
+	NextDay      key.Binding
+	PrevWeek     key.Binding
+	NextWeek     key.Binding
+	PrevMonth    key.Binding
+	NextMonth    key.Binding
+	Today        key.Binding
+	CalendarView key.Binding // Switches between the month and week layouts
+	NextDayTask  key.Binding
+	PrevDayTask  key.Binding
+	Reschedule   key.Binding // Picks up the selected task, then drops it on the selected day
+}
+
+// Keymap presets selected by the keymap setting
+const (
+	KeymapDefault = "default"
+	KeymapVim     = "vim"
+	KeymapEmacs   = "emacs"
+)
+
+// KeymapPresets lists the accepted keymap setting values
+var KeymapPresets = []string{KeymapDefault, KeymapVim, KeymapEmacs}
+
+func binding(help string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyHelp(keys), help))
+}
+
+// keyHelp names keys the way the help shows them
+func keyHelp(keys []string) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case " ":
+			names[i] = "space"
+		case "up":
+			names[i] = "↑"
+		case "down":
+			names[i] = "↓"
+		case "left":
+			names[i] = "←"
+		case "right":
+			names[i] = "→"
+		default:
+			names[i] = k
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// DefaultKeyMap returns the built-in bindings
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:      binding("up", "up", "k"),
+		Down:    binding("down", "down", "j"),
+		Confirm: binding("confirm", "enter"),
+		Cancel:  binding("cancel", "esc"),
+		Close:   binding("back", "esc", "q"),
+		Yes:     binding("yes", "y"),
+		No:      binding("no", "n"),
+
+		Top:             binding("first task", "home"),
+		Bottom:          binding("last task", "end"),
+		PageUp:          binding("page up", "pgup"),
+		PageDown:        binding("page down", "pgdown"),
+		Add:             binding("add", "a"),
+		Edit:            binding("edit", "e"),
+		Delete:          binding("delete", "d"),
+		ToggleDone:      binding("complete", " "),
+		Details:         binding("details", "enter"),
+		Priority:        binding("priority", "p"),
+		RaisePriority:   binding("raise priority", "+"),
+		LowerPriority:   binding("lower priority", "-"),
+		SetPriority:     key.NewBinding(key.WithKeys("1", "2", "3", "4"), key.WithHelp("1-4", "set priority")),
+		DueDate:         binding("due date", "t"),
+		Mark:            binding("mark", "v"),
+		ClearMarks:      binding("clear marks", "esc"),
+		Move:            binding("move", "m"),
+		Copy:            binding("copy", "c"),
+		MoveDown:        binding("move down / mark down", "J"),
+		MoveUp:          binding("move up / mark up", "K"),
+		Sort:            binding("sort", "o"),
+		Group:           binding("group", "g"),
+		FilterCompleted: binding("hide completed", "h"),
+		Lists:           binding("lists", "l"),
+		Calendar:        binding("calendar", "C"),
+		Trash:           binding("trash", "x"),
+		Profiles:        binding("profiles", "P"),
+		Undo:            binding("undo", "u"),
+		Redo:            binding("redo", "ctrl+r"),
+		Sync:            binding("sync", "s"),
+		Help:            binding("help", "?"),
+		Palette:         binding("commands", "ctrl+p"),
+		Quit:            binding("quit", "q", "ctrl+c"),
+
+		NewList:      binding("new list", "n"),
+		ManageList:   binding("manage", "m"),
+		ShowArchived: binding("show archived", "v"),
+		Unarchive:    binding("unarchive", "a"),
+		RenameList:   binding("rename", "r"),
+		DeleteList:   binding("delete", "d"),
+		ArchiveList:  binding("archive", "a"),
+
+		Restore: binding("restore", "r"),
+		Purge:   binding("purge", "p"),
+
+		PrevDay:      binding("previous day", "left", "h"),
+		NextDay:      binding("next day", "right", "l"),
+		PrevWeek:     binding("previous week", "up", "k"),
+		NextWeek:     binding("next week", "down", "j"),
+		PrevMonth:    binding("previous month", "pgup", "["),
+		NextMonth:    binding("next month", "pgdown", "]"),
+		Today:        binding("today", "."),
+		CalendarView: binding("week/month", "w"),
+		NextDayTask:  binding("next task", "tab"),
+		PrevDayTask:  binding("previous task", "shift+tab"),
+		Reschedule:   binding("move task", "m", "enter"),
+	}
+}
+
+// presetKeyMap returns the bindings of a keymap preset. The vim preset adds
+// vim motions and moves grouping to z, as in folding; the emacs preset
+// replaces j/k with control keys.
+func presetKeyMap(preset string) KeyMap {
+	keys := DefaultKeyMap()
+	switch preset {
+	case KeymapVim:
+		keys.Top = binding("first task", "home", "g")
+		keys.Bottom = binding("last task", "end", "G")
+		keys.PageUp = binding("page up", "pgup", "ctrl+u")
+		keys.PageDown = binding("page down", "pgdown", "ctrl+d")
+		keys.Group = binding("group", "z")
+		keys.Delete = binding("delete", "d", "x")
+		keys.Trash = binding("trash", "X")
+		keys.Redo = binding("redo", "ctrl+r", "U")
+		keys.Edit = binding("edit", "e", "i")
+		keys.Palette = binding("commands", ":")
+	case KeymapEmacs:
+		keys.Up = binding("up", "up", "ctrl+p")
+		keys.Down = binding("down", "down", "ctrl+n")
+		keys.Cancel = binding("cancel", "esc", "ctrl+g")
+		keys.Close = binding("back", "esc", "q", "ctrl+g")
+		keys.Top = binding("first task", "home", "alt+<")
+		keys.Bottom = binding("last task", "end", "alt+>")
+		keys.PageUp = binding("page up", "pgup", "alt+v")
+		keys.PageDown = binding("page down", "pgdown", "ctrl+v")
+		keys.ClearMarks = binding("clear marks", "esc", "ctrl+g")
+		keys.Undo = binding("undo", "u", "ctrl+_", "ctrl+/")
+		keys.Redo = binding("redo", "ctrl+r", "alt+_")
+		keys.Quit = binding("quit", "q", "ctrl+c", "ctrl+x")
+		keys.MoveDown = binding("move down / mark down", "J", "alt+n")
+		keys.MoveUp = binding("move up / mark up", "K", "alt+p")
+		keys.Palette = binding("commands", "alt+x")
+		keys.PrevDay = binding("previous day", "left", "h", "ctrl+b")
+		keys.NextDay = binding("next day", "right", "l", "ctrl+f")
+		keys.PrevWeek = binding("previous week", "up", "k", "ctrl+p")
+		keys.NextWeek = binding("next week", "down", "j", "ctrl+n")
+	}
+	return keys
+}
+
+// keyActions maps the action names used in the [keybindings] table to the
+// bindings they change
+var keyActions = map[string]func(k *KeyMap) *key.Binding{
+	"up":                func(k *KeyMap) *key.Binding { return &k.Up },
+	"down":              func(k *KeyMap) *key.Binding { return &k.Down },
+	"confirm":           func(k *KeyMap) *key.Binding { return &k.Confirm },
+	"cancel":            func(k *KeyMap) *key.Binding { return &k.Cancel },
+	"close":             func(k *KeyMap) *key.Binding { return &k.Close },
+	"yes":               func(k *KeyMap) *key.Binding { return &k.Yes },
+	"no":                func(k *KeyMap) *key.Binding { return &k.No },
+	"top":               func(k *KeyMap) *key.Binding { return &k.Top },
+	"bottom":            func(k *KeyMap) *key.Binding { return &k.Bottom },
+	"page_up":           func(k *KeyMap) *key.Binding { return &k.PageUp },
+	"page_down":         func(k *KeyMap) *key.Binding { return &k.PageDown },
+	"add":               func(k *KeyMap) *key.Binding { return &k.Add },
+	"edit":              func(k *KeyMap) *key.Binding { return &k.Edit },
+	"delete":            func(k *KeyMap) *key.Binding { return &k.Delete },
+	"toggle_done":       func(k *KeyMap) *key.Binding { return &k.ToggleDone },
+	"details":           func(k *KeyMap) *key.Binding { return &k.Details },
+	"priority":          func(k *KeyMap) *key.Binding { return &k.Priority },
+	"raise_priority":    func(k *KeyMap) *key.Binding { return &k.RaisePriority },
+	"lower_priority":    func(k *KeyMap) *key.Binding { return &k.LowerPriority },
+	"due_date":          func(k *KeyMap) *key.Binding { return &k.DueDate },
+	"mark":              func(k *KeyMap) *key.Binding { return &k.Mark },
+	"clear_marks":       func(k *KeyMap) *key.Binding { return &k.ClearMarks },
+	"move":              func(k *KeyMap) *key.Binding { return &k.Move },
+	"copy":              func(k *KeyMap) *key.Binding { return &k.Copy },
+	"move_down":         func(k *KeyMap) *key.Binding { return &k.MoveDown },
+	"move_up":           func(k *KeyMap) *key.Binding { return &k.MoveUp },
+	"sort":              func(k *KeyMap) *key.Binding { return &k.Sort },
+	"group":             func(k *KeyMap) *key.Binding { return &k.Group },
+	"filter_completed":  func(k *KeyMap) *key.Binding { return &k.FilterCompleted },
+	"lists":             func(k *KeyMap) *key.Binding { return &k.Lists },
+	"calendar":          func(k *KeyMap) *key.Binding { return &k.Calendar },
+	"trash":             func(k *KeyMap) *key.Binding { return &k.Trash },
+	"profiles":          func(k *KeyMap) *key.Binding { return &k.Profiles },
+	"undo":              func(k *KeyMap) *key.Binding { return &k.Undo },
+	"redo":              func(k *KeyMap) *key.Binding { return &k.Redo },
+	"sync":              func(k *KeyMap) *key.Binding { return &k.Sync },
+	"help":              func(k *KeyMap) *key.Binding { return &k.Help },
+	"palette":           func(k *KeyMap) *key.Binding { return &k.Palette },
+	"quit":              func(k *KeyMap) *key.Binding { return &k.Quit },
+	"new_list":          func(k *KeyMap) *key.Binding { return &k.NewList },
+	"manage_list":       func(k *KeyMap) *key.Binding { return &k.ManageList },
+	"show_archived":     func(k *KeyMap) *key.Binding { return &k.ShowArchived },
+	"unarchive":         func(k *KeyMap) *key.Binding { return &k.Unarchive },
+	"rename_list":       func(k *KeyMap) *key.Binding { return &k.RenameList },
+	"delete_list":       func(k *KeyMap) *key.Binding { return &k.DeleteList },
+	"archive_list":      func(k *KeyMap) *key.Binding { return &k.ArchiveList },
+	"restore":           func(k *KeyMap) *key.Binding { return &k.Restore },
+	"purge":             func(k *KeyMap) *key.Binding { return &k.Purge },
+	"previous_day":      func(k *KeyMap) *key.Binding { return &k.PrevDay },
+	"next_day":          func(k *KeyMap) *key.Binding { return &k.NextDay },
+	"previous_week":     func(k *KeyMap) *key.Binding { return &k.PrevWeek },
+	"next_week":         func(k *KeyMap) *key.Binding { return &k.NextWeek },
+	"previous_month":    func(k *KeyMap) *key.Binding { return &k.PrevMonth },
+	"next_month":        func(k *KeyMap) *key.Binding { return &k.NextMonth },
+	"today":             func(k *KeyMap) *key.Binding { return &k.Today },
+	"calendar_view":     func(k *KeyMap) *key.Binding { return &k.CalendarView },
+	"next_day_task":     func(k *KeyMap) *key.Binding { return &k.NextDayTask },
+	"previous_day_task": func(k *KeyMap) *key.Binding { return &k.PrevDayTask },
+	"reschedule":        func(k *KeyMap) *key.Binding { return &k.Reschedule },
+}
+
+// keyNames lets the config file name keys that are awkward to write
+var keyNames = map[string]string{
+	"space": " ",
+	"comma": ",",
+}
+
+// newKeyMap builds the keymap for a preset with the [keybindings]
+// overrides applied. Each override is a comma separated list of keys that
+// replaces the action's keys. Unknown actions and keys used twice on one
+// screen are errors.
+func newKeyMap(preset string, overrides map[string]string) (KeyMap, error) {
+	keys := presetKeyMap(preset)
+
+	var errs []string
+	for action, value := range overrides {
+		field, ok := keyActions[action]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown action %q", action))
+			continue
+		}
+		var list []string
+		for _, k := range strings.Split(value, ",") {
+			k = strings.TrimSpace(k)
+			if name, ok := keyNames[k]; ok {
+				k = name
+			}
+			if k != "" {
+				list = append(list, k)
+			}
+		}
+		if len(list) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: no keys given", action))
+			continue
+		}
+		b := field(&keys)
+		*b = key.NewBinding(key.WithKeys(list...), key.WithHelp(keyHelp(list), b.Help().Desc))
+	}
+
+	for screen, groups := range keys.screens() {
+		errs = append(errs, keyConflicts(screen, groups)...)
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return keys, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return keys, nil
+}
+
+// keyConflicts reports keys bound to more than one action on a screen.
+// Bindings are told apart by their place on the screen, so two actions
+// with the same description still clash and a key listed twice by one
+// binding does not.
+func keyConflicts(screen string, groups [][]key.Binding) []string {
+	type owner struct {
+		index int
+		desc  string
+	}
+	owners := map[string]owner{}
+	var conflicts []string
+	index := 0
+	for _, group := range groups {
+		for _, b := range group {
+			desc := b.Help().Desc
+			for _, k := range b.Keys() {
+				if o, taken := owners[k]; taken && o.index != index {
+					conflicts = append(conflicts, fmt.Sprintf("%q is bound to both %s and %s in the %s", keyHelp([]string{k}), o.desc, desc, screen))
+					continue
+				}
+				owners[k] = owner{index, desc}
+			}
+			index++
+		}
+	}
+	return conflicts
+}
+
+// Screens with their own set of bindings
+const (
+	screenTaskList     = "task list"
+	screenListSelector = "list selector"
+	screenListMenu     = "list menu"
+	screenTrash        = "trash"
+	screenArchived     = "archived list"
+	screenCalendar     = "calendar"
+	screenDetails      = "task details"
+	screenPicker       = "pickers"
+	screenPriority     = "priority selection"
+	screenConfirm      = "confirmation"
+	screenTextInput    = "text input"
+)
+
+// screens returns every screen's bindings, grouped into help columns
+func (k KeyMap) screens() map[string][][]key.Binding {
+	return map[string][][]key.Binding{
+		screenTaskList: {
+			{k.Up, k.Down, k.Top, k.Bottom, k.PageUp, k.PageDown, k.Details, k.ToggleDone},
+			{k.Add, k.Edit, k.Delete, k.Priority, k.RaisePriority, k.LowerPriority, k.SetPriority, k.DueDate},
+			{k.Mark, k.ClearMarks, k.Move, k.Copy, k.MoveDown, k.MoveUp, k.Undo, k.Redo},
+			{k.Sort, k.Group, k.FilterCompleted, k.Lists, k.Calendar, k.Trash, k.Profiles, k.Sync, k.Help, k.Palette, k.Quit},
+		},
+		screenListSelector: {
+			{k.Up, k.Down, k.Confirm, k.Cancel, k.Help, k.Palette},
+			{k.NewList, k.ManageList, k.ShowArchived, k.Unarchive, k.MoveDown, k.MoveUp},
+		},
+		screenListMenu: {{k.RenameList, k.DeleteList, k.ArchiveList, k.Cancel, k.Help, k.Palette}},
+		screenTrash:    {{k.Up, k.Down, k.Restore, k.Purge, k.Close, k.Help, k.Palette}},
+		screenArchived: {{k.Up, k.Down, k.Unarchive, k.Close, k.Help, k.Palette}},
+		screenCalendar: {
+			{k.PrevDay, k.NextDay, k.PrevWeek, k.NextWeek, k.PrevMonth, k.NextMonth, k.Today},
+			{k.CalendarView, k.NextDayTask, k.PrevDayTask, k.Reschedule, k.Close, k.Help, k.Palette},
+		},
+		screenDetails:   {{k.Up, k.Down, k.Confirm, k.SetPriority, k.Close, k.Help, k.Palette}},
+		screenPicker:    {{k.Up, k.Down, k.Confirm, k.Cancel, k.Help, k.Palette}},
+		screenPriority:  {{k.Up, k.Down, k.SetPriority, k.Confirm, k.Cancel, k.Palette}},
+		screenConfirm:   {{k.Yes, k.No, k.Cancel, k.Palette}},
+		screenTextInput: {{k.Confirm, k.Cancel}},
+	}
+}
+
+// screenKeys implements help.KeyMap for one screen
+type screenKeys [][]key.Binding
+
+func (s screenKeys) ShortHelp() []key.Binding {
+	var bindings []key.Binding
+	for _, group := range s {
+		bindings = append(bindings, group...)
+	}
+	return bindings
+}
+
+func (s screenKeys) FullHelp() [][]key.Binding {
+	return s
+}
+
+var _ help.KeyMap = screenKeys{}
+
+// currentScreen names the set of bindings in use in the current state
+func (m *model) currentScreen() string {
+	switch m.currentState {
+	case StateListSelector:
+		switch m.currentSubState {
+		case SubStateListManage:
+			return screenListMenu
+		case SubStateListDeleteConfirm:
+			return screenConfirm
+		}
+		return screenListSelector
+	case StateTrash:
+		if m.currentSubState == SubStateTrashPurgeConfirm {
+			return screenConfirm
+		}
+		return screenTrash
+	case StateArchivedView:
+		return screenArchived
+	case StateCalendar:
+		return screenCalendar
+	case StateTaskDetail:
+		if m.currentSubState == SubStateDetailEdit {
+			return screenTextInput
+		}
+		return screenDetails
+	case StateMoveTask, StateProfilePicker:
+		return screenPicker
+	case StatePrioritySelection:
+		return screenPriority
+	case StateDeleteConfirm:
+		return screenConfirm
+	case StateTaskInput, StateDueDateInput, StateEditTask, StateListNameInput:
+		return screenTextInput
+	}
+	return screenTaskList
+}
+
+// typing reports whether keys go to a text input, so the help and palette
+// keys type a character instead of opening an overlay
+func (m *model) typing() bool {
+	return m.currentScreen() == screenTextInput
+}
+
+// renderHelp shows every binding of the current screen, wrapping the help
+// columns onto more rows when they do not fit the terminal
+func (m model) renderHelp() string {
+	h := help.New()
+	h.Styles.FullKey = lipgloss.NewStyle().Bold(true)
+	h.Styles.FullDesc = lipgloss.NewStyle()
+	h.Styles.FullSeparator = StatusStyle
+
+	screen := m.currentScreen()
+	lines := []string{TitleStyle.Render("Keys: " + screen)}
+	var row [][]key.Binding
+	for _, group := range m.keys.screens()[screen] {
+		if len(row) > 0 && m.width > 0 && lipgloss.Width(h.FullHelpView(append(row, group))) > m.width {
+			lines = append(lines, h.FullHelpView(row), "")
+			row = nil
+		}
+		row = append(row, group)
+	}
+	lines = append(lines, h.FullHelpView(row), "")
+	lines = append(lines, StatusStyle.Render("Press "+m.keys.Help.Help().Key+" or "+m.keys.Cancel.Help().Key+" to close"), "")
+	return strings.Join(lines, "\n")
+}
+
+// footerHelp is the one-line summary of the task list keys
+func (m model) footerHelp() string {
+	k := m.keys
+	return m.shortHelp([]key.Binding{
+		k.Help, k.Palette, k.Lists, k.Add, k.Details, k.ToggleDone, k.Edit, k.Priority, k.DueDate, k.Delete,
+		k.Move, k.Copy, k.Mark, k.Sort, k.Group, k.FilterCompleted, k.Calendar, k.Trash, k.Profiles, k.Undo, k.Redo, k.Quit,
+	})
+}
+
+// keyHint is the key summary under a screen, after an optional note. It is
+// built from the keymap so it shows the keys actually bound, and unlike the
+// footer it is never cut short.
+func (m model) keyHint(note string, bindings ...key.Binding) string {
+	if note != "" {
+		note += " "
+	}
+	return TitleStyle.Render("(" + note + newShortHelp().ShortHelpView(bindings) + ")")
+}
+
+// relabel describes b's action as desc, for hints where the action reads
+// better in context
+func relabel(b key.Binding, desc string) key.Binding {
+	return key.NewBinding(key.WithKeys(b.Keys()...), key.WithHelp(b.Help().Key, desc))
+}
+
+// yesNo is the answer hint of a confirmation prompt
+func (m model) yesNo() string {
+	return "(" + m.keys.Yes.Help().Key + "/" + m.keys.No.Help().Key + ")"
+}
+
+// shortHelp lists bindings on one line. Bindings are dropped from the end
+// until it fits the terminal: help.Model keeps adding them when the line is
+// too full for its ellipsis.
+func (m model) shortHelp(bindings []key.Binding) string {
+	h := newShortHelp()
+	view := h.ShortHelpView(bindings)
+	for m.width > 0 && len(bindings) > 1 && lipgloss.Width(view) > m.width {
+		bindings = bindings[:len(bindings)-1]
+		view = h.ShortHelpView(bindings) + " " + h.Ellipsis
+	}
+	return view
+}
+
+// newShortHelp lists bindings as "key action" pairs separated by commas
+func newShortHelp() help.Model {
+	h := help.New()
+	h.ShortSeparator = ", "
+	h.Styles.ShortKey = lipgloss.NewStyle()
+	h.Styles.ShortDesc = lipgloss.NewStyle()
+	h.Styles.ShortSeparator = lipgloss.NewStyle()
+	return h
+}
@@ -1,91 +1,230 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
-	cfg := LoadConfig()
+	cfg, args, err := LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage()
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	if len(args) > 0 {
+		os.Exit(runCommand(cfg, args))
+	}
+
+	opened, err := openStore(ctx, &cfg, func(format string, a ...interface{}) {
+		fmt.Printf("Warning: "+format+"\n", a...)
+	})
+	if err != nil {
+		logErrorMsg("open data store", err)
+		os.Exit(1)
+	}
+
+	// Load data using the store interface
+	m, err := loadModel(ctx, opened.store)
+	if err != nil {
+		logErrorMsg("load data", err)
+		os.Exit(1)
+	}
+	m.useStore(cfg, opened)
 
-	_, err := initDB(cfg.DBPath)
+	// Run the TUI
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if cfg.Mouse {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, options...)
+	final, err := p.Run()
+	// The store may have changed if a profile was picked
+	switch fm := final.(type) {
+	case model:
+		fm.closeStore()
+	case *model:
+		fm.closeStore()
+	}
 	if err != nil {
-		logErrorMsg("initialize database", err)
+		fmt.Printf("Error starting tea: %v", err)
 		os.Exit(1)
 	}
-	defer db.Close()
+}
 
-	// Create data store (local or with sync)
-	var store DataStore
-	var syncStore *SyncStore
-	localStore := NewLocalStore(db)
+// openedStore is an open data store with what is needed to close it
+type openedStore struct {
+	store    DataStore
+	sync     *SyncStore // nil when sync is off
+	close    func()
+	archived int // Completed tasks moved to the history at startup
+}
 
-	if cfg.Sync.Enabled {
-		// Create sync client
-		syncClient := NewSyncClient(cfg.Sync)
+// openStore opens the configured backend, adding sync when it is enabled,
+// then runs the startup housekeeping. Problems that do not stop the store
+// from opening are passed to warn. Sync is switched off in cfg when the
+// backend cannot sync.
+func openStore(ctx context.Context, cfg *Config, warn func(format string, a ...interface{})) (openedStore, error) {
+	opened, err := openBackend(ctx, cfg, true, warn)
+	if err != nil {
+		return opened, err
+	}
+	purgeExpiredTrash(ctx, opened.store, cfg.TrashRetentionDays, warn)
+	opened.archived = archiveOldCompletions(ctx, opened.store, cfg.HistoryAfterDays, warn)
+	return opened, nil
+}
+
+// openCommandStore opens the configured backend for a one-off command.
+// Changes are still logged for sync, but nothing is synced and the startup
+// housekeeping is left to the app.
+func openCommandStore(ctx context.Context, cfg *Config, warn func(format string, a ...interface{})) (openedStore, error) {
+	return openBackend(ctx, cfg, false, warn)
+}
 
-		// Create sync store
-		syncStore = NewSyncStore(localStore, syncClient, cfg.Sync)
+// openBackend opens the configured backend, adding sync when it is
+// enabled. With syncNow it syncs straight away and keeps syncing in the
+// background.
+func openBackend(ctx context.Context, cfg *Config, syncNow bool, warn func(format string, a ...interface{})) (openedStore, error) {
+	opened := openedStore{close: func() {}}
 
-		// Perform initial sync if online
-		if syncClient.IsOnline() {
-			if err := syncStore.FullSync(); err != nil {
-				fmt.Printf("Warning: initial sync failed: %v\n", err)
+	if cfg.Sync.Enabled && cfg.Backend != BackendSQLite {
+		warn("sync is not available with the %s backend", cfg.Backend)
+		cfg.Sync.Enabled = false
+	}
+
+	switch cfg.Backend {
+	case BackendTodoTxt:
+		txtStore, err := NewTodoTxtStore(cfg.TodoTxtPath, cfg.DoneTxtPath)
+		if err != nil {
+			return opened, fmt.Errorf("open todo.txt: %w", err)
+		}
+		opened.store = txtStore
+	case BackendMarkdown:
+		path := cfg.MarkdownPath
+		if path == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return opened, fmt.Errorf("get working directory: %w", err)
 			}
+			path = findMarkdownFile(cwd)
 		}
+		mdStore, err := NewMarkdownStore(path)
+		if err != nil {
+			return opened, fmt.Errorf("open %s: %w", path, err)
+		}
+		opened.store = mdStore
+	case BackendSQLite:
+		if err := ensureDBDirectory(cfg.DBPath); err != nil {
+			warn("failed to create database directory: %v", err)
+		}
+		localStore, err := OpenLocalStore(ctx, cfg.DBPath)
+		if err != nil {
+			return opened, fmt.Errorf("initialize database: %w", err)
+		}
+		syncStore, err := openSQLiteStore(ctx, *cfg, localStore, syncNow, warn)
+		if err != nil {
+			localStore.Close()
+			return opened, err
+		}
+		opened.store = localStore
+		opened.close = func() { localStore.Close() }
+		if syncStore != nil {
+			opened.store = syncStore
+			opened.sync = syncStore
+			opened.close = func() {
+				syncStore.StopBackgroundSync()
+				localStore.Close()
+			}
+		}
+	default:
+		return opened, fmt.Errorf("select storage backend: unknown backend %q", cfg.Backend)
+	}
+	return opened, nil
+}
 
-		// Start background sync
-		syncStore.StartBackgroundSync()
-
-		store = syncStore
-	} else {
-		store = localStore
+// openSQLiteStore adds sync on top of the local store when it is enabled,
+// returning nil when it is not. Without syncNow the store only logs
+// changes for the next sync.
+func openSQLiteStore(ctx context.Context, cfg Config, localStore *LocalStore, syncNow bool, warn func(format string, a ...interface{})) (*SyncStore, error) {
+	if !cfg.Sync.Enabled {
+		return nil, nil
+	}
+	if !syncNow {
+		cfg.Sync.AutoSyncOnChange = false
 	}
 
-	// Load data using the store interface
-	todoLists, err := store.GetTodoLists()
+	cipher, err := openSyncCipher(cfg.Sync)
 	if err != nil {
-		logErrorMsg("load todo lists", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("load sync encryption key: %w", err)
 	}
 
-	if len(todoLists) == 0 {
-		id, err := store.CreateTodoList(DefaultListName)
+	if cfg.Sync.DeviceID == "" {
+		cfg.Sync.DeviceID, err = localStore.GetDeviceID(ctx)
 		if err != nil {
-			logErrorMsg("create default todo list", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("load device ID: %w", err)
 		}
-		todoLists = []todoList{{id: id, name: DefaultListName, archived: false}}
 	}
 
-	todoItems, err := store.GetItems()
+	// Create sync transport (HTTP server or shared directory)
+	syncClient, err := NewSyncTransport(cfg.Sync, cipher)
 	if err != nil {
-		logErrorMsg("load items from database", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("create sync transport: %w", err)
 	}
 
-	// Create model with store
-	m := initialModel(todoItems, todoLists)
-	m.store = store
-	m.syncEnabled = cfg.Sync.Enabled
-	if cfg.Sync.Enabled && syncStore != nil {
-		m.syncStatus.online = syncStore.client.IsOnline()
-		m.syncStatus.lastSyncTime = 0 // Will be set during first sync
+	// Create sync store
+	syncStore := NewSyncStore(localStore, syncClient, cfg.Sync)
+	if !syncNow {
+		return syncStore, nil
 	}
-	m.sortItems()
 
-	// Run the TUI
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	defer func() {
-		if syncStore != nil {
-			syncStore.StopBackgroundSync()
+	// Perform initial sync if online
+	if syncClient.IsOnline() {
+		if err := syncStore.FullSync(ctx); err != nil {
+			warn("initial sync failed: %v", err)
 		}
-	}()
+	}
 
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error starting tea: %v", err)
-		os.Exit(1)
+	// Start background sync
+	syncStore.StartBackgroundSync()
+
+	return syncStore, nil
+}
+
+// purgeExpiredTrash permanently removes items that have been in the trash
+// longer than the retention period
+func purgeExpiredTrash(ctx context.Context, store DataStore, retentionDays int, warn func(format string, a ...interface{})) {
+	if retentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	if _, err := store.PurgeDeletedBefore(ctx, cutoff); err != nil {
+		warn("failed to empty expired trash: %v", err)
+	}
+}
+
+// archiveOldCompletions moves tasks completed more than afterDays ago into
+// the history so they are no longer loaded at startup, and returns how many
+// were moved
+func archiveOldCompletions(ctx context.Context, store DataStore, afterDays int, warn func(format string, a ...interface{})) int {
+	if afterDays <= 0 {
+		return 0
+	}
+	cutoff := time.Now().AddDate(0, 0, -afterDays).Unix()
+	archived, err := store.ArchiveCompletedBefore(ctx, cutoff)
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		warn("failed to archive completed tasks: %v", err)
 	}
+	return archived
 }
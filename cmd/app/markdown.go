@@ -0,0 +1,699 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultMarkdownFileName is the checklist file discovered by walking up
+// from the working directory
+const defaultMarkdownFileName = "TODO.md"
+
+var (
+	mdHeadingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdCheckboxPattern = regexp.MustCompile(`^(\s*)([-*+])\s+\[([ xX])\]\s?(.*)$`)
+	mdFencePattern    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// mdLine is one line of the document. Task and heading lines point at
+// the entity they represent; everything else is kept verbatim.
+type mdLine struct {
+	raw    string
+	itemID int
+	listID int
+}
+
+// mdTask is a checkbox line and the formatting needed to write it back
+type mdTask struct {
+	item   todoItem
+	orig   todoItem
+	indent string
+	bullet string
+}
+
+// mdHeading is a heading line acting as a list
+type mdHeading struct {
+	list     todoList
+	level    int
+	origName string
+}
+
+// MarkdownStore implements DataStore on a markdown checklist such as a
+// repository's TODO.md. Each heading is a list and each `- [ ]` checkbox
+// under it is a task. Lines that are not headings or checkboxes, and tasks
+// that were not edited, are written back exactly as they were read.
+type MarkdownStore struct {
+	path          string
+	mu            sync.Mutex
+	lines         []mdLine
+	tasks         map[int]*mdTask
+	headings      map[int]*mdHeading
+	listOrder     []int
+	defaultListID int    // Virtual list for checkboxes above the first heading
+	newline       string // Line ending used by the file, "\n" or "\r\n"
+	finalNewline  bool   // Whether the file ended with a line ending
+	nextItemID    int
+	nextListID    int
+}
+
+// findMarkdownFile walks up from dir looking for TODO.md. If none exists
+// it returns dir/TODO.md so the file is created on first write.
+func findMarkdownFile(dir string) string {
+	current := dir
+	for {
+		candidate := filepath.Join(current, defaultMarkdownFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return filepath.Join(dir, defaultMarkdownFileName)
+		}
+		current = parent
+	}
+}
+
+// NewMarkdownStore loads the checklist at path. A missing file is treated
+// as empty.
+func NewMarkdownStore(path string) (*MarkdownStore, error) {
+	s := &MarkdownStore{
+		path:         path,
+		tasks:        make(map[int]*mdTask),
+		headings:     make(map[int]*mdHeading),
+		newline:      "\n",
+		finalNewline: true,
+		nextItemID:   1,
+		nextListID:   1,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	s.parse(s.splitLines(string(data)))
+	return s, nil
+}
+
+// splitLines splits the file into lines, remembering its line ending and
+// whether it ended with one so save can write them back the same way
+func (s *MarkdownStore) splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	if i := strings.Index(content, "\n"); i > 0 && content[i-1] == '\r' {
+		s.newline = "\r\n"
+	}
+	s.finalNewline = strings.HasSuffix(content, "\n")
+
+	raw := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range raw {
+		raw[i] = strings.TrimSuffix(line, "\r")
+	}
+	return raw
+}
+
+func (s *MarkdownStore) parse(raw []string) {
+	inFence := false
+	currentListID := 0
+
+	for _, text := range raw {
+		line := mdLine{raw: text}
+
+		if mdFencePattern.MatchString(text) {
+			inFence = !inFence
+			s.lines = append(s.lines, line)
+			continue
+		}
+		if inFence {
+			s.lines = append(s.lines, line)
+			continue
+		}
+
+		if m := mdHeadingPattern.FindStringSubmatch(text); m != nil {
+			currentListID = s.addHeading(m[2], len(m[1]))
+			line.listID = currentListID
+		} else if m := mdCheckboxPattern.FindStringSubmatch(text); m != nil {
+			if currentListID == 0 {
+				currentListID = s.ensureDefaultList()
+			}
+			line.itemID = s.addTask(m[1], m[2], m[3] != " ", m[4], currentListID)
+		}
+
+		s.lines = append(s.lines, line)
+	}
+}
+
+func (s *MarkdownStore) addHeading(name string, level int) int {
+	id := s.nextListID
+	s.nextListID++
+	s.headings[id] = &mdHeading{
+		list: todoList{
+			id:           id,
+			clientID:     generateClientID(),
+			name:         name,
+			displayOrder: len(s.listOrder),
+			createdAt:    now(),
+			updatedAt:    now(),
+			version:      1,
+		},
+		level:    level,
+		origName: name,
+	}
+	s.listOrder = append(s.listOrder, id)
+	return id
+}
+
+// ensureDefaultList creates the virtual list that holds checkboxes above the
+// first heading
+func (s *MarkdownStore) ensureDefaultList() int {
+	if s.defaultListID == 0 {
+		s.defaultListID = s.addHeading(DefaultListName, 0)
+	}
+	return s.defaultListID
+}
+
+func (s *MarkdownStore) addTask(indent string, bullet string, done bool, text string, listID int) int {
+	item := parseMarkdownTaskText(text)
+	item.done = done
+	item.id = s.nextItemID
+	item.clientID = generateClientID()
+	item.todoListID = listID
+	item.version = 1
+	s.nextItemID++
+
+	s.tasks[item.id] = &mdTask{
+		item:   item,
+		orig:   item,
+		indent: indent,
+		bullet: bullet,
+	}
+	return item.id
+}
+
+// parseMarkdownTaskText reads an optional (A)-(D) priority prefix and a
+// due:YYYY-MM-DD token from checkbox text; everything else is task text
+func parseMarkdownTaskText(text string) todoItem {
+	item := todoItem{priority: DefaultPriority}
+	fields := strings.Fields(text)
+
+	if len(fields) > 0 && isTodoTxtPriority(fields[0]) {
+		item.priority = todoTxtPriorityFromLetter(fields[0][1:2])
+		fields = fields[1:]
+	}
+
+	var words []string
+	for _, field := range fields {
+		if strings.HasPrefix(field, "due:") {
+			if t, ok := parseTodoTxtDate(strings.TrimPrefix(field, "due:")); ok {
+				item.dueDate = setToEndOfDay(t).Unix()
+				continue
+			}
+		}
+		words = append(words, field)
+	}
+
+	item.todo = strings.Join(words, " ")
+	return item
+}
+
+// render returns the text of a line, regenerating it only if the entity it
+// represents was edited
+func (s *MarkdownStore) render(line mdLine) string {
+	if task, ok := s.tasks[line.itemID]; ok && line.itemID != 0 {
+		if sameMarkdownTask(task.item, task.orig) {
+			return line.raw
+		}
+		return formatMarkdownTask(task)
+	}
+	if heading, ok := s.headings[line.listID]; ok && line.listID != 0 {
+		if heading.list.name == heading.origName && line.raw != "" {
+			return line.raw
+		}
+		return strings.Repeat("#", heading.level) + " " + heading.list.name
+	}
+	return line.raw
+}
+
+func sameMarkdownTask(a, b todoItem) bool {
+	return a.todo == b.todo && a.done == b.done && a.priority == b.priority && a.dueDate == b.dueDate
+}
+
+// formatMarkdownTask renders a checkbox using the todo.txt conventions for
+// priority and due date. Default priority is left implicit.
+func formatMarkdownTask(task *mdTask) string {
+	box := "[ ]"
+	if task.item.done {
+		box = "[x]"
+	}
+
+	var parts []string
+	if task.item.priority != DefaultPriority {
+		parts = append(parts, "("+todoTxtPriorityLetters[validatePriority(task.item.priority)]+")")
+	}
+	parts = append(parts, task.item.todo)
+	if task.item.dueDate > 0 {
+		parts = append(parts, "due:"+formatTodoTxtDate(task.item.dueDate))
+	}
+
+	return task.indent + task.bullet + " " + box + " " + strings.Join(parts, " ")
+}
+
+func (s *MarkdownStore) save() error {
+	out := make([]string, len(s.lines))
+	for i, line := range s.lines {
+		out[i] = s.render(line)
+	}
+	content := strings.Join(out, s.newline)
+	if len(out) > 0 && s.finalNewline {
+		content += s.newline
+	}
+	return writeFileAtomic(s.path, content)
+}
+
+// insertLine inserts a line at index i
+func (s *MarkdownStore) insertLine(i int, line mdLine) {
+	s.lines = append(s.lines, mdLine{})
+	copy(s.lines[i+1:], s.lines[i:])
+	s.lines[i] = line
+}
+
+func (s *MarkdownStore) headingLine(listID int) int {
+	for i, line := range s.lines {
+		if line.listID == listID {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertPosition returns where a new task for listID should go: after the
+// section's last checkbox, or after the last non-blank line of the section
+func (s *MarkdownStore) insertPosition(listID int) int {
+	start := s.headingLine(listID)
+	if start < 0 && listID != s.defaultListID {
+		return len(s.lines)
+	}
+
+	lastTask, lastContent := -1, start
+	for i := start + 1; i < len(s.lines); i++ {
+		if s.lines[i].listID != 0 {
+			break
+		}
+		if task, ok := s.tasks[s.lines[i].itemID]; ok && task.item.todoListID == listID {
+			lastTask = i
+		}
+		if strings.TrimSpace(s.lines[i].raw) != "" {
+			lastContent = i
+		}
+	}
+	if lastTask >= 0 {
+		return lastTask + 1
+	}
+	return lastContent + 1
+}
+
+// GetTodoLists returns one list per heading, in document order
+func (s *MarkdownStore) GetTodoLists(ctx context.Context) ([]todoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := make([]todoList, 0, len(s.listOrder))
+	for _, id := range s.listOrder {
+		lists = append(lists, s.headings[id].list)
+	}
+	return lists, nil
+}
+
+// CreateTodoList appends a new second-level heading to the document
+func (s *MarkdownStore) CreateTodoList(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.addHeading(name, 2)
+	if len(s.lines) > 0 && strings.TrimSpace(s.lines[len(s.lines)-1].raw) != "" {
+		s.lines = append(s.lines, mdLine{})
+	}
+	s.lines = append(s.lines, mdLine{listID: id})
+	s.headings[id].origName = ""
+	return id, s.save()
+}
+
+// UpdateTodoListName rewrites the heading text, keeping its level
+func (s *MarkdownStore) UpdateTodoListName(ctx context.Context, id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heading, ok := s.headings[id]
+	if !ok {
+		return fmt.Errorf("list not found: %d", id)
+	}
+	if id == s.defaultListID {
+		return ErrNotSupported
+	}
+	heading.list.name = name
+	heading.list.updatedAt = now()
+	return s.save()
+}
+
+// DeleteTodoList removes the heading and its checkboxes. Other content in
+// the section is kept.
+func (s *MarkdownStore) DeleteTodoList(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.headings[id]; !ok {
+		return fmt.Errorf("list not found: %d", id)
+	}
+
+	remaining := s.lines[:0]
+	for _, line := range s.lines {
+		if line.listID == id {
+			continue
+		}
+		if task, ok := s.tasks[line.itemID]; ok && task.item.todoListID == id {
+			delete(s.tasks, line.itemID)
+			continue
+		}
+		remaining = append(remaining, line)
+	}
+	s.lines = remaining
+
+	delete(s.headings, id)
+	for i, listID := range s.listOrder {
+		if listID == id {
+			s.listOrder = append(s.listOrder[:i], s.listOrder[i+1:]...)
+			break
+		}
+	}
+	if id == s.defaultListID {
+		s.defaultListID = 0
+	}
+	return s.save()
+}
+
+// RestoreTodoList re-adds a deleted heading at the end of the file under its
+// original ID
+func (s *MarkdownStore) RestoreTodoList(ctx context.Context, list todoList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.headings[list.id]; ok {
+		return nil
+	}
+	list.archived = false
+	s.headings[list.id] = &mdHeading{list: list, level: 2}
+	s.listOrder = append(s.listOrder, list.id)
+	if len(s.lines) > 0 && strings.TrimSpace(s.lines[len(s.lines)-1].raw) != "" {
+		s.lines = append(s.lines, mdLine{})
+	}
+	s.lines = append(s.lines, mdLine{listID: list.id})
+	return s.save()
+}
+
+// ArchiveTodoList is not supported; markdown headings cannot be archived
+func (s *MarkdownStore) ArchiveTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// UnarchiveTodoList is not supported; markdown headings cannot be archived
+func (s *MarkdownStore) UnarchiveTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// GetItems returns every checkbox in document order
+func (s *MarkdownStore) GetItems(ctx context.Context) ([]todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []todoItem{}
+	for _, line := range s.lines {
+		if task, ok := s.tasks[line.itemID]; ok && line.itemID != 0 {
+			items = append(items, task.item)
+		}
+	}
+	return items, nil
+}
+
+// GetItemByID returns a task by its session ID
+func (s *MarkdownStore) GetItemByID(ctx context.Context, id int) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task, ok := s.tasks[id]; ok {
+		return task.item, nil
+	}
+	return todoItem{}, fmt.Errorf("item not found: %d", id)
+}
+
+// GetItemByClientID returns a task by its session client ID
+func (s *MarkdownStore) GetItemByClientID(ctx context.Context, clientID string) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, task := range s.tasks {
+		if task.item.clientID == clientID {
+			return task.item, nil
+		}
+	}
+	return todoItem{}, fmt.Errorf("item not found with client_id: %s", clientID)
+}
+
+// SaveItem inserts a checkbox at the end of its list's section
+func (s *MarkdownStore) SaveItem(ctx context.Context, item todoItem) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.headings[item.todoListID]; !ok {
+		return 0, fmt.Errorf("list not found: %d", item.todoListID)
+	}
+
+	item.id = s.nextItemID
+	s.nextItemID++
+	if item.clientID == "" {
+		item.clientID = generateClientID()
+	}
+	item.version = 1
+
+	s.insertTask(item)
+	return item.id, s.save()
+}
+
+func (s *MarkdownStore) insertTask(item todoItem) {
+	task := &mdTask{item: item, bullet: "-"}
+	s.tasks[item.id] = task
+	s.insertLine(s.insertPosition(item.todoListID), mdLine{raw: formatMarkdownTask(task), itemID: item.id})
+	task.orig = item
+}
+
+// RestoreItem re-inserts a deleted checkbox under its original ID
+func (s *MarkdownStore) RestoreItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[item.id]; ok {
+		return nil
+	}
+	if _, ok := s.headings[item.todoListID]; !ok {
+		return fmt.Errorf("list not found: %d", item.todoListID)
+	}
+	item.deleted = false
+	item.deletedAt = 0
+	s.insertTask(item)
+	return s.save()
+}
+
+// UpdateItem edits a checkbox in place. Moving a task to another list moves
+// its line into that list's section.
+func (s *MarkdownStore) UpdateItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUpdate(item); err != nil {
+		return err
+	}
+	s.updateTask(item)
+	return s.save()
+}
+
+// UpdateItems edits several checkboxes and writes the file once
+func (s *MarkdownStore) UpdateItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if err := s.checkUpdate(item); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		s.updateTask(item)
+	}
+	return s.save()
+}
+
+func (s *MarkdownStore) checkUpdate(item todoItem) error {
+	if _, ok := s.tasks[item.id]; !ok {
+		return fmt.Errorf("item not found: %d", item.id)
+	}
+	if _, ok := s.headings[item.todoListID]; !ok {
+		return fmt.Errorf("list not found: %d", item.todoListID)
+	}
+	return nil
+}
+
+func (s *MarkdownStore) updateTask(item todoItem) {
+	task := s.tasks[item.id]
+	if item.todoListID != task.item.todoListID {
+		s.removeLine(item.id)
+		task.item = item
+		task.orig = todoItem{}
+		s.insertLine(s.insertPosition(item.todoListID), mdLine{itemID: item.id})
+		return
+	}
+	task.item = item
+}
+
+// DeleteItem removes a checkbox line
+func (s *MarkdownStore) DeleteItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[id]; !ok {
+		return fmt.Errorf("item not found: %d", id)
+	}
+	s.removeLine(id)
+	delete(s.tasks, id)
+	return s.save()
+}
+
+// DeleteItems removes several checkbox lines and writes the file once
+func (s *MarkdownStore) DeleteItems(ctx context.Context, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.tasks[id]; !ok {
+			return fmt.Errorf("item not found: %d", id)
+		}
+	}
+	for _, id := range ids {
+		s.removeLine(id)
+		delete(s.tasks, id)
+	}
+	return s.save()
+}
+
+// RestoreItems re-inserts several deleted checkboxes and writes the file
+// once
+func (s *MarkdownStore) RestoreItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if _, ok := s.headings[item.todoListID]; !ok {
+			return fmt.Errorf("list not found: %d", item.todoListID)
+		}
+	}
+	for _, item := range items {
+		if _, ok := s.tasks[item.id]; ok {
+			continue
+		}
+		item.deleted = false
+		item.deletedAt = 0
+		s.insertTask(item)
+	}
+	return s.save()
+}
+
+func (s *MarkdownStore) removeLine(itemID int) {
+	for i, line := range s.lines {
+		if line.itemID == itemID {
+			s.lines = append(s.lines[:i], s.lines[i+1:]...)
+			return
+		}
+	}
+}
+
+// ReorderTodoLists is not supported; headings stay in file order
+func (s *MarkdownStore) ReorderTodoLists(ctx context.Context, ids []int) error {
+	return ErrNotSupported
+}
+
+// SetTodoListView is not supported; markdown has nowhere to keep it
+func (s *MarkdownStore) SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error {
+	return ErrNotSupported
+}
+
+// GetArchivedTodoLists returns nothing; markdown headings cannot be archived
+func (s *MarkdownStore) GetArchivedTodoLists(ctx context.Context) ([]todoList, error) {
+	return []todoList{}, nil
+}
+
+// GetDeletedTodoLists returns nothing; markdown deletes are permanent
+func (s *MarkdownStore) GetDeletedTodoLists(ctx context.Context) ([]todoList, error) {
+	return []todoList{}, nil
+}
+
+// GetDeletedItems returns nothing; markdown deletes are permanent
+func (s *MarkdownStore) GetDeletedItems(ctx context.Context) ([]todoItem, error) {
+	return []todoItem{}, nil
+}
+
+// PurgeTodoList is not supported; there is no trash to purge from
+func (s *MarkdownStore) PurgeTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// PurgeItem is not supported; there is no trash to purge from
+func (s *MarkdownStore) PurgeItem(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// PurgeDeletedBefore has nothing to do; there is no trash
+func (s *MarkdownStore) PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return 0, nil
+}
+
+// ArchiveCompletedBefore is not supported; completed tasks stay in the file
+func (s *MarkdownStore) ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return 0, ErrNotSupported
+}
+
+// GetHistory is not supported; there is no history
+func (s *MarkdownStore) GetHistory(ctx context.Context, query string) ([]historyItem, error) {
+	return nil, ErrNotSupported
+}
+
+// GetLastSyncTime always returns 0; markdown files are synced by other tools
+func (s *MarkdownStore) GetLastSyncTime(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// SetLastSyncTime is a no-op for markdown
+func (s *MarkdownStore) SetLastSyncTime(ctx context.Context, timestamp int64) error {
+	return nil
+}
+
+// GetPendingChanges returns no changes for markdown
+func (s *MarkdownStore) GetPendingChanges(ctx context.Context) ([]Change, error) {
+	return []Change{}, nil
+}
+
+// MarkChangeSynced is a no-op for markdown
+func (s *MarkdownStore) MarkChangeSynced(ctx context.Context, changeID int) error {
+	return nil
+}
+
+// LogChange is a no-op for markdown
+func (s *MarkdownStore) LogChange(ctx context.Context, entityType string, entityID int, changeType string) error {
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleTodoMarkdown = `# Project
+
+Some intro text that must survive.
+
+## Backend
+
+- [ ] Fix login bug
+- [x] (A) Add rate limiting
+  * [ ] nested step
+
+` + "```" + `
+- [ ] not a task, inside a code fence
+` + "```" + `
+
+## Docs
+Notes about docs.
+`
+
+func writeSampleMarkdown(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "TODO.md")
+	if err := os.WriteFile(path, []byte(sampleTodoMarkdown), 0644); err != nil {
+		t.Fatalf("write TODO.md: %v", err)
+	}
+	return path
+}
+
+func TestMarkdownStore_Parse(t *testing.T) {
+	store, err := NewMarkdownStore(writeSampleMarkdown(t))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	lists, _ := store.GetTodoLists(t.Context())
+	var names []string
+	for _, list := range lists {
+		names = append(names, list.name)
+	}
+	if strings.Join(names, ",") != "Project,Backend,Docs" {
+		t.Errorf("unexpected lists: %v", names)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	if len(items) != 3 {
+		t.Fatalf("expected 3 tasks (code fence ignored), got %d", len(items))
+	}
+	if !items[1].done || items[1].priority != PriorityHigh || items[1].todo != "Add rate limiting" {
+		t.Errorf("unexpected second task: %+v", items[1])
+	}
+	if items[0].todoListID != lists[1].id {
+		t.Errorf("expected task in Backend list")
+	}
+}
+
+func TestMarkdownStore_WriteBackPreservesContent(t *testing.T) {
+	path := writeSampleMarkdown(t)
+	store, err := NewMarkdownStore(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	items[0].done = true
+	if err := store.UpdateItem(t.Context(), items[0]); err != nil {
+		t.Fatalf("update item: %v", err)
+	}
+
+	lists, _ := store.GetTodoLists(t.Context())
+	if _, err := store.SaveItem(t.Context(), todoItem{todo: "Write guide", priority: DefaultPriority, todoListID: lists[2].id}); err != nil {
+		t.Fatalf("save item: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := strings.Replace(sampleTodoMarkdown, "- [ ] Fix login bug", "- [x] Fix login bug", 1)
+	want = strings.Replace(want, "Notes about docs.\n", "Notes about docs.\n- [ ] Write guide\n", 1)
+	if string(got) != want {
+		t.Errorf("unexpected file content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownStore_KeepsLineEndingsAndHeadings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "TODO.md")
+	content := "# C#\r\n- [ ] Port parser\r\n## Docs ##\r\n- [ ] Write guide"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write TODO.md: %v", err)
+	}
+	store, err := NewMarkdownStore(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	lists, _ := store.GetTodoLists(t.Context())
+	if len(lists) != 2 || lists[0].name != "C#" || lists[1].name != "Docs" {
+		t.Fatalf("expected a literal # kept and a closing sequence dropped, got %+v", lists)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	items[0].done = true
+	if err := store.UpdateItem(t.Context(), items[0]); err != nil {
+		t.Fatalf("update item: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := strings.Replace(content, "- [ ] Port parser", "- [x] Port parser", 1)
+	if string(got) != want {
+		t.Errorf("expected CRLF endings and no final newline kept, got %q", got)
+	}
+}
+
+func TestMarkdownStore_RenameAndMove(t *testing.T) {
+	path := writeSampleMarkdown(t)
+	store, err := NewMarkdownStore(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	lists, _ := store.GetTodoLists(t.Context())
+	if err := store.UpdateTodoListName(t.Context(), lists[1].id, "API"); err != nil {
+		t.Fatalf("rename list: %v", err)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	items[0].todoListID = lists[0].id
+	if err := store.UpdateItem(t.Context(), items[0]); err != nil {
+		t.Fatalf("move item: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	content := string(got)
+	if !strings.Contains(content, "## API\n") {
+		t.Errorf("expected renamed heading, got:\n%s", content)
+	}
+	if !strings.Contains(content, "# Project\n\nSome intro text that must survive.\n- [ ] Fix login bug\n") {
+		t.Errorf("expected task moved under Project, got:\n%s", content)
+	}
+}
+
+func TestMarkdownStore_RestoreDeletedTask(t *testing.T) {
+	path := writeSampleMarkdown(t)
+	store, err := NewMarkdownStore(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	deleted := items[0]
+	if err := store.DeleteItem(t.Context(), deleted.id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.RestoreItem(t.Context(), deleted); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	restored, err := store.GetItemByID(t.Context(), deleted.id)
+	if err != nil || restored.todo != deleted.todo {
+		t.Fatalf("expected task restored under its ID, got %+v (%v)", restored, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- [ ] Fix login bug") {
+		t.Errorf("expected restored checkbox in file, got:\n%s", data)
+	}
+}
+
+func TestFindMarkdownFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	if got := findMarkdownFile(nested); got != filepath.Join(nested, defaultMarkdownFileName) {
+		t.Errorf("expected fallback to working directory, got %s", got)
+	}
+
+	want := filepath.Join(root, defaultMarkdownFileName)
+	if err := os.WriteFile(want, []byte("# Todo\n"), 0644); err != nil {
+		t.Fatalf("write TODO.md: %v", err)
+	}
+	if got := findMarkdownFile(nested); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
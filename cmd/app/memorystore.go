@@ -0,0 +1,595 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore implements DataStore entirely in memory. It follows the same
+// rules as LocalStore (soft deletes, archived lists hidden, a change log),
+// which makes it a drop-in store for tests.
+type MemoryStore struct {
+	mu           sync.Mutex
+	items        []todoItem
+	lists        []todoList
+	changes      []Change
+	history      []historyItem
+	lastSyncTime int64
+	nextItemID   int
+	nextListID   int
+	nextChangeID int
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextItemID:   1,
+		nextListID:   1,
+		nextChangeID: 1,
+	}
+}
+
+func (s *MemoryStore) itemIndex(id int) int {
+	for i, item := range s.items {
+		if item.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) listIndex(id int) int {
+	for i, list := range s.lists {
+		if list.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetTodoLists retrieves all non-archived todo lists
+func (s *MemoryStore) GetTodoLists(ctx context.Context) ([]todoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := []todoList{}
+	for _, list := range s.lists {
+		if !list.archived && !list.deleted {
+			lists = append(lists, list)
+		}
+	}
+	return lists, nil
+}
+
+// ReorderTodoLists sets display order to follow the order of ids
+func (s *MemoryStore) ReorderTodoLists(ctx context.Context, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for order, id := range ids {
+		i := s.listIndex(id)
+		if i < 0 {
+			return fmt.Errorf("list not found: %d", id)
+		}
+		s.lists[i].displayOrder = order
+		s.lists[i].updatedAt = now()
+	}
+	sort.SliceStable(s.lists, func(i, j int) bool {
+		return s.lists[i].displayOrder < s.lists[j].displayOrder
+	})
+	return nil
+}
+
+// SetTodoListView remembers how a list's tasks are sorted and grouped
+func (s *MemoryStore) SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+	s.lists[i].sortMode = sortMode
+	s.lists[i].groupMode = groupMode
+	return nil
+}
+
+// GetArchivedTodoLists retrieves archived lists that are not in the trash
+func (s *MemoryStore) GetArchivedTodoLists(ctx context.Context) ([]todoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := []todoList{}
+	for _, list := range s.lists {
+		if list.archived && !list.deleted {
+			lists = append(lists, list)
+		}
+	}
+	return lists, nil
+}
+
+// GetDeletedTodoLists retrieves the lists in the trash
+func (s *MemoryStore) GetDeletedTodoLists(ctx context.Context) ([]todoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := []todoList{}
+	for _, list := range s.lists {
+		if list.deleted {
+			lists = append(lists, list)
+		}
+	}
+	return lists, nil
+}
+
+// CreateTodoList creates a new todo list
+func (s *MemoryStore) CreateTodoList(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := todoList{
+		id:           s.nextListID,
+		clientID:     generateClientID(),
+		name:         name,
+		displayOrder: len(s.lists),
+		createdAt:    now(),
+		updatedAt:    now(),
+		version:      1,
+	}
+	s.nextListID++
+	s.lists = append(s.lists, list)
+	return list.id, nil
+}
+
+// UpdateTodoListName updates the name of a todo list
+func (s *MemoryStore) UpdateTodoListName(ctx context.Context, id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+	s.lists[i].name = name
+	s.lists[i].updatedAt = now()
+	return nil
+}
+
+// DeleteTodoList moves the list and its tasks to the trash
+func (s *MemoryStore) DeleteTodoList(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+
+	timestamp := now()
+	s.lists[i].deleted = true
+	s.lists[i].deletedAt = timestamp
+	s.lists[i].updatedAt = timestamp
+	for j := range s.items {
+		if s.items[j].todoListID == id && !s.items[j].deleted {
+			s.items[j].deleted = true
+			s.items[j].deletedAt = timestamp
+		}
+	}
+	return nil
+}
+
+// ArchiveTodoList archives a todo list
+func (s *MemoryStore) ArchiveTodoList(ctx context.Context, id int) error {
+	return s.setArchived(ctx, id, true)
+}
+
+// UnarchiveTodoList unarchives a todo list
+func (s *MemoryStore) UnarchiveTodoList(ctx context.Context, id int) error {
+	return s.setArchived(ctx, id, false)
+}
+
+// RestoreTodoList takes a list out of the trash
+func (s *MemoryStore) RestoreTodoList(ctx context.Context, list todoList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(list.id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", list.id)
+	}
+	s.lists[i].deleted = false
+	s.lists[i].deletedAt = 0
+	s.lists[i].updatedAt = now()
+	return nil
+}
+
+// PurgeTodoList permanently removes a deleted list and the tasks that went
+// to the trash with it. A list that still has live tasks is kept.
+func (s *MemoryStore) PurgeTodoList(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 || !s.lists[i].deleted {
+		return fmt.Errorf("list not found in trash: %d", id)
+	}
+	live := 0
+	for _, item := range s.items {
+		if !item.deleted && item.todoListID == id {
+			live++
+		}
+	}
+	if live > 0 {
+		return fmt.Errorf("list still has %d tasks outside the trash; restore it to keep them", live)
+	}
+	s.purgeLocked(func(item todoItem) bool { return item.deleted && item.todoListID == id },
+		func(list todoList) bool { return list.id == id })
+	return nil
+}
+
+func (s *MemoryStore) setArchived(ctx context.Context, id int, archived bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+	s.lists[i].archived = archived
+	s.lists[i].updatedAt = now()
+	return nil
+}
+
+// GetItems retrieves all non-deleted items
+func (s *MemoryStore) GetItems(ctx context.Context) ([]todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []todoItem{}
+	for _, item := range s.items {
+		if !item.deleted {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GetItemByID retrieves a single item by ID
+func (s *MemoryStore) GetItemByID(ctx context.Context, id int) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.itemIndex(id); i >= 0 && !s.items[i].deleted {
+		return s.items[i], nil
+	}
+	return todoItem{}, fmt.Errorf("item not found: %d", id)
+}
+
+// GetItemByClientID retrieves an item by its client ID
+func (s *MemoryStore) GetItemByClientID(ctx context.Context, clientID string) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.items {
+		if item.clientID == clientID {
+			return item, nil
+		}
+	}
+	return todoItem{}, fmt.Errorf("item not found with client_id: %s", clientID)
+}
+
+// SaveItem saves a new item and returns its ID
+func (s *MemoryStore) SaveItem(ctx context.Context, item todoItem) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.id = s.nextItemID
+	s.nextItemID++
+	if item.clientID == "" {
+		item.clientID = generateClientID()
+	}
+	if item.dateAdded == 0 {
+		item.dateAdded = now()
+	}
+	if item.position == 0 {
+		item.position = s.nextPosition(item.todoListID)
+	}
+	item.version = 1
+	s.items = append(s.items, item)
+	return item.id, nil
+}
+
+func (s *MemoryStore) nextPosition(listID int) int {
+	position := 0
+	for _, item := range s.items {
+		if item.todoListID == listID && item.position > position {
+			position = item.position
+		}
+	}
+	return position + 1
+}
+
+// UpdateItem updates an existing item
+func (s *MemoryStore) UpdateItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(item.id)
+	if i < 0 {
+		return fmt.Errorf("item not found: %d", item.id)
+	}
+	s.items[i] = item
+	return nil
+}
+
+// DeleteItem marks an item as deleted
+func (s *MemoryStore) DeleteItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(id)
+	if i < 0 {
+		return fmt.Errorf("item not found: %d", id)
+	}
+	s.items[i].deleted = true
+	s.items[i].deletedAt = now()
+	return nil
+}
+
+// RestoreItem clears the deleted flag on an item
+func (s *MemoryStore) RestoreItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(item.id)
+	if i < 0 {
+		return fmt.Errorf("item not found: %d", item.id)
+	}
+	s.items[i].deleted = false
+	s.items[i].deletedAt = 0
+	return nil
+}
+
+// UpdateItems updates several items, changing none if any is missing
+func (s *MemoryStore) UpdateItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkItems(itemIDs(items)); err != nil {
+		return err
+	}
+	for _, item := range items {
+		s.items[s.itemIndex(item.id)] = item
+	}
+	return nil
+}
+
+// DeleteItems marks several items as deleted, changing none if any is
+// missing
+func (s *MemoryStore) DeleteItems(ctx context.Context, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkItems(ids); err != nil {
+		return err
+	}
+	deletedAt := now()
+	for _, id := range ids {
+		i := s.itemIndex(id)
+		s.items[i].deleted = true
+		s.items[i].deletedAt = deletedAt
+	}
+	return nil
+}
+
+// RestoreItems clears the deleted flag on several items, changing none if
+// any is missing
+func (s *MemoryStore) RestoreItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkItems(itemIDs(items)); err != nil {
+		return err
+	}
+	for _, item := range items {
+		i := s.itemIndex(item.id)
+		s.items[i].deleted = false
+		s.items[i].deletedAt = 0
+	}
+	return nil
+}
+
+func (s *MemoryStore) checkItems(ids []int) error {
+	for _, id := range ids {
+		if s.itemIndex(id) < 0 {
+			return fmt.Errorf("item not found: %d", id)
+		}
+	}
+	return nil
+}
+
+// GetDeletedItems retrieves the tasks in the trash
+func (s *MemoryStore) GetDeletedItems(ctx context.Context) ([]todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []todoItem{}
+	for _, item := range s.items {
+		if item.deleted {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// PurgeItem permanently removes a deleted item
+func (s *MemoryStore) PurgeItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(id)
+	if i < 0 || !s.items[i].deleted {
+		return fmt.Errorf("item not found in trash: %d", id)
+	}
+	s.purgeLocked(func(item todoItem) bool { return item.id == id }, nil)
+	return nil
+}
+
+// PurgeDeletedBefore permanently removes tasks and lists deleted before
+// cutoff, along with the tasks of purged lists
+func (s *MemoryStore) PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	liveLists := map[int]bool{}
+	for _, item := range s.items {
+		if !item.deleted {
+			liveLists[item.todoListID] = true
+		}
+	}
+	expiredLists := map[int]bool{}
+	for _, list := range s.lists {
+		if list.deleted && list.deletedAt < cutoff && !liveLists[list.id] {
+			expiredLists[list.id] = true
+		}
+	}
+	return s.purgeLocked(
+		func(item todoItem) bool {
+			return item.deleted && (item.deletedAt < cutoff || expiredLists[item.todoListID])
+		},
+		func(list todoList) bool { return expiredLists[list.id] },
+	), nil
+}
+
+// ArchiveCompletedBefore moves tasks completed before cutoff into the
+// history
+func (s *MemoryStore) ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archivedAt := now()
+	items := s.items[:0]
+	archived := 0
+	for _, item := range s.items {
+		if !item.done || item.deleted || item.dateCompleted == 0 || item.dateCompleted >= cutoff {
+			items = append(items, item)
+			continue
+		}
+		entry := historyItem{todoItem: item, archivedAt: archivedAt}
+		if i := s.listIndex(item.todoListID); i >= 0 {
+			entry.listName = s.lists[i].name
+		}
+		s.history = append(s.history, entry)
+		archived++
+	}
+	s.items = items
+	return archived, nil
+}
+
+// GetHistory returns archived completions whose text contains query, most
+// recently completed first
+func (s *MemoryStore) GetHistory(ctx context.Context, query string) ([]historyItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := []historyItem{}
+	for _, item := range s.history {
+		if strings.Contains(strings.ToLower(item.todo), strings.ToLower(query)) {
+			history = append(history, item)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].dateCompleted > history[j].dateCompleted
+	})
+	return history, nil
+}
+
+// purgeLocked drops the matching tasks and lists and returns how many were
+// removed. The caller must hold s.mu.
+func (s *MemoryStore) purgeLocked(itemMatch func(todoItem) bool, listMatch func(todoList) bool) int {
+	purged := 0
+
+	items := s.items[:0]
+	for _, item := range s.items {
+		if itemMatch(item) {
+			purged++
+			continue
+		}
+		items = append(items, item)
+	}
+	s.items = items
+
+	if listMatch != nil {
+		lists := s.lists[:0]
+		for _, list := range s.lists {
+			if listMatch(list) {
+				purged++
+				continue
+			}
+			lists = append(lists, list)
+		}
+		s.lists = lists
+	}
+	return purged
+}
+
+// GetLastSyncTime retrieves the timestamp of the last successful sync
+func (s *MemoryStore) GetLastSyncTime(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSyncTime, nil
+}
+
+// SetLastSyncTime updates the last sync timestamp
+func (s *MemoryStore) SetLastSyncTime(ctx context.Context, timestamp int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSyncTime = timestamp
+	return nil
+}
+
+// GetPendingChanges retrieves all unsynced changes
+func (s *MemoryStore) GetPendingChanges(ctx context.Context) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := []Change{}
+	for _, change := range s.changes {
+		if !change.synced {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// MarkChangeSynced marks a change as successfully synced
+func (s *MemoryStore) MarkChangeSynced(ctx context.Context, changeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.changes {
+		if s.changes[i].id == changeID {
+			s.changes[i].synced = true
+		}
+	}
+	return nil
+}
+
+// LogChange records a local change for later sync
+func (s *MemoryStore) LogChange(ctx context.Context, entityType string, entityID int, changeType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.changes = append(s.changes, Change{
+		id:         s.nextChangeID,
+		entityType: entityType,
+		entityID:   entityID,
+		changeType: changeType,
+		timestamp:  now(),
+	})
+	s.nextChangeID++
+	return nil
+}
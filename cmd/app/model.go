@@ -1,7 +1,9 @@
 package main
 
 import (
-	"sort"
+	"context"
+	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -17,7 +19,11 @@ type todoList struct {
 	archived     bool
 	createdAt    int64
 	updatedAt    int64
-	version      int // For conflict detection
+	deleted      bool
+	deletedAt    int64
+	sortMode     string // How the list's tasks are ordered, empty for the default
+	groupMode    string // How the list's tasks are grouped, empty for none
+	version      int    // For conflict detection
 }
 
 // Change represents a local change pending sync
@@ -30,6 +36,14 @@ type Change struct {
 	synced     bool
 }
 
+// Tombstone records a permanently purged task or list so the purge can be
+// pushed to other devices
+type Tombstone struct {
+	clientID   string
+	entityType string // "task" or "list"
+	deletedAt  int64
+}
+
 // SyncStatus tracks the synchronization state
 type SyncStatus struct {
 	online        bool
@@ -96,9 +110,18 @@ func (f *TaskCreationFlow) reset() {
 
 // InputContext holds all temporary input/editing state
 type InputContext struct {
-	itemIndex   int // Index of item being edited (-1 = none)
-	deleteIndex int // Index of item pending deletion (-1 = none)
-	listIndex   int // Cursor position in list selector
+	itemIndex     int      // Index of item being edited (-1 = none)
+	deleteIndex   int      // Index of item pending deletion (-1 = none)
+	listIndex     int      // Cursor position in list selector
+	trashIndex    int      // Cursor position in trash view
+	showArchived  bool     // Archived lists are shown in the list selector
+	archivedList  todoList // Archived list open in the read-only view
+	moveItemIDs   []int    // Tasks being moved or copied
+	moveListIndex int      // Cursor position in the move picker
+	profileIndex  int      // Cursor position in the profile picker
+	detailItemID  int      // Task shown in the detail view
+	detailField   int      // Selected field in the detail view
+	detailPending bool     // The detail task has changes waiting to sync
 }
 
 func newInputContext() InputContext {
@@ -116,11 +139,11 @@ type model struct {
 	height              int
 	textInput           textinput.Model
 	viewport            viewport.Model
-	scrollOffset        int
 	todoLists           []todoList
 	currentListID       int
 	currentListIndex    int
 	errorMsg            string
+	statusMsg           string
 	filteredItems       []todoItem
 	filteredListID      int
 	filteredItemIndices []int
@@ -132,6 +155,21 @@ type model struct {
 	syncEnabled         bool
 	syncStatus          SyncStatus
 	store               DataStore // Data access layer
+	history             UndoHistory
+	trash               []trashEntry
+	archivedLists       []todoList
+	marked              map[int]bool // Task IDs marked for bulk actions
+	completedFilter     int          // Which completed tasks are listed, see CompletedShowAll
+	defaultPriority     int          // Priority preselected for new tasks
+	dbLabel             string       // Database shown in the title, empty to hide it
+	cfg                 Config       // Configuration the store was opened with
+	closeStoreFunc      func()       // Closes the store, nil if there is nothing to close
+	keys                KeyMap       // Active key bindings
+	showHelp            bool         // The help overlay is open
+	palette             paletteState // The command palette, shown over the current screen
+	calendar            calendarState
+	scrolled            bool // The wheel has scrolled the task list away from the selection
+	scrollTop           int  // First task list line shown while scrolled
 }
 
 func initialModel(todoItems []todoItem, todoLists []todoList) model {
@@ -154,29 +192,81 @@ func initialModel(todoItems []todoItem, todoLists []todoList) model {
 		items:            todoItems,
 		textInput:        ti,
 		viewport:         vp,
-		scrollOffset:     0,
 		todoLists:        todoLists,
 		currentListID:    currentListID,
 		currentListIndex: currentListIndex,
 		input:            newInputContext(),
+		defaultPriority:  DefaultPriority,
+		keys:             DefaultKeyMap(),
 		taskFlow:         newTaskCreationFlow(),
 		currentState:     StateMainBrowse,
 		currentSubState:  SubStateNone,
 	}
 }
 
-func (m model) Init() tea.Cmd {
-	return nil
+// loadModel builds the initial model from a store, creating the default
+// list on first run
+func loadModel(ctx context.Context, store DataStore) (model, error) {
+	todoLists, err := store.GetTodoLists(ctx)
+	if err != nil {
+		return model{}, fmt.Errorf("load todo lists: %w", err)
+	}
+
+	if len(todoLists) == 0 {
+		id, err := store.CreateTodoList(ctx, DefaultListName)
+		if err != nil {
+			return model{}, fmt.Errorf("create default todo list: %w", err)
+		}
+		todoLists = []todoList{{id: id, name: DefaultListName, archived: false}}
+	}
+
+	todoItems, err := store.GetItems(ctx)
+	if err != nil {
+		return model{}, fmt.Errorf("load items: %w", err)
+	}
+
+	m := initialModel(todoItems, todoLists)
+	m.store = store
+	m.sortItems()
+	return m, nil
 }
 
-func (m *model) sortItems() {
-	sort.Slice(m.items, func(i, j int) bool {
-		if m.items[i].done != m.items[j].done {
-			return !m.items[i].done
+// applyConfig sets the configured priority for new tasks and the database
+// label, and opens the configured startup list
+func (m *model) applyConfig(cfg Config) {
+	m.cfg = cfg
+	if err := useTheme(cfg); err != nil {
+		m.errorMsg = "Failed to load theme: " + err.Error()
+	}
+	keys, err := newKeyMap(cfg.Keymap, cfg.Keybindings)
+	if err != nil {
+		m.errorMsg = "Failed to load keybindings: " + err.Error()
+	}
+	m.keys = keys
+	m.defaultPriority = cfg.DefaultPriority
+	m.dbLabel = dbLabel(cfg)
+	if cfg.Profile != "" {
+		m.dbLabel = strings.TrimSuffix(cfg.Profile+" · "+m.dbLabel, " · ")
+	}
+	if cfg.DefaultList == "" {
+		return
+	}
+	for i, list := range m.todoLists {
+		if strings.EqualFold(list.name, cfg.DefaultList) {
+			m.switchToList(i)
+			return
 		}
-		return m.items[i].priority < m.items[j].priority
-	})
-	m.invalidateCache()
+	}
+	m.errorMsg = fmt.Sprintf("Default list %q not found", cfg.DefaultList)
+}
+
+// storeContext returns a context for a single storage call from the UI
+func (m *model) storeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), StoreTimeout)
+}
+
+func (m model) Init() tea.Cmd {
+	return nil
 }
 
 func (m *model) setState(state AppState, subState SubState) {
@@ -0,0 +1,97 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handleMouse selects tasks and lists with clicks, toggles a task when its
+// checkbox is clicked and scrolls the task list with the wheel
+func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	switch m.currentState {
+	case StateMainBrowse:
+		m.mouseTaskList(msg)
+	case StateListSelector:
+		if m.currentSubState == SubStateNone {
+			m.mouseListSelector(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m *model) mouseTaskList(msg tea.MouseMsg) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scrollTaskList(-TaskLines)
+		return
+	case tea.MouseButtonWheelDown:
+		m.scrollTaskList(TaskLines)
+		return
+	}
+	if msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress {
+		return
+	}
+
+	line, ok := m.taskListLineAt(msg.X, msg.Y)
+	if !ok {
+		return
+	}
+	_, owners, _ := m.taskListLines()
+	if line >= len(owners) || owners[line] < 0 {
+		return
+	}
+	index := owners[line]
+	m.cursor = index
+
+	firstLine := line == 0 || owners[line-1] != index
+	if firstLine && msg.X < len(CheckboxOpen) {
+		m.toggleTaskDone(index)
+	}
+}
+
+// scrollTaskList moves the task list view by a number of lines without
+// moving the selection, until the next key press
+func (m *model) scrollTaskList(lines int) {
+	content, _, cursorEnd := m.taskListLines()
+	m.scrollTop = max(0, min(m.taskListOffset(len(content), cursorEnd)+lines, len(content)-m.viewport.Height))
+	m.scrolled = true
+}
+
+// taskListLineAt returns the task list content line shown at a screen
+// position, if the position is inside the task list
+func (m *model) taskListLineAt(x, y int) (int, bool) {
+	top := lipgloss.Height(TitleStyle.Render(m.viewTitle()))
+	if x < 0 || x >= m.viewport.Width || y < top || y >= top+m.viewport.Height {
+		return 0, false
+	}
+	lines, _, cursorEnd := m.taskListLines()
+	return y - top + m.taskListOffset(len(lines), cursorEnd), true
+}
+
+func (m *model) mouseListSelector(msg tea.MouseMsg) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		if m.input.listIndex > 0 {
+			m.input.listIndex--
+		}
+		return
+	case tea.MouseButtonWheelDown:
+		if m.input.listIndex < m.selectorRowCount()-1 {
+			m.input.listIndex++
+		}
+		return
+	}
+	if msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress {
+		return
+	}
+
+	// The selector sits below the task list and a blank line
+	top := lipgloss.Height(TitleStyle.Render(m.viewTitle())) + m.viewport.Height + 1
+	_, rows := m.listSelectorRows()
+	line := msg.Y - top
+	if line < 0 || line >= len(rows) || rows[line] < 0 {
+		return
+	}
+	m.input.listIndex = rows[line]
+	m.handleListSelection()
+}
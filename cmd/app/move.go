@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startMove opens the list picker for the marked tasks, or the task under
+// the cursor if none are marked. When isCopy is set the tasks are
+// duplicated into the chosen list instead.
+func (m *model) startMove(isCopy bool) {
+	if len(m.marked) > 0 {
+		m.openMovePicker(m.markedIDs(), isCopy)
+		return
+	}
+	actualIndex := m.getVisibleItemActualIndex(m.cursor)
+	if actualIndex < 0 {
+		return
+	}
+	m.openMovePicker([]int{m.items[actualIndex].id}, isCopy)
+}
+
+func (m *model) openMovePicker(itemIDs []int, isCopy bool) {
+	m.input.moveItemIDs = itemIDs
+	m.input.moveListIndex = m.currentListIndex
+	if isCopy {
+		m.setState(StateMoveTask, SubStateCopyTask)
+	} else {
+		m.setState(StateMoveTask, SubStateMoveTask)
+	}
+}
+
+func (m *model) handleMovePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	return m.runKeyAction(msg, m.movePickerActions())
+}
+
+func (m *model) movePickerActions() []keyAction {
+	k := m.keys
+	return []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.input.moveListIndex > 0 {
+				m.input.moveListIndex--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.input.moveListIndex < len(m.todoLists)-1 {
+				m.input.moveListIndex++
+			}
+		})},
+		{k.Confirm, act(func(m *model) {
+			if m.input.moveListIndex < len(m.todoLists) {
+				m.moveItems(m.todoLists[m.input.moveListIndex])
+			}
+			m.clearMarks()
+			m.returnToMain()
+		})},
+		{k.Cancel, act((*model).returnToMain)},
+	}
+}
+
+// moveItems moves the picked tasks into target in one store call, or
+// copies them when the picker was opened for copying
+func (m *model) moveItems(target todoList) {
+	if m.currentSubState == SubStateCopyTask {
+		m.copyItems(target)
+		return
+	}
+
+	var before, after []todoItem
+	for _, id := range m.input.moveItemIDs {
+		index := m.itemIndexByID(id)
+		if index < 0 || m.items[index].todoListID == target.id {
+			continue
+		}
+		before = append(before, m.items[index])
+		position := m.nextPosition(target.id)
+		m.items[index].todoListID = target.id
+		m.items[index].position = position
+		after = append(after, m.items[index])
+	}
+	if len(after) == 0 {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.UpdateItems(ctx, after); err != nil {
+		m.errorMsg = "Failed to move tasks: " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	if len(after) == 1 {
+		m.recordUndo(updateItemCommand("move task", before[0], after[0]))
+	} else {
+		m.recordUndo(updateItemsCommand("move tasks", before, after))
+	}
+	m.statusMsg = fmt.Sprintf("Moved %d task(s) to %s", len(after), target.name)
+	m.sortItems()
+	if count := m.getVisibleItemCount(); m.cursor >= count {
+		m.cursor = max(0, count-1)
+	}
+}
+
+// copyItems adds a copy of each picked task to target, keeping whether and
+// when it was completed
+func (m *model) copyItems(target todoList) {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	var cmds []undoCommand
+	for _, id := range m.input.moveItemIDs {
+		index := m.itemIndexByID(id)
+		if index < 0 {
+			continue
+		}
+
+		newTask := todoItem{
+			todo:          m.items[index].todo,
+			priority:      m.items[index].priority,
+			dateAdded:     now(),
+			dueDate:       m.items[index].dueDate,
+			done:          m.items[index].done,
+			dateCompleted: m.items[index].dateCompleted,
+			todoListID:    target.id,
+			position:      m.nextPosition(target.id),
+		}
+		newID, err := m.store.SaveItem(ctx, newTask)
+		if err != nil {
+			m.errorMsg = "Failed to copy task: " + err.Error()
+			break
+		}
+		newTask.id = newID
+		m.items = append(m.items, newTask)
+		cmd := createItemCommand(newTask)
+		cmd.description = "copy task"
+		cmds = append(cmds, cmd)
+	}
+
+	if len(cmds) == 1 {
+		m.recordUndo(cmds[0])
+	} else if len(cmds) > 1 {
+		m.recordUndo(groupCommand("copy tasks", cmds))
+	}
+	if len(cmds) > 0 {
+		m.statusMsg = fmt.Sprintf("Copied %d task(s) to %s", len(cmds), target.name)
+	}
+	m.sortItems()
+}
+
+func (m *model) itemIndexByID(id int) int {
+	for i, item := range m.items {
+		if item.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectItem moves the cursor to a task if it is shown in the current list
+func (m *model) selectItem(id int) {
+	for i, item := range m.filterItemsByList(m.currentListID) {
+		if item.id == id {
+			m.cursor = i
+		}
+	}
+}
+
+func (m *model) renderMovePicker() string {
+	verb := "Move"
+	if m.currentSubState == SubStateCopyTask {
+		verb = "Copy"
+	}
+	title := verb + " task to:"
+	if count := len(m.input.moveItemIDs); count > 1 {
+		title = fmt.Sprintf("%s %d tasks to:", verb, count)
+	}
+	lines := []string{TitleStyle.Render(title)}
+
+	for i, list := range m.todoLists {
+		name := list.name
+		if list.id == m.currentListID {
+			name += " (current)"
+		}
+		if i == m.input.moveListIndex {
+			lines = append(lines, SelectedStyle.Render("▶ "+name))
+		} else {
+			lines = append(lines, "  "+name)
+		}
+	}
+
+	lines = append(lines, m.keyHint("", m.keys.Up, m.keys.Down, relabel(m.keys.Confirm, strings.ToLower(verb)), m.keys.Cancel))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// listView returns the sort and group modes of a live or archived list
+func (m *model) listView(listID int) (string, string) {
+	for _, list := range m.todoLists {
+		if list.id == listID {
+			return list.sortMode, list.groupMode
+		}
+	}
+	for _, list := range m.archivedLists {
+		if list.id == listID {
+			return list.sortMode, list.groupMode
+		}
+	}
+	return SortModePriority, GroupModeNone
+}
+
+// listSortMode returns the sort mode of a live or archived list
+func (m *model) listSortMode(listID int) string {
+	sortMode, _ := m.listView(listID)
+	return sortMode
+}
+
+// listGroupMode returns the group mode of a live or archived list
+func (m *model) listGroupMode(listID int) string {
+	_, groupMode := m.listView(listID)
+	return groupMode
+}
+
+// sortItems groups tasks by list, then by the list's group mode, and orders
+// each group by the list's sort mode
+func (m *model) sortItems() {
+	lists := map[int]todoList{}
+	for _, list := range m.todoLists {
+		lists[list.id] = list
+	}
+	for _, list := range m.archivedLists {
+		lists[list.id] = list
+	}
+	currentTime := time.Now().Unix()
+
+	sort.SliceStable(m.items, func(i, j int) bool {
+		a, b := m.items[i], m.items[j]
+		if a.todoListID != b.todoListID {
+			return a.todoListID < b.todoListID
+		}
+		list := lists[a.todoListID]
+		if list.groupMode != GroupModeNone {
+			rankA, labelA := groupKey(a, list.groupMode, currentTime)
+			rankB, labelB := groupKey(b, list.groupMode, currentTime)
+			if rankA != rankB {
+				return rankA < rankB
+			}
+			if labelA != labelB {
+				return strings.ToLower(labelA) < strings.ToLower(labelB)
+			}
+		}
+		return lessBySortMode(a, b, list.sortMode)
+	})
+	m.invalidateCache()
+}
+
+// lessBySortMode orders two tasks of the same group. Completed tasks sink
+// to the bottom in every mode except manual.
+func lessBySortMode(a, b todoItem, sortMode string) bool {
+	if sortMode == SortModeManual {
+		if a.position != b.position {
+			return a.position < b.position
+		}
+		return a.id < b.id
+	}
+	if a.done != b.done {
+		return !a.done
+	}
+
+	switch sortMode {
+	case SortModeDueDate:
+		if a.dueDate != b.dueDate {
+			if a.dueDate == 0 || b.dueDate == 0 {
+				return b.dueDate == 0
+			}
+			return a.dueDate < b.dueDate
+		}
+	case SortModeCreated:
+		if a.dateAdded != b.dateAdded {
+			return a.dateAdded > b.dateAdded
+		}
+	case SortModeCompleted:
+		if a.dateCompleted != b.dateCompleted {
+			return a.dateCompleted > b.dateCompleted
+		}
+	case SortModeAlpha:
+		if textA, textB := strings.ToLower(a.todo), strings.ToLower(b.todo); textA != textB {
+			return textA < textB
+		}
+	}
+	return a.priority < b.priority
+}
+
+// groupKey returns the section a task belongs to under groupMode. Sections
+// are ordered by rank, then by label.
+func groupKey(item todoItem, groupMode string, currentTime int64) (int, string) {
+	switch groupMode {
+	case GroupModePriority:
+		return item.priority, PriorityLabels[item.priority]
+	case GroupModeDueDate:
+		return dueBucket(item.dueDate, currentTime)
+	case GroupModeTag:
+		if tag := firstTag(item.todo); tag != "" {
+			return 0, tag
+		}
+		return 1, "No tag"
+	}
+	return 0, ""
+}
+
+// dueBucket sorts a due date into Overdue, Today, This week or Later
+func dueBucket(dueDate int64, currentTime int64) (int, string) {
+	if dueDate == 0 {
+		return 4, "No due date"
+	}
+	endOfToday := setToEndOfDay(time.Unix(currentTime, 0))
+	switch {
+	case dueDate < currentTime:
+		return 0, "Overdue"
+	case dueDate <= endOfToday.Unix():
+		return 1, "Today"
+	case dueDate <= endOfToday.AddDate(0, 0, 7).Unix():
+		return 2, "This week"
+	}
+	return 3, "Later"
+}
+
+// nextPosition returns the position that puts a task at the end of a list
+// in manual order
+func (m *model) nextPosition(listID int) int {
+	position := 0
+	for _, item := range m.items {
+		if item.todoListID == listID && item.position > position {
+			position = item.position
+		}
+	}
+	return position + 1
+}
+
+// cycleSortMode switches the current list to the next sort mode
+func (m *model) cycleSortMode() {
+	if m.currentListIndex >= len(m.todoLists) {
+		return
+	}
+	list := m.todoLists[m.currentListIndex]
+	sortMode := nextMode(SortModes, list.sortMode)
+	if m.setListView(sortMode, list.groupMode) {
+		m.statusMsg = "Sort: " + SortModeLabels[sortMode]
+		if sortMode == SortModeManual {
+			m.statusMsg += " (J/K to move tasks)"
+		}
+	}
+}
+
+// cycleGroupMode switches the current list to the next grouping
+func (m *model) cycleGroupMode() {
+	if m.currentListIndex >= len(m.todoLists) {
+		return
+	}
+	list := m.todoLists[m.currentListIndex]
+	groupMode := nextMode(GroupModes, list.groupMode)
+	if m.setListView(list.sortMode, groupMode) {
+		m.statusMsg = "Group: " + GroupModeLabels[groupMode]
+	}
+}
+
+// setListView saves the current list's sort and group modes and re-sorts
+// its tasks
+func (m *model) setListView(sortMode string, groupMode string) bool {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.SetTodoListView(ctx, m.currentListID, sortMode, groupMode); err != nil {
+		m.errorMsg = "Failed to change list view: " + err.Error()
+		return false
+	}
+	m.todoLists[m.currentListIndex].sortMode = sortMode
+	m.todoLists[m.currentListIndex].groupMode = groupMode
+	m.sortItems()
+	m.cursor = 0
+	return true
+}
+
+func nextMode(modes []string, current string) string {
+	for i, mode := range modes {
+		if mode == current {
+			return modes[(i+1)%len(modes)]
+		}
+	}
+	return modes[0]
+}
+
+// moveTask swaps the task under the cursor with its neighbour in manual
+// order. Every task in the list is renumbered, hidden ones included, so
+// older tasks without a position can be moved too. Tasks stay within their
+// group when the list is grouped.
+func (m *model) moveTask(step int) {
+	visible := m.filterItemsByList(m.currentListID)
+	target := m.cursor + step
+	if target < 0 || target >= len(visible) {
+		return
+	}
+	from, to := m.filteredItemIndices[m.cursor], m.filteredItemIndices[target]
+
+	if groupMode := m.listGroupMode(m.currentListID); groupMode != GroupModeNone {
+		currentTime := time.Now().Unix()
+		_, fromGroup := groupKey(m.items[from], groupMode, currentTime)
+		_, toGroup := groupKey(m.items[to], groupMode, currentTime)
+		if fromGroup != toGroup {
+			m.statusMsg = "Tasks can only be moved within their group"
+			return
+		}
+	}
+
+	var indices []int
+	for i, item := range m.items {
+		switch {
+		case i == from:
+			indices = append(indices, to)
+		case i == to:
+			indices = append(indices, from)
+		case item.todoListID == m.currentListID:
+			indices = append(indices, i)
+		}
+	}
+
+	var before, after []todoItem
+	for position, index := range indices {
+		if m.items[index].position == position+1 {
+			continue
+		}
+		before = append(before, m.items[index])
+		m.items[index].position = position + 1
+		after = append(after, m.items[index])
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.UpdateItems(ctx, after); err != nil {
+		m.errorMsg = "Failed to move task: " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	m.recordUndo(updateItemsCommand("reorder tasks", before, after))
+	m.cursor = target
+	m.sortItems()
+}
+
+// moveList swaps the list under the list selector cursor with its
+// neighbour and saves the new display order
+func (m *model) moveList(step int) {
+	target := m.input.listIndex + step
+	if m.input.listIndex >= len(m.todoLists) || target < 0 || target >= len(m.todoLists) {
+		return
+	}
+
+	before := listIDs(m.todoLists)
+	m.todoLists[m.input.listIndex], m.todoLists[target] = m.todoLists[target], m.todoLists[m.input.listIndex]
+	after := listIDs(m.todoLists)
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.ReorderTodoLists(ctx, after); err != nil {
+		m.errorMsg = "Failed to reorder lists: " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	m.recordUndo(reorderListsCommand(before, after))
+	m.input.listIndex = target
+	for i, list := range m.todoLists {
+		if list.id == m.currentListID {
+			m.currentListIndex = i
+		}
+	}
+}
+
+func listIDs(lists []todoList) []int {
+	ids := make([]int, len(lists))
+	for i, list := range lists {
+		ids[i] = list.id
+	}
+	return ids
+}
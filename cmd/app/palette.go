@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paletteState is the command palette's input and selection
+type paletteState struct {
+	open  bool
+	input textinput.Model
+	index int
+}
+
+// paletteAction is one entry of the command palette
+type paletteAction struct {
+	title string
+	keys  string // Shown beside the title, empty for actions without a key
+	run   func(m *model) (tea.Model, tea.Cmd)
+}
+
+func (m *model) openPalette() {
+	input := textinput.New()
+	input.Placeholder = "Type to search actions..."
+	input.Prompt = "> "
+	input.Width = TextInputWidth
+	input.Focus()
+	m.palette = paletteState{open: true, input: input}
+}
+
+func (m *model) handlePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	matches := m.paletteMatches()
+	switch {
+	case key.Matches(msg, m.keys.Cancel, m.keys.Palette):
+		m.palette.open = false
+	case msg.Type == tea.KeyUp:
+		if m.palette.index > 0 {
+			m.palette.index--
+		}
+	case msg.Type == tea.KeyDown:
+		if m.palette.index < len(matches)-1 {
+			m.palette.index++
+		}
+	case key.Matches(msg, m.keys.Confirm):
+		m.palette.open = false
+		if m.palette.index < len(matches) {
+			return matches[m.palette.index].run(m)
+		}
+	default:
+		var cmd tea.Cmd
+		m.palette.input, cmd = m.palette.input.Update(msg)
+		m.palette.index = 0
+		return m, cmd
+	}
+	return m, nil
+}
+
+// paletteActions lists everything that can be done on the current screen:
+// its key actions, then the lists and profiles to switch to
+func (m *model) paletteActions() []paletteAction {
+	var actions []paletteAction
+	for _, action := range m.screenActions() {
+		b := action.binding
+		if !b.Enabled() || len(b.Keys()) == 0 {
+			continue
+		}
+		actions = append(actions, paletteAction{
+			title: b.Help().Desc,
+			keys:  keyHelp(b.Keys()[:1]),
+			run:   action.run,
+		})
+	}
+
+	if m.currentState != StateMainBrowse {
+		return actions
+	}
+	for i, list := range m.todoLists {
+		if list.id == m.currentListID {
+			continue
+		}
+		index := i
+		actions = append(actions, paletteAction{
+			title: "switch to list " + list.name,
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				m.switchToList(index)
+				return m, nil
+			},
+		})
+	}
+	for _, name := range m.profileNames() {
+		if name == profileName(m.cfg.Profile) || len(m.cfg.Profiles) == 0 {
+			continue
+		}
+		profile := name
+		actions = append(actions, paletteAction{
+			title: "switch to profile " + profile,
+			run: func(m *model) (tea.Model, tea.Cmd) {
+				return m, m.switchProfile(profile)
+			},
+		})
+	}
+	return actions
+}
+
+// priorityName is a priority's label without its colour square
+func priorityName(priority int) string {
+	fields := strings.Fields(PriorityLabels[priority])
+	return strings.ToLower(fields[len(fields)-1])
+}
+
+// paletteMatches returns the actions matching the typed query, best first
+func (m *model) paletteMatches() []paletteAction {
+	query := m.palette.input.Value()
+	type scored struct {
+		action paletteAction
+		score  int
+	}
+	var matches []scored
+	for _, action := range m.paletteActions() {
+		if score, ok := fuzzyScore(query, action.title); ok {
+			matches = append(matches, scored{action, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	actions := make([]paletteAction, len(matches))
+	for i, match := range matches {
+		actions[i] = match.action
+	}
+	return actions
+}
+
+// fuzzyScore reports whether the letters of query appear in order in text,
+// ignoring case and spaces. Letters that follow each other or start a word
+// score higher, and the best scoring place to start the match wins.
+func fuzzyScore(query, text string) (int, bool) {
+	pattern := []rune(strings.ToLower(strings.ReplaceAll(query, " ", "")))
+	runes := []rune(strings.ToLower(text))
+	if len(pattern) == 0 {
+		return 0, true
+	}
+	best, found := 0, false
+	for start, r := range runes {
+		if r != pattern[0] {
+			continue
+		}
+		if score, ok := fuzzyScoreFrom(pattern, runes, start); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyScoreFrom matches pattern against runes greedily from start
+func fuzzyScoreFrom(pattern, runes []rune, start int) (int, bool) {
+	score, p, last := 0, 0, start-2
+	for i := start; i < len(runes) && p < len(pattern); i++ {
+		if runes[i] != pattern[p] {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 2
+		}
+		if i == 0 || !unicode.IsLetter(runes[i-1]) {
+			score += 3
+		}
+		last = i
+		p++
+	}
+	return score, p == len(pattern)
+}
+
+func (m model) renderPalette() string {
+	lines := []string{TitleStyle.Render("Commands"), m.palette.input.View(), ""}
+
+	matches := m.paletteMatches()
+	limit := max(1, m.height-PaletteChromeLines)
+	start := max(0, m.palette.index-limit+1)
+	width := 0
+	for _, action := range matches {
+		width = max(width, lipgloss.Width(action.title))
+	}
+	for i := start; i < len(matches) && i < start+limit; i++ {
+		line := fmt.Sprintf("%-*s  %s", width, matches[i].title, StatusStyle.Render(matches[i].keys))
+		if i == m.palette.index {
+			lines = append(lines, SelectedStyle.Render("▶ "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+	if len(matches) == 0 {
+		lines = append(lines, StatusStyle.Render("  No matching actions"))
+	}
+
+	lines = append(lines, "", m.keyHint("Type to filter, ↑/↓ to choose;", relabel(m.keys.Confirm, "run"), relabel(m.keys.Cancel, "close")))
+	return strings.Join(lines, "\n")
+}
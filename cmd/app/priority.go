@@ -0,0 +1,72 @@
+package main
+
+// startPriorityEdit opens the priority picker for the marked tasks, or the
+// task under the cursor if none are marked
+func (m *model) startPriorityEdit() {
+	m.taskFlow.reset()
+	m.taskFlow.step = TaskFlowSelectPriority
+	if len(m.marked) > 0 {
+		m.taskFlow.priority = m.defaultPriority
+		m.setState(StatePrioritySelection, SubStateBulkPriority)
+		return
+	}
+
+	actualIndex := m.getVisibleItemActualIndex(m.cursor)
+	if actualIndex < 0 {
+		return
+	}
+	m.input.itemIndex = actualIndex
+	m.taskFlow.text = m.items[actualIndex].todo
+	m.taskFlow.priority = m.items[actualIndex].priority
+	m.setState(StatePrioritySelection, SubStateEditPriority)
+}
+
+// bumpPriority raises (step -1) or lowers (step 1) the priority of the
+// marked tasks, or the task under the cursor
+func (m *model) bumpPriority(step int) {
+	if len(m.marked) > 0 {
+		m.bulkUpdate("change priority", func(item *todoItem) {
+			item.priority = clampPriority(item.priority + step)
+		})
+		return
+	}
+
+	actualIndex := m.getVisibleItemActualIndex(m.cursor)
+	if actualIndex < 0 {
+		return
+	}
+	m.setTaskPriority(actualIndex, clampPriority(m.items[actualIndex].priority+step))
+}
+
+// setTaskPriority saves a new priority and keeps the cursor on the task as
+// it moves to its new place in the list
+func (m *model) setTaskPriority(index int, priority int) {
+	if index < 0 || index >= len(m.items) || m.items[index].priority == priority {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	before := m.items[index]
+	m.items[index].priority = priority
+	if err := m.store.UpdateItem(ctx, m.items[index]); err != nil {
+		m.errorMsg = "Failed to update task: " + err.Error()
+		m.items[index] = before
+		return
+	}
+	m.recordUndo(updateItemCommand("change priority", before, m.items[index]))
+	m.statusMsg = "Priority: " + PriorityLabels[priority]
+	m.sortItems()
+	m.selectItem(before.id)
+}
+
+func clampPriority(priority int) int {
+	if priority < PriorityHigh {
+		return PriorityHigh
+	}
+	if priority > PriorityLow {
+		return PriorityLow
+	}
+	return priority
+}
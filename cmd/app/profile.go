@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// useStore hands an opened store and its configuration to the model
+func (m *model) useStore(cfg Config, opened openedStore) {
+	m.closeStoreFunc = opened.close
+	m.syncEnabled = cfg.Sync.Enabled
+	m.applyConfig(cfg)
+	if opened.sync != nil {
+		m.syncStatus.online = opened.sync.client.IsOnline()
+		m.syncStatus.lastSyncTime = 0 // Will be set during first sync
+	}
+	if opened.archived > 0 {
+		m.statusMsg = fmt.Sprintf("Moved %d completed task(s) to the history", opened.archived)
+	}
+}
+
+// closeStore stops sync and closes the database of the store in use
+func (m model) closeStore() {
+	if m.closeStoreFunc != nil {
+		m.closeStoreFunc()
+	}
+}
+
+// profileNames lists the profiles to pick from, the top-level settings first
+func (m *model) profileNames() []string {
+	return append([]string{DefaultProfile}, m.cfg.Profiles...)
+}
+
+func (m *model) openProfilePicker() {
+	m.input.profileIndex = 0
+	for i, name := range m.profileNames() {
+		if name == profileName(m.cfg.Profile) {
+			m.input.profileIndex = i
+		}
+	}
+	m.setState(StateProfilePicker, SubStateNone)
+}
+
+func (m *model) handleProfilePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	return m.runKeyAction(msg, m.profilePickerActions())
+}
+
+func (m *model) profilePickerActions() []keyAction {
+	k := m.keys
+	return []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.input.profileIndex > 0 {
+				m.input.profileIndex--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.input.profileIndex < len(m.profileNames())-1 {
+				m.input.profileIndex++
+			}
+		})},
+		{k.Confirm, func(m *model) (tea.Model, tea.Cmd) {
+			m.returnToMain()
+			if names := m.profileNames(); m.input.profileIndex < len(names) {
+				return m, m.switchProfile(names[m.input.profileIndex])
+			}
+			return m, nil
+		}},
+		{k.Cancel, act((*model).returnToMain)},
+	}
+}
+
+// profileOpenedMsg carries a profile's store and data once openProfile has
+// loaded them
+type profileOpenedMsg struct {
+	name     string
+	cfg      Config
+	opened   openedStore
+	next     model
+	warnings []string
+	err      error
+}
+
+// switchProfile starts opening the named profile's store. Opening can run
+// a full sync, so it happens outside Update and the model is replaced when
+// the profileOpenedMsg arrives.
+func (m *model) switchProfile(name string) tea.Cmd {
+	if name == profileName(m.cfg.Profile) {
+		return nil
+	}
+
+	cfg, err := m.cfg.WithProfile(name)
+	if err != nil {
+		m.errorMsg = "Failed to load profile: " + err.Error()
+		return nil
+	}
+	m.statusMsg = "Opening profile " + name + "..."
+	return func() tea.Msg {
+		return openProfile(name, cfg)
+	}
+}
+
+// openProfile opens a profile's store and loads its data
+func openProfile(name string, cfg Config) profileOpenedMsg {
+	msg := profileOpenedMsg{name: name, cfg: cfg}
+	ctx, cancel := context.WithTimeout(context.Background(), StoreTimeout)
+	defer cancel()
+
+	msg.opened, msg.err = openStore(ctx, &msg.cfg, func(format string, a ...interface{}) {
+		msg.warnings = append(msg.warnings, fmt.Sprintf(format, a...))
+	})
+	if msg.err != nil {
+		return msg
+	}
+	msg.next, msg.err = loadModel(ctx, msg.opened.store)
+	if msg.err != nil {
+		msg.opened.close()
+	}
+	return msg
+}
+
+// useProfile replaces the model's data with a profile opened by
+// switchProfile. The current store stays open if opening failed.
+func (m *model) useProfile(msg profileOpenedMsg) {
+	if msg.err != nil {
+		m.statusMsg = ""
+		m.errorMsg = "Failed to open profile: " + msg.err.Error()
+		return
+	}
+
+	next := msg.next
+	m.closeStore()
+	next.width = m.width
+	next.height = m.height
+	next.viewport.Width = next.taskListWidth(m.width)
+	next.useStore(msg.cfg, msg.opened)
+	next.viewport.Height = next.getViewportHeight(m.height)
+	*m = next
+	m.statusMsg = "Switched to profile " + msg.name
+	if len(msg.warnings) > 0 {
+		m.statusMsg += " (" + strings.Join(msg.warnings, "; ") + ")"
+	}
+}
+
+func (m *model) renderProfilePicker() string {
+	lines := []string{TitleStyle.Render("Switch profile:")}
+	for i, name := range m.profileNames() {
+		if name == profileName(m.cfg.Profile) {
+			name += " (current)"
+		}
+		if i == m.input.profileIndex {
+			lines = append(lines, SelectedStyle.Render("▶ "+name))
+		} else {
+			lines = append(lines, "  "+name)
+		}
+	}
+	lines = append(lines, m.keyHint("", m.keys.Up, m.keys.Down, relabel(m.keys.Confirm, "switch"), m.keys.Cancel))
+	return strings.Join(lines, "\n")
+}
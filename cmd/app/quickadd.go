@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Quick-add tokens, typed anywhere in a new task's text
+const (
+	QuickAddPriorityPrefix = "!"    // !1 to !4
+	QuickAddListPrefix     = "@"    // @list, dashes stand for spaces; kept unless a list matches
+	QuickAddDuePrefix      = "due:" // due:fri, due:3, due:12/25
+	QuickAddTagPrefix      = "#"    // Kept in the text
+)
+
+// QuickAddDateFormat is how the quick-add preview shows the due date
+const QuickAddDateFormat = "Mon Jan 2"
+
+// quickAdd is a task typed on one line, with the fields its tokens set
+type quickAdd struct {
+	text     string // Text without the field tokens; tags stay in it
+	priority int    // 0 when not given
+	list     string // Name of the list an @list token matched, empty when none did
+	dueDate  int64  // 0 when not given
+	tags     []string
+}
+
+// parseQuickAdd splits a line such as "Fix login bug !1 @Work #backend
+// due:fri" into the task text and its fields. Tokens that do not parse as
+// a field are left in the text, as is an @word naming none of lists, but
+// a due date that cannot be read is an error.
+func parseQuickAdd(input string, lists []todoList) (quickAdd, error) {
+	var q quickAdd
+	var words []string
+	for _, word := range strings.Fields(input) {
+		var list todoList
+		var isList bool
+		if len(word) > 1 && strings.HasPrefix(word, QuickAddListPrefix) {
+			list, isList = findList(lists, word[len(QuickAddListPrefix):])
+		}
+		switch {
+		case len(word) == 2 && strings.HasPrefix(word, QuickAddPriorityPrefix) && word[1] >= '1' && word[1] <= '4':
+			q.priority = int(word[1] - '0')
+		case isList:
+			q.list = list.name
+		case len(word) > len(QuickAddDuePrefix) && strings.HasPrefix(strings.ToLower(word), QuickAddDuePrefix):
+			value := word[len(QuickAddDuePrefix):]
+			q.dueDate = parseDueDate(value)
+			if q.dueDate == 0 {
+				return q, fmt.Errorf("cannot read due date %q", value)
+			}
+		default:
+			if len(word) > 1 && strings.HasPrefix(word, QuickAddTagPrefix) {
+				q.tags = append(q.tags, word)
+			}
+			words = append(words, word)
+		}
+	}
+	q.text = strings.Join(words, " ")
+	return q, nil
+}
+
+// hasFields reports whether any field was given, so the priority and due
+// date steps can be skipped
+func (q quickAdd) hasFields() bool {
+	return q.priority != 0 || q.list != "" || q.dueDate != 0
+}
+
+// findList returns the list an @list token names, ignoring case and
+// reading dashes as spaces
+func findList(lists []todoList, name string) (todoList, bool) {
+	for _, list := range lists {
+		if strings.EqualFold(list.name, name) || strings.EqualFold(projectTag(list.name), name) {
+			return list, true
+		}
+	}
+	return todoList{}, false
+}
+
+// quickAddPreview describes the fields typed so far in the new task input
+func (m *model) quickAddPreview() string {
+	q, err := parseQuickAdd(m.textInput.Value(), m.todoLists)
+	if err != nil {
+		return ErrorStyle.Render(err.Error())
+	}
+	if !q.hasFields() && len(q.tags) == 0 {
+		return StatusStyle.Render("Add !1-!4, @list, due:fri or #tags to set fields in one line")
+	}
+
+	var fields []string
+	if q.priority != 0 {
+		fields = append(fields, "Priority: "+PriorityLabels[q.priority])
+	}
+	if q.list != "" {
+		fields = append(fields, "List: "+q.list)
+	}
+	if q.dueDate != 0 {
+		fields = append(fields, "Due: "+time.Unix(q.dueDate, 0).Format(QuickAddDateFormat))
+	}
+	if len(q.tags) > 0 {
+		fields = append(fields, "Tags: "+strings.Join(q.tags, " "))
+	}
+	return StatusStyle.Render(strings.Join(fields, " · "))
+}
+
+// addQuickTask saves a task typed with the quick-add syntax to its list,
+// or to the current list when none was given, and returns the list
+func (m *model) addQuickTask(q quickAdd) (todoList, error) {
+	text, err := validateTaskText(q.text)
+	if err != nil {
+		return todoList{}, err
+	}
+
+	var list todoList
+	if q.list != "" {
+		found, ok := findList(m.todoLists, q.list)
+		if !ok {
+			return todoList{}, fmt.Errorf("no list named %q", q.list)
+		}
+		list = found
+	} else if m.currentListIndex < len(m.todoLists) {
+		list = m.todoLists[m.currentListIndex]
+	}
+
+	priority := q.priority
+	if priority == 0 {
+		priority = m.defaultPriority
+	}
+	err = m.addTask(todoItem{todo: text, priority: priority, dueDate: q.dueDate, todoListID: list.id})
+	return list, err
+}
+
+// addTask saves a new task at the end of its list
+func (m *model) addTask(task todoItem) error {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	task.dateAdded = time.Now().Unix()
+	task.position = m.nextPosition(task.todoListID)
+	id, err := m.store.SaveItem(ctx, task)
+	if err != nil {
+		return err
+	}
+	task.id = id
+	m.recordUndo(createItemCommand(task))
+	m.items = append(m.items, task)
+	m.sortItems()
+	m.cursor = 0
+	return nil
+}
@@ -5,38 +5,76 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mergestat/timediff"
 )
 
 func (m model) View() string {
-	currentListName := m.getCurrentListName()
-	s := []string{TitleStyle.Render("Todo list: " + currentListName)}
+	if m.showHelp {
+		return m.renderHelp()
+	}
+	if m.palette.open {
+		return m.renderPalette()
+	}
+	if m.currentState == StateTrash {
+		return m.renderTrash()
+	}
+	if m.currentState == StateTaskDetail {
+		return m.renderTaskDetail()
+	}
+	if m.currentState == StateCalendar {
+		return m.renderCalendar()
+	}
+
+	s := []string{TitleStyle.Render(m.viewTitle())}
 
 	m.updateViewport()
-	s = append(s, m.viewport.View())
+	if m.currentState == StateMainBrowse && m.showDetailPane() {
+		s = append(s, m.joinDetailPane(m.viewport.View()))
+	} else {
+		s = append(s, m.viewport.View())
+	}
 
 	switch m.currentState {
 	case StateListSelector:
 		s = append(s, "")
 		s = append(s, m.renderListSelector())
+	case StateMoveTask:
+		s = append(s, "")
+		s = append(s, m.renderMovePicker())
+	case StateProfilePicker:
+		s = append(s, "")
+		s = append(s, m.renderProfilePicker())
+	case StateArchivedView:
+		s = append(s, "")
+		s = append(s, m.keyHint("Read-only.", m.keys.Up, m.keys.Down, m.keys.Unarchive, relabel(m.keys.Close, "back")))
 	case StateEditTask:
 		s = append(s, "")
 		s = append(s, TitleStyle.Render("Edit task:"))
 		s = append(s, m.textInput.View())
-		s = append(s, TitleStyle.Render("(Press Enter to save, Esc to cancel)"))
+		s = append(s, m.keyHint("", relabel(m.keys.Confirm, "save"), m.keys.Cancel))
 	case StateDeleteConfirm:
 		s = append(s, "")
-		s = append(s, SelectedStyle.Render("Delete this task? (y/n)"))
+		if m.currentSubState == SubStateBulkDelete {
+			s = append(s, SelectedStyle.Render(fmt.Sprintf("Delete %d marked task(s)? %s", len(m.marked), m.yesNo())))
+		} else {
+			s = append(s, SelectedStyle.Render("Delete this task? "+m.yesNo()))
+		}
 	case StateTaskInput:
 		s = append(s, TitleStyle.Render("New task:"))
 		s = append(s, m.textInput.View())
-		s = append(s, TitleStyle.Render("(Press Enter to continue, Esc to cancel)"))
+		s = append(s, m.quickAddPreview())
+		s = append(s, m.keyHint("", relabel(m.keys.Confirm, "continue"), m.keys.Cancel))
 	case StatePrioritySelection:
-		s = append(s, TitleStyle.Render("Task: "+m.taskFlow.text))
+		if m.currentSubState == SubStateBulkPriority {
+			s = append(s, TitleStyle.Render(fmt.Sprintf("Priority for %d marked task(s)", len(m.marked))))
+		} else {
+			s = append(s, TitleStyle.Render("Task: "+m.taskFlow.text))
+		}
 		s = append(s, "")
 		s = append(s, m.priorityDisplay())
-		s = append(s, TitleStyle.Render("(Use k/↑ and j/↓ to navigate, 1-4 to jump, Enter to save, Esc to go back)"))
+		s = append(s, m.keyHint("", m.keys.Up, m.keys.Down, relabel(m.keys.SetPriority, "jump"), relabel(m.keys.Confirm, "save"), relabel(m.keys.Cancel, "back")))
 	case StateDueDateInput:
 		if m.currentSubState == SubStateEditDueDate {
 			s = append(s, TitleStyle.Render("Edit due date:"))
@@ -44,7 +82,7 @@ func (m model) View() string {
 			s = append(s, TitleStyle.Render("Due date (optional):"))
 		}
 		s = append(s, m.textInput.View())
-		s = append(s, TitleStyle.Render("(Enter days like '3' or date like '12/25/2025', press Enter to skip, Esc to cancel)"))
+		s = append(s, m.keyHint("Enter days like '3', a day like 'fri' or a date like '12/25/2025';", relabel(m.keys.Confirm, "save or skip"), m.keys.Cancel))
 	case StateListNameInput:
 		if m.currentSubState == SubStateListRename {
 			s = append(s, TitleStyle.Render("Rename list:"))
@@ -52,20 +90,31 @@ func (m model) View() string {
 			s = append(s, TitleStyle.Render("New list name:"))
 		}
 		s = append(s, m.textInput.View())
-		s = append(s, TitleStyle.Render("(Press Enter to save, Esc to cancel)"))
+		s = append(s, m.keyHint("", relabel(m.keys.Confirm, "save"), m.keys.Cancel))
 	}
 	if m.errorMsg != "" {
 		s = append(s, ErrorStyle.Render("Error: "+m.errorMsg))
 	}
+	if m.statusMsg != "" {
+		s = append(s, StatusStyle.Render(m.statusMsg))
+	}
 
 	// Add sync status if enabled
 	if m.syncEnabled {
 		s = append(s, m.renderSyncStatus())
 	}
 
-	s = append(s, "Press l for lists, a to add, e to edit, t to set due date, d to delete, q to quit.")
-	if m.syncEnabled {
-		s = append(s, "Press s to sync.")
+	if len(m.marked) > 0 && m.currentState == StateMainBrowse {
+		k := m.keys
+		s = append(s, fmt.Sprintf("%d marked: %s", len(m.marked), newShortHelp().ShortHelpView([]key.Binding{
+			relabel(k.Details, "complete"), k.SetPriority, k.Priority, k.DueDate, k.Delete, k.Move, k.Copy, k.ClearMarks,
+		})))
+	}
+	if m.currentState != StateArchivedView {
+		s = append(s, m.footerHelp())
+		if m.syncEnabled {
+			s = append(s, "Press "+m.keys.Sync.Help().Key+" to sync.")
+		}
 	}
 	s = append(s, "")
 	return strings.Join(s, "\n")
@@ -88,9 +137,41 @@ func (m *model) priorityDisplay() string {
 	return strings.Join(lines, "\n")
 }
 
+// viewTitle is the title line above the task list
+func (m *model) viewTitle() string {
+	title := "Todo list: " + m.getCurrentListName()
+	if m.currentState == StateArchivedView {
+		title = "Archived list: " + m.input.archivedList.name
+	}
+	if view := m.listViewLabel(m.currentListID); view != "" {
+		title += " (" + view + ")"
+	}
+	if m.dbLabel != "" {
+		title += " · " + m.dbLabel
+	}
+	return title
+}
+
 func (m *model) updateViewport() {
+	lines, _, cursorEnd := m.taskListLines()
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	m.viewport.YOffset = m.taskListOffset(len(lines), cursorEnd)
+}
+
+// taskListOffset is the first task list line shown, following the
+// selection unless the wheel has scrolled the list
+func (m *model) taskListOffset(lineCount, cursorEnd int) int {
+	if m.scrolled {
+		return max(0, min(m.scrollTop, lineCount-m.viewport.Height))
+	}
+	return max(0, cursorEnd-m.viewport.Height)
+}
+
+// taskListLines lays out the visible tasks line by line so group headers
+// can sit between them. owners holds the visible index of the task on each
+// line, -1 for headers, and cursorEnd is the line after the selected task.
+func (m *model) taskListLines() (lines []string, owners []int, cursorEnd int) {
 	visibleItems := m.filterItemsByList(m.currentListID)
-	viewportHeight := m.viewport.Height
 
 	if m.cursor >= len(visibleItems) {
 		m.cursor = len(visibleItems) - 1
@@ -99,28 +180,41 @@ func (m *model) updateViewport() {
 		m.cursor = 0
 	}
 
-	if m.cursor < m.scrollOffset {
-		m.scrollOffset = m.cursor
-	}
-	tasksPerView := viewportHeight / LinesPerTask
-	if m.cursor >= m.scrollOffset+tasksPerView {
-		m.scrollOffset = m.cursor - tasksPerView + 1
-	}
-
 	currentTime := time.Now().Unix()
+	groupMode := m.listGroupMode(m.currentListID)
 
-	var taskLines []string
+	var group string
 	for i, item := range visibleItems {
+		if groupMode != GroupModeNone {
+			if _, label := groupKey(item, groupMode, currentTime); i == 0 || label != group {
+				group = label
+				lines = append(lines, GroupHeaderStyle.Render(label))
+				owners = append(owners, -1)
+			}
+		}
+
 		dateStr := m.formatTaskTimestamps(item)
 		dueStr := m.formatDueDate(item)
-		c := fmt.Sprintf("%s\n%s%s\n", item.todo, dateStr, dueStr)
+		text := item.todo
+		if m.isMarked(item) {
+			text = MarkPrefix + text
+		}
+		checkbox := CheckboxOpen
+		if item.done {
+			checkbox = CheckboxDone
+		}
+		indent := strings.Repeat(" ", len(CheckboxOpen))
+		c := fmt.Sprintf("%s%s\n%s%s%s\n", checkbox, text, indent, dateStr, dueStr)
 		style := m.getStyle(i, item, currentTime)
-		c = style.Render(c)
-		taskLines = append(taskLines, c)
+		for _, line := range strings.Split(style.Render(c), "\n") {
+			lines = append(lines, line)
+			owners = append(owners, i)
+		}
+		if i == m.cursor {
+			cursorEnd = len(lines)
+		}
 	}
-
-	m.viewport.SetContent(strings.Join(taskLines, "\n"))
-	m.viewport.YOffset = m.scrollOffset * LinesPerTask
+	return lines, owners, cursorEnd
 }
 
 func (m *model) formatTaskTimestamps(item todoItem) string {
@@ -195,6 +289,18 @@ func (m model) getViewportHeight(totalHeight int) int {
 
 	if m.currentState == StateListSelector {
 		additionalHeight = len(m.todoLists) + 5 // 1 line per list + 5 for header/spacing/help
+		if m.input.showArchived {
+			additionalHeight += len(m.archivedLists) + 2 // Archived header
+		}
+	}
+	if m.currentState == StateMainBrowse && len(m.marked) > 0 {
+		additionalHeight++ // Bulk action hint
+	}
+	if m.currentState == StateMoveTask {
+		additionalHeight = len(m.todoLists) + 5 // 1 line per list + 5 for header/spacing/help
+	}
+	if m.currentState == StateProfilePicker {
+		additionalHeight = len(m.profileNames()) + 5 // 1 line per profile + 5 for header/spacing/help
 	}
 
 	return availableHeight - additionalHeight
@@ -207,6 +313,23 @@ func (m *model) getCurrentListName() string {
 	return "Unknown"
 }
 
+// listViewLabel describes a list's sort and group modes and the completed
+// filter for the title, or returns an empty string for the defaults
+func (m *model) listViewLabel(listID int) string {
+	sortMode, groupMode := m.listView(listID)
+	var parts []string
+	if m.completedFilter != CompletedShowAll {
+		parts = append(parts, CompletedFilterLabels[m.completedFilter])
+	}
+	if sortMode != SortModePriority {
+		parts = append(parts, "sort: "+SortModeLabels[sortMode])
+	}
+	if groupMode != GroupModeNone {
+		parts = append(parts, "group: "+GroupModeLabels[groupMode])
+	}
+	return strings.Join(parts, ", ")
+}
+
 func (m *model) renderListSelector() string {
 	var lines []string
 
@@ -216,7 +339,7 @@ func (m *model) renderListSelector() string {
 			taskCount := m.countTasksInList(selectedList.id)
 			lines = append(lines, TitleStyle.Render("Delete List"))
 			lines = append(lines, fmt.Sprintf("Delete '%s'? This will delete %d task(s).", selectedList.name, taskCount))
-			lines = append(lines, SelectedStyle.Render("Confirm: (y/n)"))
+			lines = append(lines, SelectedStyle.Render("Confirm: "+m.yesNo()))
 			return strings.Join(lines, "\n")
 		}
 	}
@@ -227,44 +350,76 @@ func (m *model) renderListSelector() string {
 			lines = append(lines, TitleStyle.Render("Manage List"))
 			lines = append(lines, fmt.Sprintf("List: %s", selectedList.name))
 			lines = append(lines, "")
-			lines = append(lines, "r: Rename")
-			lines = append(lines, "d: Delete")
+			lines = append(lines, m.keys.RenameList.Help().Key+": Rename")
+			lines = append(lines, m.keys.DeleteList.Help().Key+": Delete")
 			if selectedList.archived {
-				lines = append(lines, "a: Unarchive")
+				lines = append(lines, m.keys.ArchiveList.Help().Key+": Unarchive")
 			} else {
-				lines = append(lines, "a: Archive")
+				lines = append(lines, m.keys.ArchiveList.Help().Key+": Archive")
 			}
-			lines = append(lines, TitleStyle.Render("(Press key or Esc to go back)"))
+			lines = append(lines, m.keyHint("Press a key or", relabel(m.keys.Cancel, "to go back")))
 			return strings.Join(lines, "\n")
 		}
 	}
 
-	lines = append(lines, TitleStyle.Render("Select list:"))
+	lines, _ = m.listSelectorRows()
 
+	k := m.keys
+	showArchived := k.ShowArchived
+	if m.input.showArchived {
+		showArchived = relabel(k.ShowArchived, "hide archived")
+	}
+	var hint string
+	if m.input.listIndex < len(m.todoLists) {
+		hint = m.keyHint("", k.Up, k.Down, relabel(k.Confirm, "select"), k.ManageList, relabel(k.MoveDown, "move down"), relabel(k.MoveUp, "move up"), showArchived, k.Cancel)
+	} else if m.selectedArchivedList() != nil {
+		hint = m.keyHint("", k.Up, k.Down, relabel(k.Confirm, "view"), k.Unarchive, showArchived, k.Cancel)
+	} else {
+		hint = m.keyHint("", k.Up, k.Down, relabel(k.Confirm, "select"), showArchived, k.Cancel)
+	}
+	lines = append(lines, hint)
+
+	return strings.Join(lines, "\n")
+}
+
+// listSelectorRows lays out the list selector's lists. rows holds the
+// selector index of the list on each line, -1 for other lines.
+func (m *model) listSelectorRows() (lines []string, rows []int) {
+	add := func(text string, row int) {
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, line)
+			rows = append(rows, row)
+		}
+	}
+
+	add(TitleStyle.Render("Select list:"), -1)
 	for i, list := range m.todoLists {
 		if i == m.input.listIndex {
-			lines = append(lines, SelectedStyle.Render("▶ "+list.name))
+			add(SelectedStyle.Render("▶ "+list.name), i)
 		} else {
-			lines = append(lines, "  "+list.name)
+			add("  "+list.name, i)
 		}
 	}
 
-	lines = append(lines, "")
-	if m.input.listIndex == len(m.todoLists) {
-		lines = append(lines, SelectedStyle.Render("▶ Create New List (n)"))
-	} else {
-		lines = append(lines, "  Create New List (n)")
+	if m.input.showArchived {
+		add(TitleStyle.Render("Archived:"), -1)
+		for i, list := range m.archivedLists {
+			row := len(m.todoLists) + i
+			if row == m.input.listIndex {
+				add(SelectedStyle.Render("▶ "+list.name), row)
+			} else {
+				add(ArchivedStyle.Render("  "+list.name), row)
+			}
+		}
 	}
 
-	var hint string
-	if m.input.listIndex < len(m.todoLists) {
-		hint = "(Use k/↑ and j/↓ to navigate, Enter to select, m for manage, Esc to cancel)"
+	add("", -1)
+	if m.input.listIndex == m.selectorRowCount() {
+		add(SelectedStyle.Render("▶ Create New List (n)"), m.selectorRowCount())
 	} else {
-		hint = "(Use k/↑ and j/↓ to navigate, Enter to select, Esc to cancel)"
+		add("  Create New List (n)", m.selectorRowCount())
 	}
-	lines = append(lines, TitleStyle.Render(hint))
-
-	return strings.Join(lines, "\n")
+	return lines, rows
 }
 
 func (m *model) filterItemsByList(listID int) []todoItem {
@@ -273,8 +428,9 @@ func (m *model) filterItemsByList(listID int) []todoItem {
 	}
 	var filtered []todoItem
 	var indices []int
+	startOfToday := setToEndOfDay(time.Now()).AddDate(0, 0, -1).Unix() + 1
 	for i, item := range m.items {
-		if item.todoListID == listID {
+		if item.todoListID == listID && m.passesCompletedFilter(item, startOfToday) {
 			filtered = append(filtered, item)
 			indices = append(indices, i)
 		}
@@ -286,6 +442,25 @@ func (m *model) filterItemsByList(listID int) []todoItem {
 	return filtered
 }
 
+func (m *model) passesCompletedFilter(item todoItem, startOfToday int64) bool {
+	switch m.completedFilter {
+	case CompletedHide:
+		return !item.done
+	case CompletedToday:
+		return item.done && item.dateCompleted >= startOfToday
+	}
+	return true
+}
+
+// cycleCompletedFilter switches between showing all tasks, hiding completed
+// ones and showing only those completed today
+func (m *model) cycleCompletedFilter() {
+	m.completedFilter = (m.completedFilter + 1) % len(CompletedFilterLabels)
+	m.cursor = 0
+	m.invalidateCache()
+	m.statusMsg = "Showing " + CompletedFilterLabels[m.completedFilter]
+}
+
 func (m *model) invalidateCache() {
 	m.cacheValid = false
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// toggleMark marks or unmarks the task under the cursor for bulk actions
+func (m *model) toggleMark() {
+	actualIndex := m.getVisibleItemActualIndex(m.cursor)
+	if actualIndex < 0 {
+		return
+	}
+	id := m.items[actualIndex].id
+	if m.marked[id] {
+		delete(m.marked, id)
+	} else {
+		if m.marked == nil {
+			m.marked = map[int]bool{}
+		}
+		m.marked[id] = true
+	}
+	m.viewport.Height = m.getViewportHeight(m.height)
+}
+
+// extendMark marks the task under the cursor and the one it moves to
+func (m *model) extendMark(step int) {
+	target := m.cursor + step
+	if target < 0 || target >= m.getVisibleItemCount() {
+		return
+	}
+	if m.marked == nil {
+		m.marked = map[int]bool{}
+	}
+	m.marked[m.items[m.getVisibleItemActualIndex(m.cursor)].id] = true
+	m.cursor = target
+	m.marked[m.items[m.getVisibleItemActualIndex(m.cursor)].id] = true
+	m.viewport.Height = m.getViewportHeight(m.height)
+}
+
+func (m *model) clearMarks() {
+	m.marked = nil
+	m.viewport.Height = m.getViewportHeight(m.height)
+}
+
+func (m *model) isMarked(item todoItem) bool {
+	return m.marked[item.id]
+}
+
+// markedIDs returns the marked tasks in display order
+func (m *model) markedIDs() []int {
+	var ids []int
+	for _, item := range m.filterItemsByList(m.currentListID) {
+		if m.marked[item.id] {
+			ids = append(ids, item.id)
+		}
+	}
+	return ids
+}
+
+// bulkUpdate applies change to every marked task and saves them in one
+// store call with one undo entry
+func (m *model) bulkUpdate(description string, change func(item *todoItem)) {
+	var before, after []todoItem
+	for i := range m.items {
+		if !m.marked[m.items[i].id] {
+			continue
+		}
+		before = append(before, m.items[i])
+		change(&m.items[i])
+		after = append(after, m.items[i])
+	}
+	if len(after) == 0 {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.UpdateItems(ctx, after); err != nil {
+		m.errorMsg = "Failed to update tasks: " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	m.recordUndo(updateItemsCommand(description, before, after))
+	m.statusMsg = fmt.Sprintf("Updated %d task(s)", len(after))
+	m.invalidateCache()
+	m.sortItems()
+}
+
+// bulkToggleDone completes every marked task, or reopens them all if they
+// are already complete
+func (m *model) bulkToggleDone() {
+	done := false
+	for _, item := range m.items {
+		if m.marked[item.id] && !item.done {
+			done = true
+			break
+		}
+	}
+
+	description := "reopen tasks"
+	if done {
+		description = "complete tasks"
+	}
+	completedAt := time.Now().Unix()
+	m.bulkUpdate(description, func(item *todoItem) {
+		if item.done == done {
+			return
+		}
+		item.done = done
+		if done {
+			item.dateCompleted = completedAt
+		} else {
+			item.dateCompleted = 0
+		}
+	})
+}
+
+func (m *model) bulkSetPriority(priority int) {
+	m.bulkUpdate("change priority", func(item *todoItem) {
+		item.priority = priority
+	})
+}
+
+func (m *model) bulkSetDueDate(dueDate int64) {
+	m.bulkUpdate("change due date", func(item *todoItem) {
+		item.dueDate = dueDate
+	})
+}
+
+// bulkDelete moves every marked task to the trash
+func (m *model) bulkDelete() {
+	var deleted, remaining []todoItem
+	for _, item := range m.items {
+		if m.marked[item.id] {
+			deleted = append(deleted, item)
+		} else {
+			remaining = append(remaining, item)
+		}
+	}
+	if len(deleted) == 0 {
+		return
+	}
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.store.DeleteItems(ctx, itemIDs(deleted)); err != nil {
+		m.errorMsg = "Failed to delete tasks: " + err.Error()
+		return
+	}
+	m.recordUndo(deleteItemsCommand(deleted))
+	m.items = remaining
+	m.clearMarks()
+	m.statusMsg = fmt.Sprintf("Deleted %d task(s)", len(deleted))
+	m.invalidateCache()
+	m.sortItems()
+}
@@ -20,7 +20,7 @@ const (
 	StatusError
 )
 
-// SyncClient handles HTTP communication with the sync server
+// SyncClient is the SyncTransport that talks to an HTTP sync server
 type SyncClient struct {
 	baseURL      string
 	httpClient   *http.Client
@@ -31,6 +31,7 @@ type SyncClient struct {
 	lastError    error
 	mu           sync.RWMutex
 	checkTimeout time.Duration
+	cipher       *SyncCipher // nil when end-to-end encryption is disabled
 }
 
 // TaskPayload represents a task for sync
@@ -45,18 +46,28 @@ type TaskPayload struct {
 	Deleted       bool   `json:"deleted"`
 	DeletedAt     int64  `json:"deleted_at"`
 	TodoListID    int    `json:"todo_list_id"`
+	Position      int    `json:"position,omitempty"`
 	UpdatedAt     int64  `json:"updated_at"`
 	Version       int    `json:"version"`
+	KeyID         string `json:"key_id,omitempty"`
+	KeySalt       string `json:"key_salt,omitempty"`
+	Ciphertext    string `json:"ciphertext,omitempty"`
 }
 
 // ListPayload represents a todo list for sync
 type ListPayload struct {
-	ClientID    string `json:"client_id"`
-	Name        string `json:"name"`
+	ClientID     string `json:"client_id"`
+	Name         string `json:"name"`
 	DisplayOrder int    `json:"display_order"`
-	Archived    bool   `json:"archived"`
-	UpdatedAt   int64  `json:"updated_at"`
-	Version     int    `json:"version"`
+	SortMode     string `json:"sort_mode,omitempty"`
+	Archived     bool   `json:"archived"`
+	Deleted      bool   `json:"deleted,omitempty"`
+	DeletedAt    int64  `json:"deleted_at,omitempty"`
+	UpdatedAt    int64  `json:"updated_at"`
+	Version      int    `json:"version"`
+	KeyID        string `json:"key_id,omitempty"`
+	KeySalt      string `json:"key_salt,omitempty"`
+	Ciphertext   string `json:"ciphertext,omitempty"`
 }
 
 // PullRequest is the request for pulling changes
@@ -66,8 +77,9 @@ type PullRequest struct {
 
 // PullResponse contains the changes from the server
 type PullResponse struct {
-	Tasks []TaskPayload `json:"tasks"`
-	Lists []ListPayload `json:"lists"`
+	Tasks   []TaskPayload `json:"tasks"`
+	Lists   []ListPayload `json:"lists"`
+	Skipped int           `json:"-"` // Unreadable records the transport passed over
 }
 
 // PushRequest contains changes to push to server
@@ -81,8 +93,9 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
-// NewSyncClient creates a new sync client
-func NewSyncClient(config SyncConfig) *SyncClient {
+// NewSyncClient creates a new sync client. When cipher is non-nil all
+// payloads are encrypted before they leave the device.
+func NewSyncClient(config SyncConfig, cipher *SyncCipher) *SyncClient {
 	return &SyncClient{
 		baseURL:      config.ServerURL,
 		apiKey:       config.APIKey,
@@ -90,6 +103,7 @@ func NewSyncClient(config SyncConfig) *SyncClient {
 		status:       StatusOffline,
 		lastCheck:    time.Time{},
 		checkTimeout: 5 * time.Second,
+		cipher:       cipher,
 		httpClient: &http.Client{
 			Timeout: time.Duration(config.TimeoutSeconds) * time.Second,
 		},
@@ -172,52 +186,34 @@ func (c *SyncClient) PullChanges(since int64) (*PullResponse, error) {
 		return nil, err
 	}
 
+	if err := openPullResponse(&pullResp, c.cipher); err != nil {
+		return nil, err
+	}
+
 	return &pullResp, nil
 }
 
+// CommitPull does nothing for the server, which is always asked for the
+// changes since the last sync time. FullSync only moves that time forward
+// after the pulled changes were applied.
+func (c *SyncClient) CommitPull() error {
+	return nil
+}
+
 // PushChanges sends local changes to the server
 func (c *SyncClient) PushChanges(items []todoItem, lists []todoList) error {
 	if !c.IsOnline() {
 		return fmt.Errorf("not connected to sync server")
 	}
 
-	// Convert items to payloads
-	taskPayloads := make([]TaskPayload, len(items))
-	for i, item := range items {
-		taskPayloads[i] = TaskPayload{
-			ClientID:      item.clientID,
-			Todo:          item.todo,
-			Priority:      item.priority,
-			Done:          item.done,
-			DateAdded:     item.dateAdded,
-			DateCompleted: item.dateCompleted,
-			DueDate:       item.dueDate,
-			Deleted:       item.deleted,
-			DeletedAt:     item.deletedAt,
-			TodoListID:    item.todoListID,
-			UpdatedAt:     item.dateAdded, // TODO: Use actual updated timestamp
-			Version:       item.version,
-		}
-	}
+	pushReq := newPushRequest(items, lists)
 
-	// Convert lists to payloads
-	listPayloads := make([]ListPayload, len(lists))
-	for i, list := range lists {
-		listPayloads[i] = ListPayload{
-			ClientID:    list.clientID,
-			Name:        list.name,
-			DisplayOrder: list.displayOrder,
-			Archived:    list.archived,
-			UpdatedAt:   list.updatedAt,
-			Version:     list.version,
+	if c.cipher != nil {
+		if err := c.cipher.SealPush(&pushReq); err != nil {
+			return err
 		}
 	}
 
-	pushReq := PushRequest{
-		Tasks: taskPayloads,
-		Lists: listPayloads,
-	}
-
 	body, err := json.Marshal(pushReq)
 	if err != nil {
 		return err
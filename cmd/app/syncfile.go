@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File sync naming. Each device owns <device>.jsonl and <device>.cursor in
+// the shared directory and never writes to another device's files.
+const (
+	fileChangeExt = ".jsonl"
+	fileCursorExt = ".cursor"
+)
+
+// FileTransport is a SyncTransport that syncs through a shared directory
+// (Syncthing, Dropbox, NFS or a git checkout) instead of a server. Every
+// device appends its changes to its own change file and merges the files
+// written by the other devices.
+type FileTransport struct {
+	dir      string
+	deviceID string
+	cipher   *SyncCipher
+	mu       sync.Mutex
+	written  map[string]string // Last payload this device wrote, keyed by entity
+	pulled   map[string]int64  // Cursors reached by the last pull, saved by CommitPull
+}
+
+// fileChangeRecord is one line of a device's append-only change file
+type fileChangeRecord struct {
+	Timestamp int64         `json:"timestamp"`
+	DeviceID  string        `json:"device_id"`
+	Tasks     []TaskPayload `json:"tasks,omitempty"`
+	Lists     []ListPayload `json:"lists,omitempty"`
+}
+
+// NewFileTransport creates a file transport rooted at dir
+func NewFileTransport(dir string, deviceID string, cipher *SyncCipher) (*FileTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%s must be set when using the file sync transport", syncDirEnvVar)
+	}
+	if deviceID == "" {
+		return nil, fmt.Errorf("file sync requires a device ID")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileTransport{
+		dir:      dir,
+		deviceID: deviceID,
+		cipher:   cipher,
+	}, nil
+}
+
+// IsOnline returns true while the shared directory is reachable
+func (t *FileTransport) IsOnline() bool {
+	info, err := os.Stat(t.dir)
+	return err == nil && info.IsDir()
+}
+
+// PullChanges reads every other device's change file from where this device
+// last stopped. Files can arrive late through the shared folder, so progress
+// is tracked per device instead of by timestamp; since = 0 starts over. The
+// new position is only saved by CommitPull.
+func (t *FileTransport) PullChanges(since int64) (*PullResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.IsOnline() {
+		return nil, fmt.Errorf("sync directory %s is not available", t.dir)
+	}
+
+	cursors := map[string]int64{}
+	if since > 0 {
+		loaded, err := t.loadCursors()
+		if err != nil {
+			return nil, err
+		}
+		cursors = loaded
+	}
+
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := map[string]TaskPayload{}
+	lists := map[string]ListPayload{}
+	var taskOrder, listOrder []string
+	skipped := 0
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, fileChangeExt) {
+			continue
+		}
+		device := strings.TrimSuffix(name, fileChangeExt)
+		if device == t.deviceID {
+			continue
+		}
+
+		records, offset, bad, err := readChangeRecords(filepath.Join(t.dir, name), cursors[device])
+		if err != nil {
+			return nil, fmt.Errorf("read changes from %s: %w", device, err)
+		}
+		skipped += bad
+
+		for _, record := range records {
+			resp := PullResponse{Tasks: record.Tasks, Lists: record.Lists}
+			if err := openPullResponse(&resp, t.cipher); err != nil {
+				return nil, err
+			}
+
+			// The write time stands in for the server's receive time
+			for _, task := range resp.Tasks {
+				if task.UpdatedAt < record.Timestamp {
+					task.UpdatedAt = record.Timestamp
+				}
+				existing, seen := tasks[task.ClientID]
+				if !seen {
+					taskOrder = append(taskOrder, task.ClientID)
+				}
+				if !seen || task.UpdatedAt >= existing.UpdatedAt {
+					tasks[task.ClientID] = task
+				}
+			}
+			for _, list := range resp.Lists {
+				if list.UpdatedAt < record.Timestamp {
+					list.UpdatedAt = record.Timestamp
+				}
+				existing, seen := lists[list.ClientID]
+				if !seen {
+					listOrder = append(listOrder, list.ClientID)
+				}
+				if !seen || list.UpdatedAt >= existing.UpdatedAt {
+					lists[list.ClientID] = list
+				}
+			}
+		}
+		cursors[device] = offset
+	}
+	t.pulled = cursors
+
+	resp := &PullResponse{Skipped: skipped}
+	for _, id := range taskOrder {
+		resp.Tasks = append(resp.Tasks, tasks[id])
+	}
+	for _, id := range listOrder {
+		resp.Lists = append(resp.Lists, lists[id])
+	}
+	return resp, nil
+}
+
+// CommitPull saves how far the last pull read, once its changes are applied
+func (t *FileTransport) CommitPull() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pulled == nil {
+		return nil
+	}
+	if err := t.saveCursors(t.pulled); err != nil {
+		return err
+	}
+	t.pulled = nil
+	return nil
+}
+
+// PushChanges appends the tasks and lists that changed since this device's
+// last write. Nothing is written when nothing changed.
+func (t *FileTransport) PushChanges(items []todoItem, lists []todoList) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.IsOnline() {
+		return fmt.Errorf("sync directory %s is not available", t.dir)
+	}
+
+	if t.written == nil {
+		if err := t.loadWritten(); err != nil {
+			return err
+		}
+	}
+
+	pushReq := newPushRequest(items, lists)
+	changed := PushRequest{}
+	pending := map[string]string{}
+	keyID := ""
+	if t.cipher != nil {
+		keyID = t.cipher.CurrentKeyID()
+	}
+
+	for _, task := range pushReq.Tasks {
+		key, encoded, err := writtenKey("task", task.ClientID, keyID, task)
+		if err != nil {
+			return err
+		}
+		if t.written[key] != encoded {
+			changed.Tasks = append(changed.Tasks, task)
+			pending[key] = encoded
+		}
+	}
+	for _, list := range pushReq.Lists {
+		key, encoded, err := writtenKey("list", list.ClientID, keyID, list)
+		if err != nil {
+			return err
+		}
+		if t.written[key] != encoded {
+			changed.Lists = append(changed.Lists, list)
+			pending[key] = encoded
+		}
+	}
+
+	if len(changed.Tasks) == 0 && len(changed.Lists) == 0 {
+		return nil
+	}
+
+	if t.cipher != nil {
+		if err := t.cipher.SealPush(&changed); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal(fileChangeRecord{
+		Timestamp: time.Now().Unix(),
+		DeviceID:  t.deviceID,
+		Tasks:     changed.Tasks,
+		Lists:     changed.Lists,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(t.changeFile(t.deviceID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	for key, encoded := range pending {
+		t.written[key] = encoded
+	}
+	return nil
+}
+
+// loadWritten replays this device's own change file so a restart does not
+// rewrite everything it already published
+func (t *FileTransport) loadWritten() error {
+	t.written = map[string]string{}
+
+	records, _, _, err := readChangeRecords(t.changeFile(t.deviceID), 0)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		// Opening clears the key IDs, so note them first
+		taskKeys := make([]string, len(record.Tasks))
+		for i, task := range record.Tasks {
+			taskKeys[i] = task.KeyID
+		}
+		listKeys := make([]string, len(record.Lists))
+		for i, list := range record.Lists {
+			listKeys[i] = list.KeyID
+		}
+
+		resp := PullResponse{Tasks: record.Tasks, Lists: record.Lists}
+		if err := openPullResponse(&resp, t.cipher); err != nil {
+			return err
+		}
+		for i, task := range resp.Tasks {
+			key, encoded, err := writtenKey("task", task.ClientID, taskKeys[i], task)
+			if err != nil {
+				return err
+			}
+			t.written[key] = encoded
+		}
+		for i, list := range resp.Lists {
+			key, encoded, err := writtenKey("list", list.ClientID, listKeys[i], list)
+			if err != nil {
+				return err
+			}
+			t.written[key] = encoded
+		}
+	}
+	return nil
+}
+
+func (t *FileTransport) changeFile(deviceID string) string {
+	return filepath.Join(t.dir, deviceID+fileChangeExt)
+}
+
+func (t *FileTransport) cursorFile() string {
+	return filepath.Join(t.dir, t.deviceID+fileCursorExt)
+}
+
+// loadCursors reads how far into each peer's change file this device has read
+func (t *FileTransport) loadCursors() (map[string]int64, error) {
+	cursors := map[string]int64{}
+
+	data, err := os.ReadFile(t.cursorFile())
+	if os.IsNotExist(err) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		device, offset, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(offset, 10, 64); err == nil {
+			cursors[device] = n
+		}
+	}
+	return cursors, nil
+}
+
+func (t *FileTransport) saveCursors(cursors map[string]int64) error {
+	var b strings.Builder
+	for device, offset := range cursors {
+		fmt.Fprintf(&b, "%s %d\n", device, offset)
+	}
+	return os.WriteFile(t.cursorFile(), []byte(b.String()), 0644)
+}
+
+// writtenKey returns the key and encoded form used to detect unchanged
+// payloads. The encoded form includes the ID of the key the payload is
+// sealed with, so rotating the key writes everything again.
+func writtenKey(entityType string, clientID string, keyID string, payload interface{}) (string, string, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return "", "", err
+	}
+	return entityType + ":" + clientID, keyID + " " + string(encoded), nil
+}
+
+// readChangeRecords reads complete records from path starting at offset and
+// returns the offset just past the last complete line. A trailing partial
+// line (a file still being copied in) is left for the next read. Complete
+// lines that are not valid records are skipped and counted, so one damaged
+// line does not stop every later change from syncing.
+func readChangeRecords(path string, offset int64) ([]fileChangeRecord, int64, int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, 0, nil
+	}
+	if err != nil {
+		return nil, offset, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, 0, err
+	}
+	if offset > info.Size() {
+		// The file was replaced or truncated; start again from the top
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, 0, err
+	}
+
+	var records []fileChangeRecord
+	skipped := 0
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, offset, 0, err
+		}
+		offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var record fileChangeRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, offset, skipped, nil
+}
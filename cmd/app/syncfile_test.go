@@ -0,0 +1,313 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTransport_PushThenPull(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewFileTransport(dir, "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	b, err := NewFileTransport(dir, "device-b", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+
+	items := []todoItem{{clientID: "t1", todo: "write report", priority: 2, dateAdded: 100}}
+	if err := a.PushChanges(items, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	resp, err := b.PullChanges(0)
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if len(resp.Tasks) != 1 || resp.Tasks[0].Todo != "write report" {
+		t.Fatalf("expected pulled task, got %+v", resp.Tasks)
+	}
+	if resp.Tasks[0].UpdatedAt <= 100 {
+		t.Errorf("expected UpdatedAt to be stamped with write time, got %d", resp.Tasks[0].UpdatedAt)
+	}
+
+	// Until the changes are committed as applied, they are pulled again
+	resp, err = b.PullChanges(1)
+	if err != nil {
+		t.Fatalf("second pull: %v", err)
+	}
+	if len(resp.Tasks) != 1 {
+		t.Errorf("expected uncommitted changes to be pulled again, got %d", len(resp.Tasks))
+	}
+
+	if err := b.CommitPull(); err != nil {
+		t.Fatalf("commit pull: %v", err)
+	}
+	resp, err = b.PullChanges(1)
+	if err != nil {
+		t.Fatalf("third pull: %v", err)
+	}
+	if len(resp.Tasks) != 0 {
+		t.Errorf("expected no new changes after commit, got %d", len(resp.Tasks))
+	}
+
+	own, err := a.PullChanges(0)
+	if err != nil {
+		t.Fatalf("pull own: %v", err)
+	}
+	if len(own.Tasks) != 0 {
+		t.Errorf("device should not pull its own changes, got %d", len(own.Tasks))
+	}
+}
+
+func TestFileTransport_SkipsUnchangedPush(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewFileTransport(dir, "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+
+	items := []todoItem{{clientID: "t1", todo: "one"}}
+	for i := 0; i < 2; i++ {
+		if err := a.PushChanges(items, nil); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	}
+
+	// A restarted transport must remember what it already wrote
+	restarted, err := NewFileTransport(dir, "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	if err := restarted.PushChanges(items, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	records, _, _, err := readChangeRecords(filepath.Join(dir, "device-a"+fileChangeExt), 0)
+	if err != nil {
+		t.Fatalf("read records: %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("expected 1 record, got %d", len(records))
+	}
+}
+
+func TestReadChangeRecords_IgnoresPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device"+fileChangeExt)
+	content := `{"timestamp":1,"device_id":"device"}` + "\n" + `{"timestamp":2,"dev`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	records, offset, skipped, err := readChangeRecords(path, 0)
+	if err != nil {
+		t.Fatalf("read records: %v", err)
+	}
+	if len(records) != 1 || skipped != 0 {
+		t.Fatalf("expected 1 complete record and none skipped, got %d and %d", len(records), skipped)
+	}
+	if want := int64(len(`{"timestamp":1,"device_id":"device"}`) + 1); offset != want {
+		t.Errorf("expected offset %d, got %d", want, offset)
+	}
+}
+
+func TestReadChangeRecords_SkipsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device"+fileChangeExt)
+	content := `{"timestamp":1,"device_id":"device"}` + "\n" + `{"timestamp":2,"dev` + "\n" + "not json\n" + `{"timestamp":3,"device_id":"device"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	records, offset, skipped, err := readChangeRecords(path, 0)
+	if err != nil {
+		t.Fatalf("read records: %v", err)
+	}
+	if len(records) != 2 || records[1].Timestamp != 3 {
+		t.Fatalf("expected the records around the bad lines, got %+v", records)
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped lines, got %d", skipped)
+	}
+	if offset != int64(len(content)) {
+		t.Errorf("expected offset %d past the last record, got %d", len(content), offset)
+	}
+}
+
+func TestSyncStore_PullSkipsBadRecords(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	other, err := NewFileTransport(dir, "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	transport, err := NewFileTransport(dir, "device-b", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	local := openTestStore(t, "todo.db")
+	store := NewSyncStore(local, transport, SyncConfig{})
+	listID, _ := local.CreateTodoList(ctx, "Work")
+
+	f, err := os.OpenFile(filepath.Join(dir, "device-a"+fileChangeExt), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open change file: %v", err)
+	}
+	f.WriteString("{broken\n")
+	f.Close()
+	if err := other.PushChanges([]todoItem{{clientID: "t1", todo: "write report", todoListID: listID}}, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	err = store.PullChanges(ctx, 0)
+	if !errors.Is(err, ErrSkippedRecords) {
+		t.Fatalf("expected skipped records to be reported, got %v", err)
+	}
+	if _, err := local.GetItemByClientID(ctx, "t1"); err != nil {
+		t.Errorf("expected the readable task to be applied: %v", err)
+	}
+
+	// The cursor moved past the bad line, so it is not reported again
+	if err := store.PullChanges(ctx, 1); err != nil {
+		t.Errorf("expected a clean second pull, got %v", err)
+	}
+}
+
+func TestFileTransport_Encrypted(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewSyncCipher(filepath.Join(t.TempDir(), "sync.key"), "shared secret", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	a, _ := NewFileTransport(dir, "device-a", c)
+	b, _ := NewFileTransport(dir, "device-b", c)
+	plain, _ := NewFileTransport(dir, "device-c", nil)
+
+	if err := a.PushChanges([]todoItem{{clientID: "t1", todo: "private"}}, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	resp, err := b.PullChanges(0)
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if len(resp.Tasks) != 1 || resp.Tasks[0].Todo != "private" {
+		t.Errorf("expected decrypted task, got %+v", resp.Tasks)
+	}
+
+	if _, err := plain.PullChanges(0); err == nil {
+		t.Error("expected an error pulling encrypted changes without a key")
+	}
+}
+
+func TestFileTransport_RotationRewritesEverything(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewSyncCipher(filepath.Join(t.TempDir(), "sync.key"), "old secret", "")
+	if err != nil {
+		t.Fatalf("create cipher: %v", err)
+	}
+	a, _ := NewFileTransport(dir, "device-a", c)
+
+	items := []todoItem{{clientID: "t1", todo: "one"}, {clientID: "t2", todo: "two"}}
+	if err := a.PushChanges(items, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if err := c.RotateKey("new secret", ""); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	// Even a restarted transport must see that the tasks use the old key
+	restarted, _ := NewFileTransport(dir, "device-a", c)
+	if err := restarted.PushChanges(items, nil); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	records, _, _, err := readChangeRecords(filepath.Join(dir, "device-a"+fileChangeExt), 0)
+	if err != nil {
+		t.Fatalf("read records: %v", err)
+	}
+	if len(records) != 2 || len(records[1].Tasks) != 2 {
+		t.Fatalf("expected every task written again after rotation, got %+v", records)
+	}
+	for _, task := range records[1].Tasks {
+		if task.KeyID != c.CurrentKeyID() {
+			t.Errorf("expected task %s sealed with key %s, got %s", task.ClientID, c.CurrentKeyID(), task.KeyID)
+		}
+	}
+}
+
+func TestSyncStore_BulkChangeLogsInSameTransaction(t *testing.T) {
+	ctx := t.Context()
+	local := openTestStore(t, "todo.db")
+	transport, err := NewFileTransport(t.TempDir(), "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	store := NewSyncStore(local, transport, SyncConfig{})
+
+	listID, _ := local.CreateTodoList(ctx, "Work")
+	first, _ := local.SaveItem(ctx, todoItem{todo: "write report", todoListID: listID})
+	second, _ := local.SaveItem(ctx, todoItem{todo: "review", todoListID: listID})
+
+	items := []todoItem{{id: first, todo: "write report", priority: 1, todoListID: listID}, {id: second, todo: "review", priority: 1, todoListID: listID}}
+	if err := store.UpdateItems(ctx, items); err != nil {
+		t.Fatalf("update items: %v", err)
+	}
+	if changes, _ := local.GetPendingChanges(ctx); len(changes) != 2 {
+		t.Fatalf("expected a change logged per task, got %d", len(changes))
+	}
+
+	// A failed change rolls back with its log entries
+	failed := errors.New("disk full")
+	err = store.bulkChange(ctx, "update items", "task", []int{first}, "update", func(tx *sql.Tx) error {
+		if err := updateItems(ctx, tx, []todoItem{{id: first, todo: "changed", todoListID: listID}}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the change's error, got %v", err)
+	}
+	if changes, _ := local.GetPendingChanges(ctx); len(changes) != 2 {
+		t.Errorf("expected no change logged for the failed update, got %d", len(changes))
+	}
+	if item, _ := local.GetItemByID(ctx, first); item.todo != "write report" {
+		t.Errorf("expected the failed update rolled back, got %q", item.todo)
+	}
+}
+
+func TestSyncStore_PullAppliesListOrder(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	other, err := NewFileTransport(dir, "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	transport, err := NewFileTransport(dir, "device-b", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	local := openTestStore(t, "todo.db")
+	store := NewSyncStore(local, transport, SyncConfig{})
+
+	local.CreateTodoList(ctx, "Work")
+	local.CreateTodoList(ctx, "Home")
+	lists, _ := local.GetTodoLists(ctx)
+	list := lists[0]
+
+	moved := todoList{clientID: list.clientID, name: "Work", displayOrder: 5, sortMode: SortModeManual, updatedAt: list.updatedAt + 10}
+	if err := other.PushChanges(nil, []todoList{moved}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if err := store.PullChanges(ctx, 0); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	got, _ := local.GetTodoListByClientID(ctx, list.clientID)
+	if got.displayOrder != 5 || got.sortMode != SortModeManual {
+		t.Errorf("expected pulled order and sort mode, got order %d and sort mode %q", got.displayOrder, got.sortMode)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,235 +12,423 @@ import (
 // SyncStore wraps LocalStore with synchronization capabilities
 type SyncStore struct {
 	local   *LocalStore
-	client  *SyncClient
+	client  SyncTransport
 	config  SyncConfig
 	mu      sync.RWMutex
 	stopCh  chan struct{}
 	running bool
+
+	// ctx bounds syncs started by the store itself and is cancelled by
+	// StopBackgroundSync so in-flight requests do not outlive the app
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewSyncStore creates a new sync store instance
-func NewSyncStore(local *LocalStore, client *SyncClient, config SyncConfig) *SyncStore {
+func NewSyncStore(local *LocalStore, client SyncTransport, config SyncConfig) *SyncStore {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &SyncStore{
 		local:   local,
 		client:  client,
 		config:  config,
 		stopCh:  make(chan struct{}),
 		running: false,
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 }
 
 // GetTodoLists retrieves all todo lists
-func (s *SyncStore) GetTodoLists() ([]todoList, error) {
-	return s.local.GetTodoLists()
+func (s *SyncStore) GetTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.local.GetTodoLists(ctx)
 }
 
 // CreateTodoList creates a new todo list
-func (s *SyncStore) CreateTodoList(name string) (int, error) {
-	id, err := s.local.CreateTodoList(name)
+func (s *SyncStore) CreateTodoList(ctx context.Context, name string) (int, error) {
+	id, err := s.local.CreateTodoList(ctx, name)
 	if err != nil {
 		return 0, err
 	}
 
-	s.local.LogChange("list", id, "create")
+	s.local.LogChange(ctx, "list", id, "create")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return id, nil
 }
 
 // UpdateTodoListName updates a todo list name
-func (s *SyncStore) UpdateTodoListName(id int, name string) error {
-	err := s.local.UpdateTodoListName(id, name)
+func (s *SyncStore) UpdateTodoListName(ctx context.Context, id int, name string) error {
+	err := s.local.UpdateTodoListName(ctx, id, name)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("list", id, "update")
+	s.local.LogChange(ctx, "list", id, "update")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
 // DeleteTodoList deletes a todo list
-func (s *SyncStore) DeleteTodoList(id int) error {
-	err := s.local.DeleteTodoList(id)
+func (s *SyncStore) DeleteTodoList(ctx context.Context, id int) error {
+	err := s.local.DeleteTodoList(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("list", id, "delete")
+	s.local.LogChange(ctx, "list", id, "delete")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
 // ArchiveTodoList archives a todo list
-func (s *SyncStore) ArchiveTodoList(id int) error {
-	err := s.local.ArchiveTodoList(id)
+func (s *SyncStore) ArchiveTodoList(ctx context.Context, id int) error {
+	err := s.local.ArchiveTodoList(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("list", id, "update")
+	s.local.LogChange(ctx, "list", id, "update")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
 // UnarchiveTodoList unarchives a todo list
-func (s *SyncStore) UnarchiveTodoList(id int) error {
-	err := s.local.UnarchiveTodoList(id)
+func (s *SyncStore) UnarchiveTodoList(ctx context.Context, id int) error {
+	err := s.local.UnarchiveTodoList(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("list", id, "update")
+	s.local.LogChange(ctx, "list", id, "update")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
+	}
+
+	return nil
+}
+
+// RestoreTodoList takes a list out of the trash
+func (s *SyncStore) RestoreTodoList(ctx context.Context, list todoList) error {
+	err := s.local.RestoreTodoList(ctx, list)
+	if err != nil {
+		return err
+	}
+
+	s.local.LogChange(ctx, "list", list.id, "update")
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
+	}
+
+	return nil
+}
+
+// ReorderTodoLists changes the list order, logging every list as updated
+func (s *SyncStore) ReorderTodoLists(ctx context.Context, ids []int) error {
+	return s.bulkChange(ctx, "reorder todo lists", "list", ids, "update", func(tx *sql.Tx) error {
+		return reorderTodoLists(ctx, tx, ids, now())
+	})
+}
+
+// SetTodoListView remembers a list's sort and group modes. The sort mode is
+// synced so manual order carries over; grouping stays a local preference.
+func (s *SyncStore) SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error {
+	if err := s.local.SetTodoListView(ctx, id, sortMode, groupMode); err != nil {
+		return err
+	}
+
+	s.local.LogChange(ctx, "list", id, "update")
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
+	}
+
+	return nil
+}
+
+// GetArchivedTodoLists retrieves archived lists
+func (s *SyncStore) GetArchivedTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.local.GetArchivedTodoLists(ctx)
+}
+
+// GetDeletedTodoLists retrieves the lists in the trash
+func (s *SyncStore) GetDeletedTodoLists(ctx context.Context) ([]todoList, error) {
+	return s.local.GetDeletedTodoLists(ctx)
+}
+
+// PurgeTodoList permanently removes a deleted list and the tasks that went
+// to the trash with it
+func (s *SyncStore) PurgeTodoList(ctx context.Context, id int) error {
+	err := s.local.PurgeTodoList(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.local.LogChange(ctx, "list", id, "purge")
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
 // GetItems retrieves all items
-func (s *SyncStore) GetItems() ([]todoItem, error) {
-	return s.local.GetItems()
+func (s *SyncStore) GetItems(ctx context.Context) ([]todoItem, error) {
+	return s.local.GetItems(ctx)
 }
 
 // GetItemByID retrieves an item by ID
-func (s *SyncStore) GetItemByID(id int) (todoItem, error) {
-	return s.local.GetItemByID(id)
+func (s *SyncStore) GetItemByID(ctx context.Context, id int) (todoItem, error) {
+	return s.local.GetItemByID(ctx, id)
 }
 
 // GetItemByClientID retrieves an item by client ID
-func (s *SyncStore) GetItemByClientID(clientID string) (todoItem, error) {
-	return s.local.GetItemByClientID(clientID)
+func (s *SyncStore) GetItemByClientID(ctx context.Context, clientID string) (todoItem, error) {
+	return s.local.GetItemByClientID(ctx, clientID)
 }
 
 // SaveItem saves a new item
-func (s *SyncStore) SaveItem(item todoItem) error {
+func (s *SyncStore) SaveItem(ctx context.Context, item todoItem) (int, error) {
 	if item.clientID == "" {
 		item.clientID = generateClientID()
 	}
 
-	err := s.local.SaveItem(item)
+	id, err := s.local.SaveItem(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+
+	s.local.LogChange(ctx, "task", id, "create")
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
+	}
+
+	return id, nil
+}
+
+// UpdateItem updates an existing item
+func (s *SyncStore) UpdateItem(ctx context.Context, item todoItem) error {
+	err := s.local.UpdateItem(ctx, item)
 	if err != nil {
 		return err
 	}
 
-	// Get the item back to get the ID
-	savedItem, err := s.local.GetItemByClientID(item.clientID)
-	if err == nil {
-		s.local.LogChange("task", savedItem.id, "create")
+	s.local.LogChange(ctx, "task", item.id, "update")
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
+	}
+
+	return nil
+}
+
+// DeleteItem deletes an item
+func (s *SyncStore) DeleteItem(ctx context.Context, id int) error {
+	err := s.local.DeleteItem(ctx, id)
+	if err != nil {
+		return err
 	}
 
+	s.local.LogChange(ctx, "task", id, "delete")
+
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
-// UpdateItem updates an existing item
-func (s *SyncStore) UpdateItem(item todoItem) error {
-	err := s.local.UpdateItem(item)
+// UpdateItems updates several items, logging them together and syncing
+// once
+func (s *SyncStore) UpdateItems(ctx context.Context, items []todoItem) error {
+	return s.bulkChange(ctx, "update items", "task", itemIDs(items), "update", func(tx *sql.Tx) error {
+		return updateItems(ctx, tx, items)
+	})
+}
+
+// DeleteItems deletes several items, logging them together and syncing
+// once
+func (s *SyncStore) DeleteItems(ctx context.Context, ids []int) error {
+	return s.bulkChange(ctx, "delete items", "task", ids, "delete", func(tx *sql.Tx) error {
+		return deleteItems(ctx, tx, ids, now())
+	})
+}
+
+// RestoreItems restores several deleted items, logging them together and
+// syncing once
+func (s *SyncStore) RestoreItems(ctx context.Context, items []todoItem) error {
+	return s.bulkChange(ctx, "restore items", "task", itemIDs(items), "restore", func(tx *sql.Tx) error {
+		return restoreItems(ctx, tx, items)
+	})
+}
+
+// bulkChange runs change and logs it for every entity in the same
+// transaction, so a change is never saved without being queued for sync
+func (s *SyncStore) bulkChange(ctx context.Context, operation string, entityType string, ids []int, changeType string, change func(tx *sql.Tx) error) error {
+	timestamp := now()
+	err := s.local.inTx(ctx, operation, func(tx *sql.Tx) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		return logChanges(ctx, tx, entityType, ids, changeType, timestamp)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Trigger sync if enabled
+	if s.config.AutoSyncOnChange && s.client.IsOnline() {
+		go s.FullSync(s.ctx)
+	}
+
+	return nil
+}
+
+func itemIDs(items []todoItem) []int {
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.id
+	}
+	return ids
+}
+
+// RestoreItem restores a deleted item
+func (s *SyncStore) RestoreItem(ctx context.Context, item todoItem) error {
+	err := s.local.RestoreItem(ctx, item)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("task", item.id, "update")
+	s.local.LogChange(ctx, "task", item.id, "update")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
-// DeleteItem deletes an item
-func (s *SyncStore) DeleteItem(id int) error {
-	err := s.local.DeleteItem(id)
+// GetDeletedItems retrieves the tasks in the trash
+func (s *SyncStore) GetDeletedItems(ctx context.Context) ([]todoItem, error) {
+	return s.local.GetDeletedItems(ctx)
+}
+
+// PurgeItem permanently removes a deleted item
+func (s *SyncStore) PurgeItem(ctx context.Context, id int) error {
+	err := s.local.PurgeItem(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	s.local.LogChange("task", id, "delete")
+	s.local.LogChange(ctx, "task", id, "purge")
 
 	// Trigger sync if enabled
 	if s.config.AutoSyncOnChange && s.client.IsOnline() {
-		go s.FullSync()
+		go s.FullSync(s.ctx)
 	}
 
 	return nil
 }
 
+// PurgeDeletedBefore empties trash older than cutoff. Tombstones for the
+// purged rows go out with the next push.
+func (s *SyncStore) PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return s.local.PurgeDeletedBefore(ctx, cutoff)
+}
+
+// ArchiveCompletedBefore moves old completions into the local history.
+// Nothing is pushed: other devices keep the tasks until they archive them
+// themselves.
+func (s *SyncStore) ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return s.local.ArchiveCompletedBefore(ctx, cutoff)
+}
+
+// GetHistory searches the local history
+func (s *SyncStore) GetHistory(ctx context.Context, query string) ([]historyItem, error) {
+	return s.local.GetHistory(ctx, query)
+}
+
 // GetLastSyncTime retrieves the last sync timestamp
-func (s *SyncStore) GetLastSyncTime() (int64, error) {
-	return s.local.GetLastSyncTime()
+func (s *SyncStore) GetLastSyncTime(ctx context.Context) (int64, error) {
+	return s.local.GetLastSyncTime(ctx)
 }
 
 // SetLastSyncTime updates the last sync timestamp
-func (s *SyncStore) SetLastSyncTime(timestamp int64) error {
-	return s.local.SetLastSyncTime(timestamp)
+func (s *SyncStore) SetLastSyncTime(ctx context.Context, timestamp int64) error {
+	return s.local.SetLastSyncTime(ctx, timestamp)
 }
 
 // GetPendingChanges retrieves pending changes
-func (s *SyncStore) GetPendingChanges() ([]Change, error) {
-	return s.local.GetPendingChanges()
+func (s *SyncStore) GetPendingChanges(ctx context.Context) ([]Change, error) {
+	return s.local.GetPendingChanges(ctx)
 }
 
 // MarkChangeSynced marks a change as synced
-func (s *SyncStore) MarkChangeSynced(changeID int) error {
-	return s.local.MarkChangeSynced(changeID)
+func (s *SyncStore) MarkChangeSynced(ctx context.Context, changeID int) error {
+	return s.local.MarkChangeSynced(ctx, changeID)
 }
 
 // LogChange logs a change
-func (s *SyncStore) LogChange(entityType string, entityID int, changeType string) error {
-	return s.local.LogChange(entityType, entityID, changeType)
+func (s *SyncStore) LogChange(ctx context.Context, entityType string, entityID int, changeType string) error {
+	return s.local.LogChange(ctx, entityType, entityID, changeType)
 }
 
 // FullSync performs a complete sync (pull then push)
-func (s *SyncStore) FullSync() error {
-	lastSync, _ := s.local.GetLastSyncTime()
+func (s *SyncStore) FullSync(ctx context.Context) error {
+	lastSync, _ := s.local.GetLastSyncTime(ctx)
 
-	// Pull first to get latest state from server
-	if err := s.PullChanges(lastSync); err != nil {
-		return fmt.Errorf("pull changes failed: %w", err)
+	// Pull first to get latest state from server. Skipped records are
+	// reported once the rest of the sync is done.
+	pullErr := s.PullChanges(ctx, lastSync)
+	if pullErr != nil && !errors.Is(pullErr, ErrSkippedRecords) {
+		return fmt.Errorf("pull changes failed: %w", pullErr)
 	}
 
 	// Push local changes
-	if err := s.PushChanges(); err != nil {
+	if err := s.PushChanges(ctx); err != nil {
 		return fmt.Errorf("push changes failed: %w", err)
 	}
 
 	// Update last sync time
-	s.local.SetLastSyncTime(time.Now().Unix())
+	s.local.SetLastSyncTime(ctx, time.Now().Unix())
 
-	return nil
+	return pullErr
 }
 
-// PullChanges pulls changes from server and applies them locally
-func (s *SyncStore) PullChanges(since int64) error {
+// PullChanges pulls changes from server and applies them locally. The
+// transport is only told the changes were received once all of them are
+// applied, so a failure pulls them again on the next sync.
+func (s *SyncStore) PullChanges(ctx context.Context, since int64) error {
 	resp, err := s.client.PullChanges(since)
 	if err != nil {
 		return err
@@ -249,9 +440,27 @@ func (s *SyncStore) PullChanges(since int64) error {
 
 	// Apply task changes
 	for _, serverTask := range resp.Tasks {
+		// Never resurrect a task that was purged or archived here
+		purged, err := s.local.HasTombstone(ctx, serverTask.ClientID)
+		if err != nil {
+			return err
+		}
+		archived, err := s.local.InHistory(ctx, serverTask.ClientID)
+		if err != nil {
+			return err
+		}
+		if purged || archived {
+			continue
+		}
+
 		// Try to find existing local task by client ID
-		localTask, err := s.local.GetItemByClientID(serverTask.ClientID)
+		localTask, err := s.local.GetItemByClientID(ctx, serverTask.ClientID)
 		if err != nil {
+			// Deleted elsewhere before we ever saw it - nothing to do
+			if serverTask.Deleted {
+				continue
+			}
+
 			// Doesn't exist locally - create it
 			newItem := todoItem{
 				clientID:      serverTask.ClientID,
@@ -265,9 +474,23 @@ func (s *SyncStore) PullChanges(since int64) error {
 				deleted:       serverTask.Deleted,
 				deletedAt:     serverTask.DeletedAt,
 				todoListID:    serverTask.TodoListID,
+				position:      serverTask.Position,
 				version:       serverTask.Version,
 			}
-			s.local.SaveItem(newItem)
+			if _, err := s.local.SaveItem(ctx, newItem); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A deletion elsewhere always moves the local copy to the trash.
+		// Tombstones carry no task data, so only the flag is applied.
+		if serverTask.Deleted {
+			if !localTask.deleted {
+				if err := s.local.DeleteItem(ctx, localTask.id); err != nil {
+					return err
+				}
+			}
 			continue
 		}
 
@@ -282,46 +505,100 @@ func (s *SyncStore) PullChanges(since int64) error {
 			localTask.deleted = serverTask.Deleted
 			localTask.deletedAt = serverTask.DeletedAt
 			localTask.todoListID = serverTask.TodoListID
+			localTask.position = serverTask.Position
 			localTask.version = serverTask.Version
-			s.local.UpdateItem(localTask)
+			if err := s.local.UpdateItem(ctx, localTask); err != nil {
+				return err
+			}
 		}
 		// Otherwise local is newer, leave it as is
 	}
 
-	// Apply list changes
+	// Apply list order. Creating, renaming and deleting lists from other
+	// devices is not synced yet, so only lists known here are updated.
 	for _, serverList := range resp.Lists {
-		// For now, we'll skip list syncing as it requires more complex logic
-		// with archived lists
-		_ = serverList
+		if serverList.Deleted {
+			continue
+		}
+		localList, err := s.local.GetTodoListByClientID(ctx, serverList.ClientID)
+		if err != nil {
+			continue
+		}
+		if serverList.UpdatedAt > localList.updatedAt {
+			if err := s.local.ApplyTodoListOrder(ctx, localList.id, serverList.DisplayOrder, serverList.SortMode, serverList.UpdatedAt); err != nil {
+				return err
+			}
+		}
 	}
 
+	if err := s.client.CommitPull(); err != nil {
+		return err
+	}
+	if resp.Skipped > 0 {
+		return fmt.Errorf("%w: %d", ErrSkippedRecords, resp.Skipped)
+	}
 	return nil
 }
 
-// PushChanges pushes pending local changes to the server
-func (s *SyncStore) PushChanges() error {
-	items, err := s.local.GetItems()
+// PushChanges pushes pending local changes to the server. Trashed rows are
+// sent with their deleted flag and purged rows as tombstones.
+func (s *SyncStore) PushChanges(ctx context.Context) error {
+	items, err := s.local.GetItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	deletedItems, err := s.local.GetDeletedItems(ctx)
+	if err != nil {
+		return err
+	}
+	items = append(items, deletedItems...)
+
+	lists, err := s.local.GetTodoLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	archivedLists, err := s.local.GetArchivedTodoLists(ctx)
 	if err != nil {
 		return err
 	}
+	lists = append(lists, archivedLists...)
 
-	lists, err := s.local.GetTodoLists()
+	deletedLists, err := s.local.GetDeletedTodoLists(ctx)
 	if err != nil {
 		return err
 	}
+	lists = append(lists, deletedLists...)
+
+	tombstones, err := s.local.GetTombstones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, tombstone := range tombstones {
+		if tombstone.entityType == "list" {
+			lists = append(lists, todoList{clientID: tombstone.clientID, deleted: true, deletedAt: tombstone.deletedAt, updatedAt: tombstone.deletedAt})
+		} else {
+			items = append(items, todoItem{clientID: tombstone.clientID, deleted: true, deletedAt: tombstone.deletedAt})
+		}
+	}
 
 	if err := s.client.PushChanges(items, lists); err != nil {
 		return err
 	}
 
+	for _, tombstone := range tombstones {
+		s.local.MarkTombstoneSynced(ctx, tombstone.clientID)
+	}
+
 	// Mark all changes as synced
-	changes, err := s.local.GetPendingChanges()
+	changes, err := s.local.GetPendingChanges(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, change := range changes {
-		s.local.MarkChangeSynced(change.id)
+		s.local.MarkChangeSynced(ctx, change.id)
 	}
 
 	return nil
@@ -345,7 +622,7 @@ func (s *SyncStore) StartBackgroundSync() {
 			select {
 			case <-ticker.C:
 				if s.client.IsOnline() {
-					s.FullSync()
+					s.FullSync(s.ctx)
 				}
 			case <-s.stopCh:
 				return
@@ -359,6 +636,7 @@ func (s *SyncStore) StopBackgroundSync() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.cancel()
 	if s.running {
 		close(s.stopCh)
 		s.running = false
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSkippedRecords is returned by a sync that applied everything it could
+// read but had to pass over unreadable change records
+var ErrSkippedRecords = errors.New("skipped unreadable change records")
+
+// SyncTransport moves sync payloads between this device and its peers.
+// SyncStore works the same with any implementation.
+type SyncTransport interface {
+	// IsOnline reports whether the transport can currently be used
+	IsOnline() bool
+	// PullChanges returns changes made elsewhere since the given timestamp
+	PullChanges(since int64) (*PullResponse, error)
+	// CommitPull records that the last pulled changes were applied, so
+	// they are not returned again
+	CommitPull() error
+	// PushChanges publishes the local state of the given items and lists
+	PushChanges(items []todoItem, lists []todoList) error
+}
+
+// Sync transport names accepted by TODO_SYNC_TRANSPORT
+const (
+	SyncTransportHTTP = "http"
+	SyncTransportFile = "file"
+)
+
+// NewSyncTransport creates the transport selected in the sync config
+func NewSyncTransport(config SyncConfig, cipher *SyncCipher) (SyncTransport, error) {
+	switch config.Transport {
+	case SyncTransportHTTP:
+		return NewSyncClient(config, cipher), nil
+	case SyncTransportFile:
+		return NewFileTransport(config.Dir, config.DeviceID, cipher)
+	default:
+		return nil, fmt.Errorf("unknown sync transport %q (expected %q or %q)", config.Transport, SyncTransportHTTP, SyncTransportFile)
+	}
+}
+
+// newPushRequest converts local items and lists into sync payloads
+func newPushRequest(items []todoItem, lists []todoList) PushRequest {
+	taskPayloads := make([]TaskPayload, len(items))
+	for i, item := range items {
+		taskPayloads[i] = TaskPayload{
+			ClientID:      item.clientID,
+			Todo:          item.todo,
+			Priority:      item.priority,
+			Done:          item.done,
+			DateAdded:     item.dateAdded,
+			DateCompleted: item.dateCompleted,
+			DueDate:       item.dueDate,
+			Deleted:       item.deleted,
+			DeletedAt:     item.deletedAt,
+			TodoListID:    item.todoListID,
+			Position:      item.position,
+			UpdatedAt:     item.dateAdded, // TODO: Use actual updated timestamp
+			Version:       item.version,
+		}
+	}
+
+	listPayloads := make([]ListPayload, len(lists))
+	for i, list := range lists {
+		listPayloads[i] = ListPayload{
+			ClientID:     list.clientID,
+			Name:         list.name,
+			DisplayOrder: list.displayOrder,
+			SortMode:     list.sortMode,
+			Archived:     list.archived,
+			Deleted:      list.deleted,
+			DeletedAt:    list.deletedAt,
+			UpdatedAt:    list.updatedAt,
+			Version:      list.version,
+		}
+	}
+
+	return PushRequest{
+		Tasks: taskPayloads,
+		Lists: listPayloads,
+	}
+}
+
+// openPullResponse decrypts a pull response, or rejects encrypted payloads
+// when this device has no key
+func openPullResponse(resp *PullResponse, cipher *SyncCipher) error {
+	if cipher != nil {
+		return cipher.OpenPull(resp)
+	}
+	return checkPlaintextPull(resp)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the colors the styles are built from. Colors may be
+// lipgloss.AdaptiveColor to follow the terminal's background.
+type Theme struct {
+	Accent           lipgloss.TerminalColor // Titles, task text and group headers
+	Selection        lipgloss.TerminalColor // Background of the selected row
+	Muted            lipgloss.TerminalColor // Status lines, archived lists and borders
+	Error            lipgloss.TerminalColor
+	Overdue          lipgloss.TerminalColor
+	PriorityHigh     lipgloss.TerminalColor
+	PriorityMedHigh  lipgloss.TerminalColor
+	PriorityMed      lipgloss.TerminalColor
+	PriorityLow      lipgloss.TerminalColor
+	ReverseSelection bool // Show the selected row in reverse video instead of a background
+}
+
+// themesTable holds user-defined themes, such as [themes.solarized]
+const themesTable = "themes"
+
+// noColorEnvVar turns off colors when set to anything, see no-color.org
+const noColorEnvVar = "NO_COLOR"
+
+// Built-in theme names
+const (
+	ThemeDefault      = "default" // Adapts to light and dark terminals
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNone         = "none" // No colors, used for NO_COLOR
+)
+
+var darkTheme = Theme{
+	Accent:          lipgloss.Color("#3c71A8"),
+	Selection:       lipgloss.Color("#A3A3A3"),
+	Muted:           lipgloss.Color("#A3A3A3"),
+	Error:           lipgloss.Color("#FF0000"),
+	Overdue:         lipgloss.Color("#FF6B6B"),
+	PriorityHigh:    lipgloss.Color("#FF0000"),
+	PriorityMedHigh: lipgloss.Color("#FFA500"),
+	PriorityMed:     lipgloss.Color("#FFFF00"),
+	PriorityLow:     lipgloss.Color("#008000"),
+}
+
+var lightTheme = Theme{
+	Accent:          lipgloss.Color("#1F4E79"),
+	Selection:       lipgloss.Color("#D6D6D6"),
+	Muted:           lipgloss.Color("#6B6B6B"),
+	Error:           lipgloss.Color("#C00000"),
+	Overdue:         lipgloss.Color("#C0392B"),
+	PriorityHigh:    lipgloss.Color("#C00000"),
+	PriorityMedHigh: lipgloss.Color("#B35900"),
+	PriorityMed:     lipgloss.Color("#8A6D00"),
+	PriorityLow:     lipgloss.Color("#1E7B1E"),
+}
+
+// BuiltinThemes are the themes available without a config file
+var BuiltinThemes = map[string]Theme{
+	ThemeDefault: adaptiveTheme(lightTheme, darkTheme),
+	ThemeDark:    darkTheme,
+	ThemeLight:   lightTheme,
+	ThemeHighContrast: {
+		Accent:           lipgloss.AdaptiveColor{Light: "#000000", Dark: "#FFFFFF"},
+		Selection:        lipgloss.NoColor{},
+		Muted:            lipgloss.AdaptiveColor{Light: "#262626", Dark: "#E4E4E4"},
+		Error:            lipgloss.AdaptiveColor{Light: "#AF0000", Dark: "#FF5F5F"},
+		Overdue:          lipgloss.AdaptiveColor{Light: "#AF0000", Dark: "#FF5F5F"},
+		PriorityHigh:     lipgloss.AdaptiveColor{Light: "#AF0000", Dark: "#FF5F5F"},
+		PriorityMedHigh:  lipgloss.AdaptiveColor{Light: "#875F00", Dark: "#FFAF00"},
+		PriorityMed:      lipgloss.AdaptiveColor{Light: "#5F5F00", Dark: "#FFFF5F"},
+		PriorityLow:      lipgloss.AdaptiveColor{Light: "#005F00", Dark: "#5FFF5F"},
+		ReverseSelection: true,
+	},
+	ThemeNone: {
+		Accent:           lipgloss.NoColor{},
+		Selection:        lipgloss.NoColor{},
+		Muted:            lipgloss.NoColor{},
+		Error:            lipgloss.NoColor{},
+		Overdue:          lipgloss.NoColor{},
+		PriorityHigh:     lipgloss.NoColor{},
+		PriorityMedHigh:  lipgloss.NoColor{},
+		PriorityMed:      lipgloss.NoColor{},
+		PriorityLow:      lipgloss.NoColor{},
+		ReverseSelection: true,
+	},
+}
+
+// themeColors maps the color keys of a [themes.<name>] table to their
+// Theme fields
+var themeColors = map[string]func(t *Theme) *lipgloss.TerminalColor{
+	"accent":               func(t *Theme) *lipgloss.TerminalColor { return &t.Accent },
+	"selection":            func(t *Theme) *lipgloss.TerminalColor { return &t.Selection },
+	"muted":                func(t *Theme) *lipgloss.TerminalColor { return &t.Muted },
+	"error":                func(t *Theme) *lipgloss.TerminalColor { return &t.Error },
+	"overdue":              func(t *Theme) *lipgloss.TerminalColor { return &t.Overdue },
+	"priority_high":        func(t *Theme) *lipgloss.TerminalColor { return &t.PriorityHigh },
+	"priority_medium_high": func(t *Theme) *lipgloss.TerminalColor { return &t.PriorityMedHigh },
+	"priority_medium":      func(t *Theme) *lipgloss.TerminalColor { return &t.PriorityMed },
+	"priority_low":         func(t *Theme) *lipgloss.TerminalColor { return &t.PriorityLow },
+}
+
+// adaptiveTheme picks each color from light or dark depending on the
+// terminal's background
+func adaptiveTheme(light, dark Theme) Theme {
+	pair := func(l, d lipgloss.TerminalColor) lipgloss.TerminalColor {
+		return lipgloss.AdaptiveColor{Light: string(l.(lipgloss.Color)), Dark: string(d.(lipgloss.Color))}
+	}
+	return Theme{
+		Accent:          pair(light.Accent, dark.Accent),
+		Selection:       pair(light.Selection, dark.Selection),
+		Muted:           pair(light.Muted, dark.Muted),
+		Error:           pair(light.Error, dark.Error),
+		Overdue:         pair(light.Overdue, dark.Overdue),
+		PriorityHigh:    pair(light.PriorityHigh, dark.PriorityHigh),
+		PriorityMedHigh: pair(light.PriorityMedHigh, dark.PriorityMedHigh),
+		PriorityMed:     pair(light.PriorityMed, dark.PriorityMed),
+		PriorityLow:     pair(light.PriorityLow, dark.PriorityLow),
+	}
+}
+
+// themeNames lists the built-in and user-defined themes
+func themeNames(cfg Config) []string {
+	var names []string
+	for name := range BuiltinThemes {
+		names = append(names, name)
+	}
+	for name := range cfg.Themes {
+		if _, builtin := BuiltinThemes[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTheme returns the configured theme. A user-defined theme starts from
+// its base theme, the default one unless base is set, and replaces the
+// colors it lists.
+func loadTheme(cfg Config) (Theme, error) {
+	name := strings.ToLower(cfg.Theme)
+	values, isUser := cfg.Themes[name]
+	if !isUser {
+		theme, ok := BuiltinThemes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", cfg.Theme, strings.Join(themeNames(cfg), ", "))
+		}
+		return theme, nil
+	}
+
+	base := ThemeDefault
+	if value, ok := values["base"]; ok {
+		base = strings.ToLower(value)
+	}
+	theme, ok := BuiltinThemes[base]
+	if !ok {
+		return Theme{}, fmt.Errorf("theme %s: unknown base theme %q", name, base)
+	}
+
+	var errs []string
+	for key, value := range values {
+		switch field, isColor := themeColors[key]; {
+		case key == "base":
+		case key == "reverse_selection":
+			reverse, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %q is not true or false", key, value))
+			}
+			theme.ReverseSelection = reverse
+		case isColor:
+			color, err := parseThemeColor(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			}
+			*field(&theme) = color
+		default:
+			errs = append(errs, "unknown key "+key)
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return Theme{}, fmt.Errorf("theme %s: %s", name, strings.Join(errs, "; "))
+	}
+	return theme, nil
+}
+
+// parseThemeColor reads "#RRGGBB", "#RGB" or an ANSI color number. Two
+// colors separated by a comma are used on light and dark backgrounds.
+func parseThemeColor(value string) (lipgloss.TerminalColor, error) {
+	if light, dark, found := strings.Cut(value, ","); found {
+		light, dark = strings.TrimSpace(light), strings.TrimSpace(dark)
+		if err := checkThemeColor(light); err != nil {
+			return nil, err
+		}
+		if err := checkThemeColor(dark); err != nil {
+			return nil, err
+		}
+		return lipgloss.AdaptiveColor{Light: light, Dark: dark}, nil
+	}
+	value = strings.TrimSpace(value)
+	if err := checkThemeColor(value); err != nil {
+		return nil, err
+	}
+	return lipgloss.Color(value), nil
+}
+
+func checkThemeColor(value string) error {
+	if hex, isHex := strings.CutPrefix(value, "#"); isHex {
+		if _, err := strconv.ParseUint(hex, 16, 32); err == nil && (len(hex) == 3 || len(hex) == 6) {
+			return nil
+		}
+	} else if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 255 {
+		return nil
+	}
+	return fmt.Errorf("%q is not a color like #3c71a8 or an ANSI number 0-255", value)
+}
+
+// noColor reports whether NO_COLOR asks for output without colors
+func noColor() bool {
+	return os.Getenv(noColorEnvVar) != ""
+}
+
+// useTheme applies the configured theme, or no colors when NO_COLOR is set.
+// An invalid theme falls back to the default one.
+func useTheme(cfg Config) error {
+	if noColor() {
+		applyTheme(BuiltinThemes[ThemeNone])
+		return nil
+	}
+	theme, err := loadTheme(cfg)
+	if err != nil {
+		applyTheme(BuiltinThemes[ThemeDefault])
+		return err
+	}
+	applyTheme(theme)
+	return nil
+}
+
+// applyTheme rebuilds the styles from a theme's colors
+func applyTheme(t Theme) {
+	selected := lipgloss.NewStyle().Background(t.Selection)
+	if t.ReverseSelection {
+		selected = lipgloss.NewStyle().Reverse(true)
+	}
+
+	TitleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Accent).
+		MarginBottom(1)
+	SelectedStyle = selected
+	DoneStyle = lipgloss.NewStyle().Strikethrough(true)
+	SelectedDoneStyle = selected.Strikethrough(true)
+	ItemStyle = lipgloss.NewStyle().Foreground(t.Accent)
+	DocStyle = lipgloss.NewStyle().Margin(0)
+	OneStyle = lipgloss.NewStyle().Foreground(t.PriorityHigh)
+	TwoStyle = lipgloss.NewStyle().Foreground(t.PriorityMedHigh)
+	ThreeStyle = lipgloss.NewStyle().Foreground(t.PriorityMed)
+	FourStyle = lipgloss.NewStyle().Foreground(t.PriorityLow)
+	SelectedOneStyle = selected.Foreground(t.PriorityHigh)
+	SelectedTwoStyle = selected.Foreground(t.PriorityMedHigh)
+	SelectedThreeStyle = selected.Foreground(t.PriorityMed)
+	SelectedFourStyle = selected.Foreground(t.PriorityLow)
+	OverdueStyle = lipgloss.NewStyle().Foreground(t.Overdue)
+	ErrorStyle = lipgloss.NewStyle().
+		Foreground(t.Error).
+		Bold(true)
+	StatusStyle = lipgloss.NewStyle().Foreground(t.Muted)
+	GroupHeaderStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Accent)
+	ArchivedStyle = lipgloss.NewStyle().Foreground(t.Muted)
+	DetailPaneStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		BorderForeground(t.Muted).
+		PaddingLeft(1)
+
+	PriorityStyles = map[int]lipgloss.Style{
+		PriorityHigh:    OneStyle,
+		PriorityMedHigh: TwoStyle,
+		PriorityMed:     ThreeStyle,
+		PriorityLow:     FourStyle,
+	}
+	SelectedPriorityStyles = map[int]lipgloss.Style{
+		PriorityHigh:    SelectedOneStyle,
+		PriorityMedHigh: SelectedTwoStyle,
+		PriorityMed:     SelectedThreeStyle,
+		PriorityLow:     SelectedFourStyle,
+	}
+}
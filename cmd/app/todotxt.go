@@ -0,0 +1,647 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// todo.txt date layout
+const todoTxtDateLayout = "2006-01-02"
+
+// Priorities map to todo.txt letters; anything past D reads as low
+var todoTxtPriorityLetters = map[int]string{
+	PriorityHigh:    "A",
+	PriorityMedHigh: "B",
+	PriorityMed:     "C",
+	PriorityLow:     "D",
+}
+
+// TodoTxtStore implements DataStore on a todo.txt/done.txt pair. Lists are
+// +project tags, priorities are (A)-(D) and due dates are due:YYYY-MM-DD.
+// Open tasks are written to todo.txt and completed tasks to done.txt.
+type TodoTxtStore struct {
+	todoPath   string
+	donePath   string
+	mu         sync.Mutex
+	items      []todoItem
+	lists      []todoList
+	lines      map[int]todoTxtLine // How each task read from the files was written, by ID
+	nextItemID int
+	nextListID int
+}
+
+// todoTxtLine is how a task was written in the file, so it can be written
+// back the same way while its fields are unchanged
+type todoTxtLine struct {
+	item        todoItem // The task as it was read
+	project     string   // First +project tag, without the +
+	hasPriority bool     // Whether the line had (X) or pri:X
+	tokens      []string // Words after the dates, tags included, in file order
+	projectAt   int      // Index of the +project token in tokens, -1 if none
+	dueAt       int      // Index of the due: token, -1 if none
+	priorityAt  int      // Index of the pri: token, -1 if none
+}
+
+// NewTodoTxtStore loads the todo.txt and done.txt files. Missing files are
+// treated as empty and created on the first write.
+func NewTodoTxtStore(todoPath string, donePath string) (*TodoTxtStore, error) {
+	if donePath == "" {
+		donePath = filepath.Join(filepath.Dir(todoPath), "done.txt")
+	}
+
+	s := &TodoTxtStore{
+		todoPath:   todoPath,
+		donePath:   donePath,
+		lines:      map[int]todoTxtLine{},
+		nextItemID: 1,
+		nextListID: 1,
+	}
+
+	for _, path := range []string{todoPath, donePath} {
+		if err := s.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *TodoTxtStore) loadFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		item, parsed := parseTodoTxtLine(line)
+		item.id = s.nextItemID
+		s.nextItemID++
+		item.clientID = generateClientID()
+		item.version = 1
+		item.todoListID = s.listIDForProject(parsed.project)
+		parsed.item = item
+		s.lines[item.id] = parsed
+		s.items = append(s.items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
+
+// listIDForProject returns the list for a +project tag, creating it on
+// first sight. Tasks without a project belong to the default list.
+func (s *TodoTxtStore) listIDForProject(project string) int {
+	name := project
+	if name == "" {
+		name = DefaultListName
+	}
+	for _, list := range s.lists {
+		if list.name == name || projectTag(list.name) == project {
+			return list.id
+		}
+	}
+	return s.addList(name)
+}
+
+func (s *TodoTxtStore) addList(name string) int {
+	list := todoList{
+		id:           s.nextListID,
+		clientID:     generateClientID(),
+		name:         name,
+		displayOrder: len(s.lists),
+		createdAt:    now(),
+		updatedAt:    now(),
+		version:      1,
+	}
+	s.nextListID++
+	s.lists = append(s.lists, list)
+	return list.id
+}
+
+// projectTag converts a list name into a +project token
+func projectTag(name string) string {
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// parseTodoTxtLine parses a single todo.txt line and returns the task and
+// how it was written, including its first +project tag (removed from the
+// task text)
+func parseTodoTxtLine(line string) (todoItem, todoTxtLine) {
+	item := todoItem{priority: DefaultPriority}
+	parsed := todoTxtLine{projectAt: -1, dueAt: -1, priorityAt: -1}
+	fields := strings.Fields(line)
+
+	if len(fields) > 0 && fields[0] == "x" {
+		item.done = true
+		fields = fields[1:]
+		if len(fields) > 0 {
+			if t, ok := parseTodoTxtDate(fields[0]); ok {
+				item.dateCompleted = t.Unix()
+				fields = fields[1:]
+			}
+		}
+	}
+
+	if len(fields) > 0 && isTodoTxtPriority(fields[0]) {
+		item.priority = todoTxtPriorityFromLetter(fields[0][1:2])
+		parsed.hasPriority = true
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		if t, ok := parseTodoTxtDate(fields[0]); ok {
+			item.dateAdded = t.Unix()
+			fields = fields[1:]
+		}
+	}
+
+	var words []string
+	for i, field := range fields {
+		switch {
+		case parsed.projectAt < 0 && strings.HasPrefix(field, "+") && len(field) > 1:
+			parsed.project = field[1:]
+			parsed.projectAt = i
+		case strings.HasPrefix(field, "due:"):
+			if t, ok := parseTodoTxtDate(strings.TrimPrefix(field, "due:")); ok {
+				item.dueDate = setToEndOfDay(t).Unix()
+				parsed.dueAt = i
+			} else {
+				words = append(words, field)
+			}
+		case strings.HasPrefix(field, "pri:") && len(field) == 5:
+			// Completed tasks keep their priority as pri:X
+			item.priority = todoTxtPriorityFromLetter(field[4:5])
+			parsed.hasPriority = true
+			parsed.priorityAt = i
+		default:
+			words = append(words, field)
+		}
+	}
+
+	item.todo = strings.Join(words, " ")
+	parsed.item = item
+	parsed.tokens = fields
+	return item, parsed
+}
+
+// formatTodoTxtLine renders a task as a todo.txt line. orig is how the task
+// was read, the zero value for a new task. A task read without a priority
+// is written without one until its priority changes, and while its text is
+// unchanged its tags stay where they were.
+func formatTodoTxtLine(item todoItem, listName string, orig todoTxtLine) string {
+	var parts []string
+	letter := todoTxtPriorityLetters[validatePriority(item.priority)]
+	writePriority := orig.hasPriority || item.priority != orig.item.priority
+
+	if item.done {
+		parts = append(parts, "x")
+		if item.dateCompleted > 0 {
+			parts = append(parts, formatTodoTxtDate(item.dateCompleted))
+		}
+	} else if writePriority {
+		parts = append(parts, "("+letter+")")
+	}
+
+	if item.dateAdded > 0 {
+		parts = append(parts, formatTodoTxtDate(item.dateAdded))
+	}
+
+	var project, due, priority string
+	if listName != "" && listName != DefaultListName {
+		project = "+" + projectTag(listName)
+	}
+	if item.dueDate > 0 {
+		due = "due:" + formatTodoTxtDate(item.dueDate)
+	}
+	if item.done && writePriority {
+		priority = "pri:" + letter
+	}
+
+	if orig.tokens != nil && item.todo == orig.item.todo {
+		for i, token := range orig.tokens {
+			switch i {
+			case orig.projectAt:
+				token, project = project, ""
+			case orig.dueAt:
+				token, due = due, ""
+			case orig.priorityAt:
+				token, priority = priority, ""
+			}
+			if token != "" {
+				parts = append(parts, token)
+			}
+		}
+	} else {
+		parts = append(parts, item.todo)
+	}
+
+	// Tags the line did not have yet go at the end
+	for _, tag := range []string{project, due, priority} {
+		if tag != "" {
+			parts = append(parts, tag)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func isTodoTxtPriority(field string) bool {
+	return len(field) == 3 && field[0] == '(' && field[2] == ')' && field[1] >= 'A' && field[1] <= 'Z'
+}
+
+func todoTxtPriorityFromLetter(letter string) int {
+	for priority, l := range todoTxtPriorityLetters {
+		if l == letter {
+			return priority
+		}
+	}
+	return PriorityLow
+}
+
+func formatTodoTxtDate(timestamp int64) string {
+	return time.Unix(timestamp, 0).Format(todoTxtDateLayout)
+}
+
+func parseTodoTxtDate(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation(todoTxtDateLayout, s, time.Local)
+	return t, err == nil
+}
+
+// save rewrites both files from memory
+func (s *TodoTxtStore) save() error {
+	var open, done []string
+	for _, item := range s.items {
+		line := formatTodoTxtLine(item, s.listName(item.todoListID), s.lines[item.id])
+		if item.done {
+			done = append(done, line)
+		} else {
+			open = append(open, line)
+		}
+	}
+
+	if err := writeLinesAtomic(s.todoPath, open); err != nil {
+		return err
+	}
+	return writeLinesAtomic(s.donePath, done)
+}
+
+// writeLinesAtomic replaces path with lines via a temporary file so a crash
+// never leaves a half-written file behind
+func writeLinesAtomic(path string, lines []string) error {
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+	return writeFileAtomic(path, content)
+}
+
+// writeFileAtomic replaces path with content via a temporary file
+func writeFileAtomic(path string, content string) error {
+	if err := ensureDBDirectory(path); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *TodoTxtStore) listName(id int) string {
+	for _, list := range s.lists {
+		if list.id == id {
+			return list.name
+		}
+	}
+	return ""
+}
+
+func (s *TodoTxtStore) itemIndex(id int) int {
+	for i, item := range s.items {
+		if item.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *TodoTxtStore) listIndex(id int) int {
+	for i, list := range s.lists {
+		if list.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetTodoLists returns every +project seen in the files plus lists created
+// this session
+func (s *TodoTxtStore) GetTodoLists(ctx context.Context) ([]todoList, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]todoList(nil), s.lists...), nil
+}
+
+// CreateTodoList adds a list. It is written to disk once a task uses it.
+func (s *TodoTxtStore) CreateTodoList(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addList(name), nil
+}
+
+// UpdateTodoListName renames the list and its +project tag on every task
+func (s *TodoTxtStore) UpdateTodoListName(ctx context.Context, id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+	s.lists[i].name = name
+	s.lists[i].updatedAt = now()
+	return s.save()
+}
+
+// DeleteTodoList removes the list and all of its tasks from both files
+func (s *TodoTxtStore) DeleteTodoList(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.listIndex(id)
+	if i < 0 {
+		return fmt.Errorf("list not found: %d", id)
+	}
+
+	remaining := s.items[:0]
+	for _, item := range s.items {
+		if item.todoListID != id {
+			remaining = append(remaining, item)
+		}
+	}
+	s.items = remaining
+	s.lists = append(s.lists[:i], s.lists[i+1:]...)
+	return s.save()
+}
+
+// RestoreTodoList re-adds a deleted list under its original ID
+func (s *TodoTxtStore) RestoreTodoList(ctx context.Context, list todoList) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listIndex(list.id) >= 0 {
+		return nil
+	}
+	list.archived = false
+	s.lists = append(s.lists, list)
+	return s.save()
+}
+
+// ArchiveTodoList is not supported; todo.txt has no notion of archived projects
+func (s *TodoTxtStore) ArchiveTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// UnarchiveTodoList is not supported; todo.txt has no notion of archived projects
+func (s *TodoTxtStore) UnarchiveTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// GetItems returns all tasks from both files
+func (s *TodoTxtStore) GetItems(ctx context.Context) ([]todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]todoItem(nil), s.items...), nil
+}
+
+// GetItemByID returns a task by its session ID
+func (s *TodoTxtStore) GetItemByID(ctx context.Context, id int) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.itemIndex(id); i >= 0 {
+		return s.items[i], nil
+	}
+	return todoItem{}, fmt.Errorf("item not found: %d", id)
+}
+
+// GetItemByClientID returns a task by its session client ID
+func (s *TodoTxtStore) GetItemByClientID(ctx context.Context, clientID string) (todoItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range s.items {
+		if item.clientID == clientID {
+			return item, nil
+		}
+	}
+	return todoItem{}, fmt.Errorf("item not found with client_id: %s", clientID)
+}
+
+// SaveItem appends a new task
+func (s *TodoTxtStore) SaveItem(ctx context.Context, item todoItem) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item.id = s.nextItemID
+	s.nextItemID++
+	if item.clientID == "" {
+		item.clientID = generateClientID()
+	}
+	if item.dateAdded == 0 {
+		item.dateAdded = now()
+	}
+	item.version = 1
+	s.items = append(s.items, item)
+	return item.id, s.save()
+}
+
+// UpdateItem replaces an existing task
+func (s *TodoTxtStore) UpdateItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(item.id)
+	if i < 0 {
+		return fmt.Errorf("item not found: %d", item.id)
+	}
+	s.items[i] = item
+	return s.save()
+}
+
+// DeleteItem removes a task from the files
+func (s *TodoTxtStore) DeleteItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.itemIndex(id)
+	if i < 0 {
+		return fmt.Errorf("item not found: %d", id)
+	}
+	s.items = append(s.items[:i], s.items[i+1:]...)
+	return s.save()
+}
+
+// RestoreItem re-adds a deleted task under its original ID
+func (s *TodoTxtStore) RestoreItem(ctx context.Context, item todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.itemIndex(item.id) >= 0 {
+		return nil
+	}
+	if s.listIndex(item.todoListID) < 0 {
+		return fmt.Errorf("list not found: %d", item.todoListID)
+	}
+	item.deleted = false
+	item.deletedAt = 0
+	s.items = append(s.items, item)
+	return s.save()
+}
+
+// UpdateItems updates several tasks and writes the files once
+func (s *TodoTxtStore) UpdateItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if s.itemIndex(item.id) < 0 {
+			return fmt.Errorf("item not found: %d", item.id)
+		}
+	}
+	for _, item := range items {
+		s.items[s.itemIndex(item.id)] = item
+	}
+	return s.save()
+}
+
+// DeleteItems removes several tasks and writes the files once
+func (s *TodoTxtStore) DeleteItems(ctx context.Context, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if s.itemIndex(id) < 0 {
+			return fmt.Errorf("item not found: %d", id)
+		}
+	}
+	for _, id := range ids {
+		i := s.itemIndex(id)
+		s.items = append(s.items[:i], s.items[i+1:]...)
+	}
+	return s.save()
+}
+
+// RestoreItems re-adds several deleted tasks and writes the files once
+func (s *TodoTxtStore) RestoreItems(ctx context.Context, items []todoItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range items {
+		if s.listIndex(item.todoListID) < 0 {
+			return fmt.Errorf("list not found: %d", item.todoListID)
+		}
+	}
+	for _, item := range items {
+		if s.itemIndex(item.id) >= 0 {
+			continue
+		}
+		item.deleted = false
+		item.deletedAt = 0
+		s.items = append(s.items, item)
+	}
+	return s.save()
+}
+
+// ReorderTodoLists is not supported; todo.txt projects have no order
+func (s *TodoTxtStore) ReorderTodoLists(ctx context.Context, ids []int) error {
+	return ErrNotSupported
+}
+
+// SetTodoListView is not supported; todo.txt has nowhere to keep it
+func (s *TodoTxtStore) SetTodoListView(ctx context.Context, id int, sortMode string, groupMode string) error {
+	return ErrNotSupported
+}
+
+// GetArchivedTodoLists returns nothing; todo.txt lists cannot be archived
+func (s *TodoTxtStore) GetArchivedTodoLists(ctx context.Context) ([]todoList, error) {
+	return []todoList{}, nil
+}
+
+// GetDeletedTodoLists returns nothing; todo.txt deletes are permanent
+func (s *TodoTxtStore) GetDeletedTodoLists(ctx context.Context) ([]todoList, error) {
+	return []todoList{}, nil
+}
+
+// GetDeletedItems returns nothing; todo.txt deletes are permanent
+func (s *TodoTxtStore) GetDeletedItems(ctx context.Context) ([]todoItem, error) {
+	return []todoItem{}, nil
+}
+
+// PurgeTodoList is not supported; there is no trash to purge from
+func (s *TodoTxtStore) PurgeTodoList(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// PurgeItem is not supported; there is no trash to purge from
+func (s *TodoTxtStore) PurgeItem(ctx context.Context, id int) error {
+	return ErrNotSupported
+}
+
+// PurgeDeletedBefore has nothing to do; there is no trash
+func (s *TodoTxtStore) PurgeDeletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return 0, nil
+}
+
+// ArchiveCompletedBefore is not supported; done.txt already holds completed tasks
+func (s *TodoTxtStore) ArchiveCompletedBefore(ctx context.Context, cutoff int64) (int, error) {
+	return 0, ErrNotSupported
+}
+
+// GetHistory is not supported; there is no history
+func (s *TodoTxtStore) GetHistory(ctx context.Context, query string) ([]historyItem, error) {
+	return nil, ErrNotSupported
+}
+
+// GetLastSyncTime always returns 0; todo.txt files are synced by other tools
+func (s *TodoTxtStore) GetLastSyncTime(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// SetLastSyncTime is a no-op for todo.txt
+func (s *TodoTxtStore) SetLastSyncTime(ctx context.Context, timestamp int64) error {
+	return nil
+}
+
+// GetPendingChanges returns no changes for todo.txt
+func (s *TodoTxtStore) GetPendingChanges(ctx context.Context) ([]Change, error) {
+	return []Change{}, nil
+}
+
+// MarkChangeSynced is a no-op for todo.txt
+func (s *TodoTxtStore) MarkChangeSynced(ctx context.Context, changeID int) error {
+	return nil
+}
+
+// LogChange is a no-op for todo.txt
+func (s *TodoTxtStore) LogChange(ctx context.Context, entityType string, entityID int, changeType string) error {
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxtLine(t *testing.T) {
+	item, parsed := parseTodoTxtLine("(B) 2025-03-01 Call plumber @phone +Home due:2025-03-05")
+
+	if item.priority != PriorityMedHigh {
+		t.Errorf("expected priority %d, got %d", PriorityMedHigh, item.priority)
+	}
+	if parsed.project != "Home" {
+		t.Errorf("expected project Home, got %q", parsed.project)
+	}
+	if item.todo != "Call plumber @phone" {
+		t.Errorf("expected text without project and due tag, got %q", item.todo)
+	}
+	if got := time.Unix(item.dateAdded, 0).Format(todoTxtDateLayout); got != "2025-03-01" {
+		t.Errorf("expected creation date 2025-03-01, got %s", got)
+	}
+	if got := time.Unix(item.dueDate, 0).Format(todoTxtDateLayout); got != "2025-03-05" {
+		t.Errorf("expected due date 2025-03-05, got %s", got)
+	}
+}
+
+func TestParseTodoTxtLine_Completed(t *testing.T) {
+	item, parsed := parseTodoTxtLine("x 2025-03-02 2025-03-01 File taxes +Work pri:A")
+
+	if !item.done {
+		t.Error("expected task to be done")
+	}
+	if item.priority != PriorityHigh {
+		t.Errorf("expected priority kept from pri:A, got %d", item.priority)
+	}
+	if got := time.Unix(item.dateCompleted, 0).Format(todoTxtDateLayout); got != "2025-03-02" {
+		t.Errorf("expected completion date 2025-03-02, got %s", got)
+	}
+	if parsed.project != "Work" || item.todo != "File taxes" {
+		t.Errorf("unexpected project %q / text %q", parsed.project, item.todo)
+	}
+}
+
+func TestFormatTodoTxtLine_RoundTrip(t *testing.T) {
+	lines := []string{
+		"(A) 2025-03-01 Fix login bug +Work due:2025-03-05",
+		"(D) Water plants",
+		"x 2025-03-02 2025-03-01 Ship release +Work pri:B",
+		"Water plants",
+		"+Home fix the sink due:2025-03-05 before the weekend",
+		"x 2025-03-02 pri:C Call +Work back",
+	}
+
+	for _, line := range lines {
+		item, parsed := parseTodoTxtLine(line)
+		listName := parsed.project
+		if listName == "" {
+			listName = DefaultListName
+		}
+		if got := formatTodoTxtLine(item, listName, parsed); got != line {
+			t.Errorf("round trip mismatch:\n  want %q\n  got  %q", line, got)
+		}
+	}
+}
+
+func TestFormatTodoTxtLine_ChangedFields(t *testing.T) {
+	item, parsed := parseTodoTxtLine("+Home fix the sink due:2025-03-05 soon")
+
+	moved := item
+	moved.dueDate = 0
+	if got := formatTodoTxtLine(moved, "Work", parsed); got != "+Work fix the sink soon" {
+		t.Errorf("expected the tags replaced in place, got %q", got)
+	}
+
+	bumped := item
+	bumped.priority = PriorityHigh
+	bumped.todo = "fix the sink today"
+	if got := formatTodoTxtLine(bumped, "Home", parsed); got != "(A) fix the sink today +Home due:2025-03-05" {
+		t.Errorf("expected a new priority and the tags after new text, got %q", got)
+	}
+
+	if got := formatTodoTxtLine(todoItem{todo: "New task", priority: PriorityMed}, DefaultListName, todoTxtLine{}); got != "(C) New task" {
+		t.Errorf("expected new tasks to get a priority, got %q", got)
+	}
+}
+
+func TestTodoTxtStore_CompletingMovesToDone(t *testing.T) {
+	dir := t.TempDir()
+	todoPath := filepath.Join(dir, "todo.txt")
+	if err := os.WriteFile(todoPath, []byte("(A) Write tests +Dev\n(C) Buy milk\n"), 0644); err != nil {
+		t.Fatalf("write todo.txt: %v", err)
+	}
+
+	store, err := NewTodoTxtStore(todoPath, "")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+
+	lists, _ := store.GetTodoLists(t.Context())
+	if len(lists) != 2 || lists[0].name != "Dev" || lists[1].name != DefaultListName {
+		t.Fatalf("unexpected lists: %+v", lists)
+	}
+
+	items, _ := store.GetItems(t.Context())
+	items[0].done = true
+	items[0].dateCompleted = time.Date(2025, 3, 2, 12, 0, 0, 0, time.Local).Unix()
+	if err := store.UpdateItem(t.Context(), items[0]); err != nil {
+		t.Fatalf("update item: %v", err)
+	}
+
+	todo, _ := os.ReadFile(todoPath)
+	done, _ := os.ReadFile(filepath.Join(dir, "done.txt"))
+	if strings.Contains(string(todo), "Write tests") {
+		t.Errorf("completed task should leave todo.txt, got %q", todo)
+	}
+	if !strings.HasPrefix(string(done), "x 2025-03-02 Write tests +Dev") {
+		t.Errorf("expected completed task in done.txt, got %q", done)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mergestat/timediff"
+)
+
+// trashEntry is one row of the trash view: either a deleted list, together
+// with the tasks deleted along with it, or a single deleted task
+type trashEntry struct {
+	isList   bool
+	list     todoList
+	item     todoItem
+	tasks    []todoItem // Tasks deleted with the list
+	listName string     // Name of a task's list, empty if it is gone
+}
+
+func (e trashEntry) deletedAt() int64 {
+	if e.isList {
+		return e.list.deletedAt
+	}
+	return e.item.deletedAt
+}
+
+// buildTrashEntries folds tasks deleted together with their list into the
+// list's entry and orders everything newest first
+func buildTrashEntries(lists []todoList, items []todoItem, liveLists []todoList) []trashEntry {
+	listNames := map[int]string{}
+	for _, list := range liveLists {
+		listNames[list.id] = list.name
+	}
+
+	listEntries := map[int]int{}
+	entries := make([]trashEntry, 0, len(lists)+len(items))
+	for _, list := range lists {
+		listEntries[list.id] = len(entries)
+		entries = append(entries, trashEntry{isList: true, list: list})
+	}
+
+	for _, item := range items {
+		if i, ok := listEntries[item.todoListID]; ok && item.deletedAt == entries[i].list.deletedAt {
+			entries[i].tasks = append(entries[i].tasks, item)
+			continue
+		}
+		entries = append(entries, trashEntry{item: item, listName: listNames[item.todoListID]})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].deletedAt() > entries[j].deletedAt()
+	})
+	return entries
+}
+
+func (m *model) openTrash() {
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := m.loadTrash(ctx); err != nil {
+		m.errorMsg = "Failed to load trash: " + err.Error()
+		return
+	}
+	m.input.trashIndex = 0
+	m.setState(StateTrash, SubStateNone)
+}
+
+func (m *model) loadTrash(ctx context.Context) error {
+	lists, err := m.store.GetDeletedTodoLists(ctx)
+	if err != nil {
+		return err
+	}
+	items, err := m.store.GetDeletedItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Tasks from archived lists keep their list when restored
+	archived, err := m.store.GetArchivedTodoLists(ctx)
+	if err != nil {
+		return err
+	}
+	liveLists := append(append([]todoList{}, m.todoLists...), archived...)
+
+	m.trash = buildTrashEntries(lists, items, liveLists)
+	if m.input.trashIndex >= len(m.trash) && m.input.trashIndex > 0 {
+		m.input.trashIndex = len(m.trash) - 1
+	}
+	return nil
+}
+
+func (m *model) handleTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.currentSubState == SubStateTrashPurgeConfirm {
+		return m.runKeyAction(msg, m.purgeConfirmActions())
+	}
+	return m.runKeyAction(msg, m.trashActions())
+}
+
+func (m *model) trashActions() []keyAction {
+	k := m.keys
+	return []keyAction{
+		{k.Up, act(func(m *model) {
+			if m.input.trashIndex > 0 {
+				m.input.trashIndex--
+			}
+		})},
+		{k.Down, act(func(m *model) {
+			if m.input.trashIndex < len(m.trash)-1 {
+				m.input.trashIndex++
+			}
+		})},
+		{k.Restore, act((*model).restoreTrashEntry)},
+		{k.Purge, act(func(m *model) {
+			if m.input.trashIndex < len(m.trash) {
+				m.currentSubState = SubStateTrashPurgeConfirm
+			}
+		})},
+		{k.Close, act((*model).returnToMain)},
+	}
+}
+
+func (m *model) purgeConfirmActions() []keyAction {
+	keep := act(func(m *model) { m.currentSubState = SubStateNone })
+	return []keyAction{
+		{m.keys.Yes, act(func(m *model) {
+			m.purgeTrashEntry()
+			m.currentSubState = SubStateNone
+		})},
+		{m.keys.No, keep},
+		{m.keys.Cancel, keep},
+	}
+}
+
+// restoreTrashEntry brings the selected entry back. A task whose list no
+// longer exists is restored into the current list.
+func (m *model) restoreTrashEntry() {
+	if m.input.trashIndex >= len(m.trash) {
+		return
+	}
+	entry := m.trash[m.input.trashIndex]
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if entry.isList {
+		if err := m.store.RestoreTodoList(ctx, entry.list); err != nil {
+			m.errorMsg = "Failed to restore list: " + err.Error()
+			return
+		}
+		for _, item := range entry.tasks {
+			if err := m.store.RestoreItem(ctx, item); err != nil {
+				m.errorMsg = "Failed to restore task: " + err.Error()
+				break
+			}
+		}
+		m.statusMsg = "Restored list " + entry.list.name
+	} else {
+		item := entry.item
+		listName := entry.listName
+		if listName == "" {
+			item.todoListID = m.currentListID
+			listName = m.getCurrentListName()
+			if err := m.store.UpdateItem(ctx, item); err != nil {
+				m.errorMsg = "Failed to restore task: " + err.Error()
+				return
+			}
+		}
+		if err := m.store.RestoreItem(ctx, item); err != nil {
+			m.errorMsg = "Failed to restore task: " + err.Error()
+			return
+		}
+		m.statusMsg = "Restored task to " + listName
+	}
+
+	m.reloadFromStore(ctx)
+	if err := m.loadTrash(ctx); err != nil {
+		m.errorMsg = "Failed to load trash: " + err.Error()
+	}
+}
+
+// purgeTrashEntry permanently removes the selected entry
+func (m *model) purgeTrashEntry() {
+	if m.input.trashIndex >= len(m.trash) {
+		return
+	}
+	entry := m.trash[m.input.trashIndex]
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if entry.isList {
+		if err := m.store.PurgeTodoList(ctx, entry.list.id); err != nil {
+			m.errorMsg = "Failed to purge list: " + err.Error()
+			return
+		}
+	} else if err := m.store.PurgeItem(ctx, entry.item.id); err != nil {
+		m.errorMsg = "Failed to purge task: " + err.Error()
+		return
+	}
+
+	if err := m.loadTrash(ctx); err != nil {
+		m.errorMsg = "Failed to load trash: " + err.Error()
+	}
+}
+
+func (m model) renderTrash() string {
+	lines := []string{TitleStyle.Render("Trash")}
+
+	if len(m.trash) == 0 {
+		lines = append(lines, "Trash is empty.")
+	}
+
+	// Keep the cursor on screen when there are more entries than rows
+	visible := m.height - TrashChromeLines
+	if visible < 1 || visible > len(m.trash) {
+		visible = len(m.trash)
+	}
+	start := 0
+	if m.input.trashIndex >= visible {
+		start = m.input.trashIndex - visible + 1
+	}
+
+	for i := start; i < start+visible; i++ {
+		line := formatTrashEntry(m.trash[i])
+		if i == m.input.trashIndex {
+			lines = append(lines, SelectedStyle.Render("▶ "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	lines = append(lines, "")
+	if m.currentSubState == SubStateTrashPurgeConfirm && m.input.trashIndex < len(m.trash) {
+		entry := m.trash[m.input.trashIndex]
+		if entry.isList {
+			lines = append(lines, SelectedStyle.Render(fmt.Sprintf("Permanently delete list '%s' and its tasks? %s", entry.list.name, m.yesNo())))
+		} else {
+			lines = append(lines, SelectedStyle.Render("Permanently delete this task? "+m.yesNo()))
+		}
+	} else {
+		lines = append(lines, m.keyHint("", m.keys.Up, m.keys.Down, m.keys.Restore, m.keys.Purge, relabel(m.keys.Close, "back")))
+	}
+
+	if m.errorMsg != "" {
+		lines = append(lines, ErrorStyle.Render("Error: "+m.errorMsg))
+	}
+	if m.statusMsg != "" {
+		lines = append(lines, StatusStyle.Render(m.statusMsg))
+	}
+	lines = append(lines, "")
+	return strings.Join(lines, "\n")
+}
+
+func formatTrashEntry(entry trashEntry) string {
+	deleted := "deleted " + timediff.TimeDiff(time.Unix(entry.deletedAt(), 0))
+	if entry.isList {
+		return fmt.Sprintf("List: %s (%d task(s)) | %s", entry.list.name, len(entry.tasks), deleted)
+	}
+	listName := entry.listName
+	if listName == "" {
+		listName = "list deleted"
+	}
+	return fmt.Sprintf("%s (%s) | %s", entry.item.todo, listName, deleted)
+}
@@ -0,0 +1,1118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata/")
+
+// Terminal size used for every snapshot
+const (
+	testTermWidth  = 80
+	testTermHeight = 30
+)
+
+// tuiDriver feeds messages into the model the same way the bubbletea
+// runtime does and exposes the rendered view
+type tuiDriver struct {
+	t     *testing.T
+	store *MemoryStore
+	model tea.Model
+}
+
+// newTUIDriver builds a model over a MemoryStore seeded by seed
+func newTUIDriver(t *testing.T, seed func(s *MemoryStore)) *tuiDriver {
+	t.Helper()
+
+	store := NewMemoryStore()
+	if seed != nil {
+		seed(store)
+	}
+
+	m, err := loadModel(t.Context(), store)
+	if err != nil {
+		t.Fatalf("load model: %v", err)
+	}
+
+	d := &tuiDriver{t: t, store: store, model: m}
+	d.send(tea.WindowSizeMsg{Width: testTermWidth, Height: testTermHeight})
+	return d
+}
+
+// send delivers messages to Update, discarding returned commands
+func (d *tuiDriver) send(msgs ...tea.Msg) {
+	for _, msg := range msgs {
+		d.model, _ = d.model.Update(msg)
+	}
+}
+
+// press sends key presses by name ("enter", "esc", "up", "ctrl+c") or as
+// literal characters ("a", "y")
+func (d *tuiDriver) press(keys ...string) {
+	for _, key := range keys {
+		d.send(keyMsg(key))
+	}
+}
+
+// pressAndRun sends key presses and runs the command each one returns,
+// delivering its message, for keys that start work outside Update
+func (d *tuiDriver) pressAndRun(keys ...string) {
+	for _, key := range keys {
+		var cmd tea.Cmd
+		d.model, cmd = d.model.Update(keyMsg(key))
+		if cmd == nil {
+			continue
+		}
+		if msg := cmd(); msg != nil {
+			d.send(msg)
+		}
+	}
+}
+
+// typeText sends each rune of text as a separate key press
+func (d *tuiDriver) typeText(text string) {
+	for _, r := range text {
+		d.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func (d *tuiDriver) view() string {
+	return d.model.View()
+}
+
+// state returns the current model regardless of whether Update returned
+// a value or a pointer
+func (d *tuiDriver) state() *model {
+	switch m := d.model.(type) {
+	case model:
+		return &m
+	case *model:
+		return m
+	}
+	d.t.Fatalf("unexpected model type %T", d.model)
+	return nil
+}
+
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "backspace":
+		return tea.KeyMsg{Type: tea.KeyBackspace}
+	case "ctrl+c":
+		return tea.KeyMsg{Type: tea.KeyCtrlC}
+	case "ctrl+r":
+		return tea.KeyMsg{Type: tea.KeyCtrlR}
+	case "ctrl+p":
+		return tea.KeyMsg{Type: tea.KeyCtrlP}
+	case " ", "space":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// assertGolden compares got with testdata/<name>.golden. Run
+// `go test -run TestTUI -update` to rewrite the files after an intended
+// change to the UI.
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatalf("create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if string(want) != got {
+		t.Errorf("view does not match %s\n--- want ---\n%s\n--- got ---\n%s", path, want, got)
+	}
+}
+
+// seedBasic creates two lists with a few tasks whose rendered timestamps
+// do not depend on when the test runs
+func seedBasic(s *MemoryStore) {
+	ctx := context.Background()
+	work, _ := s.CreateTodoList(ctx, "Work")
+	home, _ := s.CreateTodoList(ctx, "Home")
+
+	added := time.Now().AddDate(0, 0, -105).Unix()
+	s.SaveItem(ctx, todoItem{todo: "Write report", priority: PriorityHigh, dateAdded: added, todoListID: work})
+	s.SaveItem(ctx, todoItem{todo: "Review pull requests", priority: PriorityMed, dateAdded: added, todoListID: work})
+	s.SaveItem(ctx, todoItem{todo: "Water plants", priority: PriorityLow, dateAdded: added, todoListID: home})
+}
+
+func TestTUI_InitialView(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+	assertGolden(t, "initial_view", d.view())
+}
+
+func TestTUI_AddTaskWithPriorityAndDueDate(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("a")
+	d.typeText("Book flights")
+	assertGolden(t, "add_task_text", d.view())
+
+	d.press("enter", "1")
+	assertGolden(t, "add_task_priority", d.view())
+
+	d.press("enter")
+	d.typeText("3")
+	assertGolden(t, "add_task_due_date", d.view())
+
+	d.press("enter")
+	assertGolden(t, "add_task_done", d.view())
+
+	items, _ := d.store.GetItems(t.Context())
+	var saved *todoItem
+	for i := range items {
+		if items[i].todo == "Book flights" {
+			saved = &items[i]
+		}
+	}
+	if saved == nil {
+		t.Fatal("expected new task to be saved to the store")
+	}
+	if saved.priority != PriorityHigh {
+		t.Errorf("expected priority %d, got %d", PriorityHigh, saved.priority)
+	}
+	if saved.dueDate == 0 {
+		t.Error("expected due date to be set")
+	}
+}
+
+func TestTUI_RenameList(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("l", "j")
+	assertGolden(t, "list_selector", d.view())
+
+	d.press("m")
+	assertGolden(t, "list_manage", d.view())
+
+	d.press("r")
+	for range "Home" {
+		d.press("backspace")
+	}
+	d.typeText("Household")
+	d.press("enter")
+
+	lists, _ := d.store.GetTodoLists(t.Context())
+	if lists[1].name != "Household" {
+		t.Errorf("expected list renamed in store, got %q", lists[1].name)
+	}
+
+	d.press("l")
+	assertGolden(t, "list_renamed", d.view())
+}
+
+func TestTUI_DeleteConfirm(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("d")
+	assertGolden(t, "delete_confirm", d.view())
+
+	d.press("n")
+	if got := len(d.state().items); got != 3 {
+		t.Fatalf("expected cancel to keep all tasks, got %d", got)
+	}
+
+	d.press("d", "y")
+	assertGolden(t, "delete_done", d.view())
+
+	items, _ := d.store.GetItems(t.Context())
+	for _, item := range items {
+		if item.todo == "Write report" {
+			t.Error("expected deleted task to be gone from the store")
+		}
+	}
+}
+
+func TestTUI_ToggleDone(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press(" ")
+
+	view := d.view()
+	if !strings.Contains(view, "completed") {
+		t.Errorf("expected completed timestamp in view, got:\n%s", view)
+	}
+
+	items, _ := d.store.GetItems(t.Context())
+	for _, item := range items {
+		if item.todo == "Write report" && !item.done {
+			t.Error("expected task to be marked done in the store")
+		}
+	}
+}
+
+func TestTUI_UndoRedoDelete(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("d", "y")
+	d.press("u")
+	assertGolden(t, "undo_delete", d.view())
+
+	items, _ := d.store.GetItems(t.Context())
+	if len(items) != 3 {
+		t.Fatalf("expected undo to restore the task in the store, got %d items", len(items))
+	}
+
+	d.press("ctrl+r")
+	items, _ = d.store.GetItems(t.Context())
+	if len(items) != 2 {
+		t.Fatalf("expected redo to delete the task again, got %d items", len(items))
+	}
+	if got := len(d.state().items); got != 2 {
+		t.Errorf("expected model to reload after redo, got %d items", got)
+	}
+}
+
+func TestTUI_UndoListDeleteAndEdit(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("e")
+	d.typeText(" today")
+	d.press("enter")
+
+	d.press("l", "m", "d", "y")
+	if lists, _ := d.store.GetTodoLists(t.Context()); len(lists) != 1 {
+		t.Fatalf("expected list to be deleted, got %d lists", len(lists))
+	}
+
+	d.press("u")
+	lists, _ := d.store.GetTodoLists(t.Context())
+	if len(lists) != 2 {
+		t.Fatalf("expected undo to restore the list, got %d lists", len(lists))
+	}
+	items, _ := d.store.GetItems(t.Context())
+	if len(items) != 3 {
+		t.Fatalf("expected undo to restore the list's tasks, got %d items", len(items))
+	}
+
+	d.press("u")
+	item, _ := d.store.GetItemByID(t.Context(), 1)
+	if item.todo != "Write report" {
+		t.Errorf("expected edit to be undone, got %q", item.todo)
+	}
+
+	d.press("u")
+	if !strings.Contains(d.view(), "Nothing to undo") {
+		t.Errorf("expected empty history message, got:\n%s", d.view())
+	}
+}
+
+func TestTUI_TrashRestoreAndPurge(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("d", "y")
+	d.press("l", "j", "m", "d", "y")
+	d.press("x")
+	assertGolden(t, "trash_view", d.view())
+
+	// Restoring the list brings back its task too
+	d.press("r")
+	lists, _ := d.store.GetTodoLists(t.Context())
+	if len(lists) != 2 {
+		t.Fatalf("expected list restored, got %d lists", len(lists))
+	}
+	if got := len(d.state().trash); got != 1 {
+		t.Fatalf("expected one entry left in trash, got %d", got)
+	}
+
+	d.press("p")
+	assertGolden(t, "trash_purge_confirm", d.view())
+	d.press("y")
+
+	deleted, _ := d.store.GetDeletedItems(t.Context())
+	if len(deleted) != 0 {
+		t.Errorf("expected purged task gone from the store, got %d", len(deleted))
+	}
+	if !strings.Contains(d.view(), "Trash is empty.") {
+		t.Errorf("expected empty trash, got:\n%s", d.view())
+	}
+
+	d.press("esc")
+	items, _ := d.store.GetItems(t.Context())
+	if got := len(items); got != 2 {
+		t.Errorf("expected 2 live tasks, got %d", got)
+	}
+}
+
+func TestTUI_ArchivedLists(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("l", "j", "m", "a")
+	if lists, _ := d.store.GetTodoLists(t.Context()); len(lists) != 1 {
+		t.Fatalf("expected list to be archived, got %d live lists", len(lists))
+	}
+
+	d.press("v", "j")
+	assertGolden(t, "archived_lists", d.view())
+
+	d.press("enter")
+	assertGolden(t, "archived_view", d.view())
+
+	// Task actions are ignored while browsing an archived list
+	d.press("d", "y", " ")
+	items, _ := d.store.GetItems(t.Context())
+	if len(items) != 3 || items[2].done {
+		t.Fatalf("expected archived list to be read-only, got %+v", items)
+	}
+
+	d.press("a")
+	if lists, _ := d.store.GetTodoLists(t.Context()); len(lists) != 2 {
+		t.Fatalf("expected list to be unarchived, got %d live lists", len(lists))
+	}
+	m := d.state()
+	if m.currentState != StateMainBrowse || m.getCurrentListName() != "Home" {
+		t.Errorf("expected to land on the unarchived list, got %q in state %d", m.getCurrentListName(), m.currentState)
+	}
+}
+
+func TestTUI_MoveAndCopyTask(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("m")
+	assertGolden(t, "move_picker", d.view())
+
+	d.press("j", "enter")
+	item, _ := d.store.GetItemByID(t.Context(), 1)
+	if item.todoListID != 2 {
+		t.Fatalf("expected task moved to Home, got list %d", item.todoListID)
+	}
+	if got := d.state().getVisibleItemCount(); got != 1 {
+		t.Errorf("expected one task left in Work, got %d", got)
+	}
+
+	d.press("u")
+	if item, _ = d.store.GetItemByID(t.Context(), 1); item.todoListID != 1 {
+		t.Fatalf("expected undo to move the task back, got list %d", item.todoListID)
+	}
+
+	d.press("c", "j", "enter")
+	items, _ := d.store.GetItems(t.Context())
+	if len(items) != 4 {
+		t.Fatalf("expected copy to add a task, got %d items", len(items))
+	}
+	if items[3].todo != "Write report" || items[3].todoListID != 2 {
+		t.Errorf("expected copy in Home, got %q in list %d", items[3].todo, items[3].todoListID)
+	}
+	if item, _ = d.store.GetItemByID(t.Context(), 1); item.todoListID != 1 {
+		t.Errorf("expected original to stay in Work, got list %d", item.todoListID)
+	}
+}
+
+func TestTUI_MoveMarkedTasks(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("v", "j", "v", "m", "j", "enter")
+	items, _ := d.store.GetItems(t.Context())
+	for _, item := range items {
+		if item.todoListID != 2 {
+			t.Fatalf("expected every marked task moved to Home, got %q in list %d", item.todo, item.todoListID)
+		}
+	}
+	if len(d.state().marked) != 0 {
+		t.Error("expected marks cleared after the move")
+	}
+	if cursor := d.state().cursor; cursor != 0 {
+		t.Errorf("expected the cursor back on the emptied list, got %d", cursor)
+	}
+
+	// Both tasks moved in one action, so a single undo brings them back
+	d.press("u")
+	for _, id := range []int{1, 2} {
+		if item, _ := d.store.GetItemByID(t.Context(), id); item.todoListID != 1 {
+			t.Errorf("expected undo to move task %d back, got list %d", id, item.todoListID)
+		}
+	}
+}
+
+func TestTUI_BulkActions(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("J")
+	assertGolden(t, "bulk_marked", d.view())
+
+	d.press("2", "enter")
+	items, _ := d.store.GetItems(t.Context())
+	for _, item := range items[:2] {
+		if item.priority != PriorityMedHigh || !item.done {
+			t.Fatalf("expected marked tasks reprioritized and completed, got %+v", item)
+		}
+	}
+
+	// Completing was one action, so a single undo reopens both tasks
+	d.press("u")
+	items, _ = d.store.GetItems(t.Context())
+	if items[0].done || items[1].done || items[0].priority != PriorityMedHigh {
+		t.Fatalf("expected undo to reopen both tasks only, got %+v", items[:2])
+	}
+
+	d.press("t")
+	d.typeText("3")
+	d.press("enter")
+	items, _ = d.store.GetItems(t.Context())
+	if items[0].dueDate == 0 || items[1].dueDate == 0 || items[2].dueDate != 0 {
+		t.Fatalf("expected due date on marked tasks only, got %+v", items)
+	}
+
+	d.press("d", "y")
+	if items, _ = d.store.GetItems(t.Context()); len(items) != 1 {
+		t.Fatalf("expected marked tasks deleted, got %d items", len(items))
+	}
+	if len(d.state().marked) != 0 {
+		t.Error("expected marks cleared after delete")
+	}
+
+	d.press("u")
+	if items, _ = d.store.GetItems(t.Context()); len(items) != 3 {
+		t.Errorf("expected undo to restore both tasks, got %d items", len(items))
+	}
+}
+
+func TestTUI_EditDueDate(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("t")
+	d.typeText("3")
+	d.press("enter")
+
+	item, _ := d.store.GetItemByID(t.Context(), 1)
+	if item.dueDate == 0 {
+		t.Fatal("expected due date to be set on the task")
+	}
+	if m := d.state(); m.currentState != StateMainBrowse {
+		t.Errorf("expected to return to the main view, got state %d", m.currentState)
+	}
+}
+
+func TestTUI_ManualOrder(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("o", "j", "K")
+	visible := d.state().filterItemsByList(1)
+	if visible[0].todo != "Review pull requests" {
+		t.Fatalf("expected task moved to the top, got %q first", visible[0].todo)
+	}
+
+	// Manual order is stored, so it survives a reload
+	m, err := loadModel(t.Context(), d.store)
+	if err != nil {
+		t.Fatalf("reload model: %v", err)
+	}
+	if got := m.filterItemsByList(1)[0].todo; got != "Review pull requests" {
+		t.Errorf("expected manual order after reload, got %q first", got)
+	}
+
+	d.press("u")
+	if got := d.state().filterItemsByList(1)[0].todo; got != "Write report" {
+		t.Errorf("expected undo to restore the order, got %q first", got)
+	}
+}
+
+func TestTUI_ManualOrderWithHiddenTasks(t *testing.T) {
+	d := newTUIDriver(t, func(s *MemoryStore) {
+		ctx := context.Background()
+		work, _ := s.CreateTodoList(ctx, "Work")
+		s.SaveItem(ctx, todoItem{todo: "Write report", priority: PriorityHigh, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Send invoice", priority: PriorityHigh, done: true, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Review pull requests", priority: PriorityHigh, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Plan offsite", priority: PriorityLow, todoListID: work})
+	})
+
+	d.press("o", "h", "J")
+	items, _ := d.store.GetItems(t.Context())
+	positions := map[int]string{}
+	for _, item := range items {
+		if other, taken := positions[item.position]; taken {
+			t.Fatalf("expected every task renumbered, %q and %q share position %d", other, item.todo, item.position)
+		}
+		positions[item.position] = item.todo
+	}
+	if got := d.state().filterItemsByList(1)[1].todo; got != "Write report" {
+		t.Errorf("expected the task moved past its visible neighbour, got %q second", got)
+	}
+
+	// A move never crosses a group header
+	d.press("g", "j", "J")
+	var got []string
+	for _, item := range d.state().filterItemsByList(1) {
+		got = append(got, item.todo)
+	}
+	want := []string{"Review pull requests", "Write report", "Plan offsite"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected the order kept at the group boundary %v, got %v", want, got)
+	}
+	after, _ := d.store.GetItems(t.Context())
+	for i := range after {
+		if after[i].position != items[i].position {
+			t.Errorf("expected %q to keep position %d, got %d", after[i].todo, items[i].position, after[i].position)
+		}
+	}
+}
+
+func TestTUI_SortAndGroup(t *testing.T) {
+	d := newTUIDriver(t, func(s *MemoryStore) {
+		ctx := context.Background()
+		work, _ := s.CreateTodoList(ctx, "Work")
+		added := time.Now().AddDate(0, 0, -105).Unix()
+		s.SaveItem(ctx, todoItem{todo: "Write report #docs", priority: PriorityLow, dateAdded: added, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Fix login bug #code", priority: PriorityHigh, dateAdded: added, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Answer email", priority: PriorityMed, dateAdded: added, todoListID: work})
+	})
+
+	d.press("g", "g", "g")
+	assertGolden(t, "grouped_by_tag", d.view())
+
+	var got []string
+	for _, item := range d.state().filterItemsByList(1) {
+		got = append(got, item.todo)
+	}
+	want := []string{"Fix login bug #code", "Write report #docs", "Answer email"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected tag groups in order %v, got %v", want, got)
+	}
+
+	// Sort cycles priority, manual, due date, created, completed, alphabetical
+	d.press("o", "o", "o", "o", "o")
+	if m := d.state(); m.statusMsg != "Sort: alphabetical" {
+		t.Errorf("expected alphabetical sort, got status %q", m.statusMsg)
+	}
+
+	m, err := loadModel(t.Context(), d.store)
+	if err != nil {
+		t.Fatalf("reload model: %v", err)
+	}
+	if sortMode, groupMode := m.listView(1); sortMode != SortModeAlpha || groupMode != GroupModeTag {
+		t.Errorf("expected alpha/tag after reload, got %q/%q", sortMode, groupMode)
+	}
+}
+
+func TestTUI_CompletedFilter(t *testing.T) {
+	d := newTUIDriver(t, func(s *MemoryStore) {
+		ctx := context.Background()
+		work, _ := s.CreateTodoList(ctx, "Work")
+		added := time.Now().AddDate(0, 0, -105).Unix()
+		s.SaveItem(ctx, todoItem{todo: "Open task", priority: PriorityHigh, dateAdded: added, todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Done today", priority: PriorityMed, dateAdded: added, done: true, dateCompleted: now(), todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Done last week", priority: PriorityLow, dateAdded: added, done: true, dateCompleted: time.Now().AddDate(0, 0, -6).Unix(), todoListID: work})
+	})
+
+	visible := func() []string {
+		var todos []string
+		for _, item := range d.state().filterItemsByList(1) {
+			todos = append(todos, item.todo)
+		}
+		return todos
+	}
+
+	d.press("h")
+	if got := visible(); len(got) != 1 || got[0] != "Open task" {
+		t.Errorf("expected only the open task, got %v", got)
+	}
+
+	d.press("h")
+	if got := visible(); len(got) != 1 || got[0] != "Done today" {
+		t.Errorf("expected only today's completion, got %v", got)
+	}
+
+	d.press("h")
+	if got := visible(); len(got) != 3 {
+		t.Errorf("expected all tasks again, got %v", got)
+	}
+}
+
+func TestTUI_TaskDetail(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("enter")
+	view := d.view()
+	for _, want := range []string{"Task details", "Text:      Write report", "List:      Work", "Sync:      sync off"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in detail view:\n%s", want, view)
+		}
+	}
+
+	// Priority steps to the next level; list moves to the next list
+	d.press("j", "enter", "j", "j", "enter")
+	item, _ := d.store.GetItemByID(t.Context(), 1)
+	if item.priority != PriorityMedHigh || item.todoListID != 2 {
+		t.Fatalf("expected priority 2 in Home, got priority %d in list %d", item.priority, item.todoListID)
+	}
+
+	d.press("k", "k", "k", "enter")
+	d.typeText(" today")
+	d.press("enter")
+	if item, _ = d.store.GetItemByID(t.Context(), 1); item.todo != "Write report today" {
+		t.Errorf("expected text edited in place, got %q", item.todo)
+	}
+	if m := d.state(); m.currentState != StateTaskDetail {
+		t.Errorf("expected to stay in the detail view, got state %d", m.currentState)
+	}
+
+	d.press("esc")
+	if m := d.state(); m.currentState != StateMainBrowse {
+		t.Errorf("expected to return to the main view, got state %d", m.currentState)
+	}
+	d.press("u")
+	if item, _ = d.store.GetItemByID(t.Context(), 1); item.todo != "Write report" {
+		t.Errorf("expected undo to restore the text, got %q", item.todo)
+	}
+}
+
+func TestTUI_TaskDetailSyncState(t *testing.T) {
+	ctx := t.Context()
+	transport, err := NewFileTransport(t.TempDir(), "device-a", nil)
+	if err != nil {
+		t.Fatalf("create transport: %v", err)
+	}
+	store := NewSyncStore(openTestStore(t, "todo.db"), transport, SyncConfig{})
+	listID, _ := store.CreateTodoList(ctx, "Work")
+	store.SaveItem(ctx, todoItem{todo: "Write report", todoListID: listID})
+
+	m, err := loadModel(ctx, store)
+	if err != nil {
+		t.Fatalf("load model: %v", err)
+	}
+	m.syncEnabled = true
+	d := &tuiDriver{t: t, model: m}
+	d.send(tea.WindowSizeMsg{Width: testTermWidth, Height: testTermHeight})
+
+	d.press("enter")
+	if view := d.view(); !strings.Contains(view, "Sync:      changes pending") {
+		t.Errorf("expected the new task to be pending:\n%s", view)
+	}
+
+	d.press("esc")
+	if err := store.FullSync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	d.press("enter")
+	if view := d.view(); !strings.Contains(view, "Sync:      synced") {
+		t.Errorf("expected the task to read synced after a sync:\n%s", view)
+	}
+}
+
+func TestTUI_DetailPane(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.send(tea.WindowSizeMsg{Width: DetailPaneMinWidth, Height: testTermHeight})
+	lines := strings.Split(d.view(), "\n")
+	if !strings.Contains(lines[2], "Write report") || !strings.Contains(lines[2], "Text: Write report") {
+		t.Errorf("expected the task and its details side by side, got %q", lines[2])
+	}
+
+	d.press("j")
+	if view := d.view(); !strings.Contains(view, "Text: Review pull requests") {
+		t.Errorf("expected the pane to follow the cursor:\n%s", view)
+	}
+}
+
+func TestTUI_EditPriority(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	// Review pull requests starts at medium and moves above Write report
+	d.press("j", "p", "1", "enter")
+	item, _ := d.store.GetItemByID(t.Context(), 2)
+	if item.priority != PriorityHigh {
+		t.Fatalf("expected high priority, got %d", item.priority)
+	}
+	if m := d.state(); m.currentState != StateMainBrowse || m.filterItemsByList(1)[m.cursor].id != 2 {
+		t.Errorf("expected the cursor to follow the task back in the main view")
+	}
+
+	d.press("-", "-")
+	if item, _ = d.store.GetItemByID(t.Context(), 2); item.priority != PriorityMed {
+		t.Errorf("expected two bumps down to medium, got %d", item.priority)
+	}
+
+	d.press("u")
+	if item, _ = d.store.GetItemByID(t.Context(), 2); item.priority != PriorityMedHigh {
+		t.Errorf("expected undo to revert one bump, got %d", item.priority)
+	}
+
+	d.press("p", "esc")
+	if m := d.state(); m.currentState != StateMainBrowse {
+		t.Errorf("expected esc to cancel the priority picker, got state %d", m.currentState)
+	}
+}
+
+func TestTUI_ReorderLists(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("l", "j", "K")
+	lists, _ := d.store.GetTodoLists(t.Context())
+	if lists[0].name != "Home" {
+		t.Fatalf("expected Home first, got %q", lists[0].name)
+	}
+	if m := d.state(); m.getCurrentListName() != "Work" {
+		t.Errorf("expected current list to stay Work, got %q", m.getCurrentListName())
+	}
+}
+
+func TestTUI_ProfilePicker(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Setenv(configPathEnvVar, writeConfigFile(t, `
+db_path = "`+filepath.Join(dir, "default.db")+`"
+
+[profiles.home]
+db_path = "`+filepath.Join(dir, "home.db")+`"
+
+[profiles.work]
+db_path = "`+filepath.Join(dir, "work.db")+`"
+`))
+	cfg, _, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	d := newTUIDriver(t, seedBasic)
+	m := d.state()
+	m.applyConfig(cfg)
+	d.model = *m
+	t.Cleanup(func() { d.state().closeStore() })
+
+	d.press("P")
+	view := d.view()
+	for _, want := range []string{"Switch profile:", "default (current)", "home", "work"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the profile picker:\n%s", want, view)
+		}
+	}
+
+	d.press("j", "j")
+	d.pressAndRun("enter")
+	state := d.state()
+	if state.cfg.Profile != "work" || state.currentState != StateMainBrowse {
+		t.Fatalf("expected the work profile, got %q in state %d", state.cfg.Profile, state.currentState)
+	}
+	if len(state.items) != 0 || state.getCurrentListName() != DefaultListName {
+		t.Errorf("expected the empty work database, got %d tasks in %q", len(state.items), state.getCurrentListName())
+	}
+	if !strings.Contains(d.view(), "work · ") {
+		t.Errorf("expected the profile in the title:\n%s", d.view())
+	}
+
+	d.press("a")
+	d.typeText("Work task")
+	d.press("enter", "enter", "enter")
+	d.press("P", "k")
+	d.pressAndRun("enter")
+	if state = d.state(); state.cfg.Profile != "home" || len(state.items) != 0 {
+		t.Fatalf("expected the empty home profile, got %q with %d tasks", state.cfg.Profile, len(state.items))
+	}
+
+	d.press("P", "j")
+	d.pressAndRun("enter")
+	if state = d.state(); len(state.items) != 1 || state.items[0].todo != "Work task" {
+		t.Errorf("expected the work task to be kept in its database, got %v", state.items)
+	}
+}
+
+func TestTUI_KeybindingsAndHelp(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+	m := d.state()
+	m.applyConfig(Config{Keymap: KeymapDefault, Keybindings: map[string]string{"add": "n"}, DefaultPriority: DefaultPriority, Theme: ThemeDefault})
+	d.model = *m
+
+	if !strings.Contains(d.view(), "n add") {
+		t.Errorf("expected the footer to follow the keymap:\n%s", d.view())
+	}
+
+	d.press("?")
+	assertGolden(t, "help_overlay", d.view())
+	d.press("a")
+	if !d.state().showHelp {
+		t.Fatal("expected other keys to leave the help open")
+	}
+	d.press("esc")
+	if d.state().showHelp {
+		t.Fatal("expected esc to close the help")
+	}
+
+	d.press("a")
+	if d.state().currentState != StateMainBrowse {
+		t.Fatalf("expected a to do nothing once add moved to n, got state %d", d.state().currentState)
+	}
+	d.press("n")
+	d.typeText("Why?")
+	d.press("enter", "enter", "enter")
+	if items, _ := d.store.GetItems(t.Context()); items[len(items)-1].todo != "Why?" {
+		t.Errorf("expected ? to be typed in the task text, got %q", items[len(items)-1].todo)
+	}
+
+	d.press("l", "?")
+	if view := d.view(); !strings.Contains(view, "new list") || strings.Contains(view, "due date") {
+		t.Errorf("expected the list selector keys in its help:\n%s", view)
+	}
+}
+
+func TestTUI_Mouse(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+	click := func(x, y int) tea.MouseMsg {
+		return tea.MouseMsg{X: x, Y: y, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress}
+	}
+
+	// Rows 0-1 hold the title, then each task takes three lines
+	d.send(click(10, 5))
+	if d.state().cursor != 1 {
+		t.Fatalf("expected a click on the second task to select it, got cursor %d", d.state().cursor)
+	}
+	if item, _ := d.store.GetItemByID(t.Context(), 2); item.done {
+		t.Fatal("expected a click on the text not to complete the task")
+	}
+
+	d.send(click(1, 2))
+	if d.state().cursor != 0 {
+		t.Fatalf("expected the first task selected, got cursor %d", d.state().cursor)
+	}
+	if item, _ := d.store.GetItemByID(t.Context(), 1); !item.done {
+		t.Fatal("expected a click on the checkbox to complete the task")
+	}
+
+	d.send(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	if d.state().cursor != 0 {
+		t.Errorf("expected the wheel to leave the selection alone, got cursor %d", d.state().cursor)
+	}
+	d.press("j")
+	d.send(click(10, 20))
+	if d.state().cursor != 1 {
+		t.Errorf("expected a click below the tasks to change nothing, got cursor %d", d.state().cursor)
+	}
+
+	// The list selector starts below the viewport and a blank line, with
+	// its title taking two rows
+	d.press("l")
+	top := 2 + d.state().viewport.Height + 1 + 2
+	d.send(click(3, top+1))
+	if d.state().currentState != StateMainBrowse || d.state().getCurrentListName() != "Home" {
+		t.Errorf("expected a click on Home to open it, got %q in state %d", d.state().getCurrentListName(), d.state().currentState)
+	}
+}
+
+func TestTUI_MouseWheelScrolls(t *testing.T) {
+	d := newTUIDriver(t, func(s *MemoryStore) {
+		ctx := context.Background()
+		work, _ := s.CreateTodoList(ctx, "Work")
+		for i := range 20 {
+			s.SaveItem(ctx, todoItem{todo: fmt.Sprintf("Task %02d", i), priority: PriorityMed, todoListID: work})
+		}
+	})
+	wheel := func(button tea.MouseButton) {
+		d.send(tea.MouseMsg{Button: button, Action: tea.MouseActionPress})
+	}
+
+	for range 3 {
+		wheel(tea.MouseButtonWheelDown)
+	}
+	view := d.view()
+	if d.state().cursor != 0 {
+		t.Fatalf("expected the wheel to leave the selection alone, got cursor %d", d.state().cursor)
+	}
+	if strings.Contains(view, "Task 00") || !strings.Contains(view, "Task 03") {
+		t.Fatalf("expected the wheel to scroll three tasks down:\n%s", view)
+	}
+
+	// A click lands on the task shown under the pointer
+	d.send(tea.MouseMsg{X: 10, Y: 2, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+	if d.state().cursor != 3 {
+		t.Errorf("expected a click on the top task to select Task 03, got cursor %d", d.state().cursor)
+	}
+
+	wheel(tea.MouseButtonWheelUp)
+	if view := d.view(); !strings.Contains(view, "Task 02") {
+		t.Errorf("expected the wheel to scroll back up:\n%s", view)
+	}
+
+	// A key press brings the selection back into view
+	d.press("g")
+	if view := d.view(); !strings.Contains(view, "Task 00") {
+		t.Errorf("expected a key press to follow the selection again:\n%s", view)
+	}
+}
+
+func TestTUI_CommandPalette(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("ctrl+p")
+	assertGolden(t, "command_palette", d.view())
+
+	d.typeText("add")
+	d.press("enter")
+	if d.state().palette.open || d.state().currentState != StateTaskInput {
+		t.Fatalf("expected the palette to start adding a task, got state %d", d.state().currentState)
+	}
+	d.press("esc")
+
+	d.press("ctrl+p")
+	d.typeText("home")
+	if view := d.view(); !strings.Contains(view, "switch to list Home") || strings.Contains(view, "trash") {
+		t.Errorf("expected the query to filter the actions:\n%s", view)
+	}
+	d.press("enter")
+	if list := d.state().todoLists[d.state().currentListIndex]; list.name != "Home" {
+		t.Fatalf("expected the palette to switch to Home, got %s", list.name)
+	}
+
+	d.press("ctrl+p")
+	d.typeText("trsh")
+	d.press("enter", "ctrl+p")
+	view := d.view()
+	if !strings.Contains(view, "restore") || strings.Contains(view, "add") {
+		t.Errorf("expected the trash actions in the palette:\n%s", view)
+	}
+	d.press("esc")
+	if d.state().palette.open || d.state().currentState != StateTrash {
+		t.Fatal("expected esc to close the palette and stay in the trash")
+	}
+}
+
+func TestTUI_CommandPaletteRunsActions(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	// Redo is bound to ctrl+r, which the palette must run without a key press
+	d.press("space", "u", "ctrl+p")
+	d.typeText("redo")
+	d.press("enter")
+	if item, _ := d.store.GetItemByID(t.Context(), 1); !item.done {
+		t.Error("expected the palette to redo completing the task")
+	}
+
+	d.press("ctrl+p")
+	d.typeText("sync")
+	if view := d.view(); !strings.Contains(view, "No matching actions") {
+		t.Errorf("expected no sync action while sync is off:\n%s", view)
+	}
+	d.press("esc")
+
+	d.state().syncEnabled = true
+	d.press("ctrl+p")
+	d.typeText("sync")
+	if view := d.view(); !strings.Contains(view, "sync") || strings.Contains(view, "No matching actions") {
+		t.Errorf("expected the sync action while sync is on:\n%s", view)
+	}
+}
+
+func TestTUI_QuickAdd(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	d.press("a")
+	d.typeText("Buy soil !2 @home #garden due:12/25/2030")
+	assertGolden(t, "quick_add_preview", d.view())
+
+	d.press("enter")
+	if d.state().currentState != StateMainBrowse {
+		t.Fatalf("expected the fields to skip the priority and due date steps, got state %d", d.state().currentState)
+	}
+	items, _ := d.store.GetItems(t.Context())
+	added := items[len(items)-1]
+	if added.todo != "Buy soil #garden" || added.priority != PriorityMedHigh || added.dueDate != parseDueDate("12/25/2030") {
+		t.Errorf("unexpected task: %+v", added)
+	}
+	if list, _ := findList(d.state().todoLists, "Home"); added.todoListID != list.id {
+		t.Errorf("expected the task in Home, got list %d", added.todoListID)
+	}
+	if !strings.Contains(d.view(), "Added to Home") {
+		t.Errorf("expected a note that the task went to another list:\n%s", d.view())
+	}
+
+	d.press("a")
+	d.typeText("Call @bob !1")
+	d.press("enter")
+	items, _ = d.store.GetItems(t.Context())
+	added = items[len(items)-1]
+	if d.state().currentState != StateMainBrowse || added.todo != "Call @bob" || added.todoListID != d.state().currentListID {
+		t.Errorf("expected an @word naming no list to stay in the text of a task in the current list: %+v", added)
+	}
+}
+
+func TestTUI_Calendar(t *testing.T) {
+	d := newTUIDriver(t, func(s *MemoryStore) {
+		ctx := context.Background()
+		work, _ := s.CreateTodoList(ctx, "Work")
+		home, _ := s.CreateTodoList(ctx, "Home")
+		s.SaveItem(ctx, todoItem{todo: "Send invoice", priority: PriorityHigh, dueDate: parseDueDate("1"), todoListID: work})
+		s.SaveItem(ctx, todoItem{todo: "Call plumber", priority: PriorityMed, dueDate: parseDueDate("1"), todoListID: home})
+		s.SaveItem(ctx, todoItem{todo: "File taxes", priority: PriorityLow, dueDate: setToEndOfDay(time.Now().AddDate(0, 0, -2)).Unix(), todoListID: work})
+	})
+	tomorrow := time.Now().AddDate(0, 0, 1)
+
+	d.press("C")
+	view := d.view()
+	if d.state().currentState != StateCalendar || !strings.Contains(view, time.Now().Format("January 2006")) {
+		t.Fatalf("expected the calendar of this month:\n%s", view)
+	}
+	if !strings.Contains(view, "1 overdue") || !strings.Contains(view, "(today)") || !strings.Contains(view, "No tasks due") {
+		t.Errorf("expected the overdue count and an empty today:\n%s", view)
+	}
+
+	d.press("l")
+	view = d.view()
+	if !strings.Contains(view, tomorrow.Format(DateHeadingFormat)) || !strings.Contains(view, "Send invoice · Work") || !strings.Contains(view, "Call plumber · Home") {
+		t.Errorf("expected tomorrow's tasks from every list:\n%s", view)
+	}
+
+	d.press("tab", "m", "l", "enter")
+	item, _ := d.store.GetItemByID(t.Context(), 2)
+	if want := setToEndOfDay(tomorrow.AddDate(0, 0, 1)).Unix(); item.dueDate != want {
+		t.Errorf("expected the plumber call moved a day later, got due %v", time.Unix(item.dueDate, 0))
+	}
+	if !strings.Contains(d.view(), "Call plumber · Home") {
+		t.Errorf("expected the moved task on the selected day:\n%s", d.view())
+	}
+
+	d.press("w")
+	if view := d.view(); !strings.Contains(view, "Week of") || !strings.Contains(view, "Send invo") {
+		t.Errorf("expected the week layout with its tasks:\n%s", view)
+	}
+
+	d.press("esc", "u")
+	if d.state().currentState != StateMainBrowse {
+		t.Fatalf("expected esc to leave the calendar, got state %d", d.state().currentState)
+	}
+	if item, _ := d.store.GetItemByID(t.Context(), 2); item.dueDate != parseDueDate("1") {
+		t.Errorf("expected undo to restore the due date, got %v", time.Unix(item.dueDate, 0))
+	}
+}
+
+func TestTUI_CopyKeepsCompletion(t *testing.T) {
+	d := newTUIDriver(t, seedBasic)
+
+	// The completed task sorts below the open one
+	d.press(" ", "j", "c", "j", "enter")
+	original, _ := d.store.GetItemByID(t.Context(), 1)
+	copied, err := d.store.GetItemByID(t.Context(), 4)
+	if err != nil {
+		t.Fatalf("expected a copy to be added: %v", err)
+	}
+	if copied.todo != original.todo || copied.todoListID != 2 || !copied.done || copied.dateCompleted != original.dateCompleted {
+		t.Errorf("expected a completed copy in Home, got %+v", copied)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+)
+
+// undoCommand is a reversible store mutation. Both directions go through
+// the DataStore so sync change logging sees them like any other edit.
+type undoCommand struct {
+	description string
+	undo        func(ctx context.Context, store DataStore) error
+	redo        func(ctx context.Context, store DataStore) error
+}
+
+// UndoHistory holds the undo and redo stacks
+type UndoHistory struct {
+	undoStack []undoCommand
+	redoStack []undoCommand
+}
+
+// push records a new command, dropping the oldest past MaxUndoHistory and
+// clearing anything that could have been redone
+func (h *UndoHistory) push(cmd undoCommand) {
+	h.undoStack = append(h.undoStack, cmd)
+	if len(h.undoStack) > MaxUndoHistory {
+		h.undoStack = h.undoStack[len(h.undoStack)-MaxUndoHistory:]
+	}
+	h.redoStack = nil
+}
+
+func updateItemCommand(description string, before todoItem, after todoItem) undoCommand {
+	return undoCommand{
+		description: description,
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateItem(ctx, before)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateItem(ctx, after)
+		},
+	}
+}
+
+func updateItemsCommand(description string, before []todoItem, after []todoItem) undoCommand {
+	return undoCommand{
+		description: description,
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateItems(ctx, before)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateItems(ctx, after)
+		},
+	}
+}
+
+func createItemCommand(item todoItem) undoCommand {
+	return undoCommand{
+		description: "add task",
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.DeleteItem(ctx, item.id)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.RestoreItem(ctx, item)
+		},
+	}
+}
+
+func deleteItemCommand(item todoItem) undoCommand {
+	return undoCommand{
+		description: "delete task",
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.RestoreItem(ctx, item)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.DeleteItem(ctx, item.id)
+		},
+	}
+}
+
+func deleteItemsCommand(items []todoItem) undoCommand {
+	return undoCommand{
+		description: "delete tasks",
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.RestoreItems(ctx, items)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.DeleteItems(ctx, itemIDs(items))
+		},
+	}
+}
+
+func renameListCommand(id int, oldName string, newName string) undoCommand {
+	return undoCommand{
+		description: "rename list",
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateTodoListName(ctx, id, oldName)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.UpdateTodoListName(ctx, id, newName)
+		},
+	}
+}
+
+func archiveListCommand(id int, archived bool) undoCommand {
+	archive := func(ctx context.Context, store DataStore) error {
+		return store.ArchiveTodoList(ctx, id)
+	}
+	unarchive := func(ctx context.Context, store DataStore) error {
+		return store.UnarchiveTodoList(ctx, id)
+	}
+	if archived {
+		return undoCommand{description: "archive list", undo: unarchive, redo: archive}
+	}
+	return undoCommand{description: "unarchive list", undo: archive, redo: unarchive}
+}
+
+// deleteListCommand restores the list and the tasks it held when it was
+// deleted; tasks deleted earlier stay deleted
+func deleteListCommand(list todoList, items []todoItem) undoCommand {
+	return undoCommand{
+		description: "delete list",
+		undo: func(ctx context.Context, store DataStore) error {
+			if err := store.RestoreTodoList(ctx, list); err != nil {
+				return err
+			}
+			for _, item := range items {
+				if err := store.RestoreItem(ctx, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.DeleteTodoList(ctx, list.id)
+		},
+	}
+}
+
+// groupCommand bundles several commands into one history entry, undoing
+// them in reverse order
+func groupCommand(description string, cmds []undoCommand) undoCommand {
+	return undoCommand{
+		description: description,
+		undo: func(ctx context.Context, store DataStore) error {
+			for i := len(cmds) - 1; i >= 0; i-- {
+				if err := cmds[i].undo(ctx, store); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			for _, cmd := range cmds {
+				if err := cmd.redo(ctx, store); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func reorderListsCommand(before []int, after []int) undoCommand {
+	return undoCommand{
+		description: "reorder lists",
+		undo: func(ctx context.Context, store DataStore) error {
+			return store.ReorderTodoLists(ctx, before)
+		},
+		redo: func(ctx context.Context, store DataStore) error {
+			return store.ReorderTodoLists(ctx, after)
+		},
+	}
+}
+
+func (m *model) recordUndo(cmd undoCommand) {
+	m.history.push(cmd)
+}
+
+func (m *model) undo() {
+	stack := m.history.undoStack
+	if len(stack) == 0 {
+		m.statusMsg = "Nothing to undo"
+		return
+	}
+	cmd := stack[len(stack)-1]
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := cmd.undo(ctx, m.store); err != nil {
+		m.errorMsg = "Failed to undo " + cmd.description + ": " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	m.history.undoStack = stack[:len(stack)-1]
+	m.history.redoStack = append(m.history.redoStack, cmd)
+	m.statusMsg = "Undid " + cmd.description
+	m.reloadFromStore(ctx)
+}
+
+func (m *model) redo() {
+	stack := m.history.redoStack
+	if len(stack) == 0 {
+		m.statusMsg = "Nothing to redo"
+		return
+	}
+	cmd := stack[len(stack)-1]
+
+	ctx, cancel := m.storeContext()
+	defer cancel()
+
+	if err := cmd.redo(ctx, m.store); err != nil {
+		m.errorMsg = "Failed to redo " + cmd.description + ": " + err.Error()
+		m.reloadFromStore(ctx)
+		return
+	}
+	m.history.redoStack = stack[:len(stack)-1]
+	m.history.undoStack = append(m.history.undoStack, cmd)
+	m.statusMsg = "Redid " + cmd.description
+	m.reloadFromStore(ctx)
+}
+
+// reloadFromStore replaces the in-memory lists and tasks with the store's,
+// staying on the current list when it still exists
+func (m *model) reloadFromStore(ctx context.Context) {
+	todoLists, err := m.store.GetTodoLists(ctx)
+	if err != nil {
+		m.errorMsg = "Failed to load lists: " + err.Error()
+		return
+	}
+	items, err := m.store.GetItems(ctx)
+	if err != nil {
+		m.errorMsg = "Failed to load tasks: " + err.Error()
+		return
+	}
+
+	if err := m.loadArchivedLists(ctx); err != nil {
+		m.errorMsg = "Failed to load archived lists: " + err.Error()
+		return
+	}
+
+	m.todoLists = todoLists
+	m.items = items
+	m.sortItems()
+
+	for i, list := range m.todoLists {
+		if list.id == m.currentListID {
+			m.currentListIndex = i
+			return
+		}
+	}
+	if len(m.todoLists) > 0 {
+		m.switchToList(0)
+	}
+}
@@ -60,6 +60,10 @@ func parseDueDate(input string) int64 {
 		return 0
 	}
 
+	if t, ok := namedDueDate(strings.ToLower(input)); ok {
+		return setToEndOfDay(t).Unix()
+	}
+
 	var days int
 	if len(input) <= 3 {
 		_, err := fmt.Sscanf(input, "%d", &days)
@@ -95,3 +99,35 @@ func parseDueDate(input string) int64 {
 
 	return 0
 }
+
+// namedDueDate reads "today", "tomorrow" and weekday names such as "fri"
+// or "friday", which mean the next such day after today
+func namedDueDate(input string) (time.Time, bool) {
+	now := time.Now()
+	switch input {
+	case "today":
+		return now, true
+	case "tomorrow", "tom":
+		return now.AddDate(0, 0, 1), true
+	}
+	if len(input) < 3 {
+		return time.Time{}, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), input) {
+			days := (int(day)-int(now.Weekday())+6)%7 + 1
+			return now.AddDate(0, 0, days), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstTag returns the first #tag in a task's text, or an empty string
+func firstTag(text string) string {
+	for _, word := range strings.Fields(text) {
+		if len(word) > 1 && strings.HasPrefix(word, "#") {
+			return word
+		}
+	}
+	return ""
+}
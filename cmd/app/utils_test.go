@@ -147,3 +147,71 @@ func TestParseDueDate_TimeSetToEndOfDay(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDueDate_NamedDays(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		input string
+		days  int
+	}{
+		{"today", 0},
+		{"Tomorrow", 1},
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		days := (int(day)-int(now.Weekday())+6)%7 + 1
+		tests = append(tests, struct {
+			input string
+			days  int
+		}{day.String()[:3], days})
+	}
+
+	for _, tt := range tests {
+		result := parseDueDate(tt.input)
+		if result == 0 {
+			t.Errorf("expected non-zero result for %q", tt.input)
+			continue
+		}
+		got := time.Unix(result, 0).Format("2006-01-02")
+		if want := now.AddDate(0, 0, tt.days).Format("2006-01-02"); got != want {
+			t.Errorf("for input %q: expected date %s, got %s", tt.input, want, got)
+		}
+	}
+}
+
+func TestParseQuickAdd(t *testing.T) {
+	lists := []todoList{{id: 1, name: "Work"}, {id: 2, name: "Side Project"}}
+	q, err := parseQuickAdd("Fix login bug !1 @work #backend due:fri", lists)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if q.text != "Fix login bug #backend" {
+		t.Errorf("expected the tokens removed but the tag kept, got %q", q.text)
+	}
+	if q.priority != PriorityHigh || q.list != "Work" || q.dueDate != parseDueDate("fri") {
+		t.Errorf("unexpected fields: %+v", q)
+	}
+	if len(q.tags) != 1 || q.tags[0] != "#backend" {
+		t.Errorf("expected the #backend tag, got %v", q.tags)
+	}
+
+	q, err = parseQuickAdd("Ship it! !5 @ email me@example.com", lists)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if q.hasFields() || q.text != "Ship it! !5 @ email me@example.com" {
+		t.Errorf("expected words that are not fields to stay in the text, got %+v", q)
+	}
+
+	// An @word that names no list is part of the text
+	q, err = parseQuickAdd("call @bob about @side-project", lists)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if q.text != "call @bob about" || q.list != "Side Project" {
+		t.Errorf("expected only the list token taken from the text, got %+v", q)
+	}
+
+	if _, err := parseQuickAdd("Pay rent due:someday", lists); err == nil {
+		t.Error("expected an error for an unreadable due date")
+	}
+}
@@ -3,11 +3,13 @@ module github.com/billgoswell/commandlinetodo
 go 1.25.4
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/charmbracelet/bubbles v0.21.0
 	github.com/charmbracelet/bubbletea v1.3.10
 	github.com/charmbracelet/lipgloss v1.1.0
 	github.com/google/uuid v1.6.0
 	github.com/mergestat/timediff v0.0.4
+	golang.org/x/term v0.35.0
 	modernc.org/sqlite v1.40.1
 )
 